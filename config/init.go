@@ -36,15 +36,38 @@ func InitDatabase(dsn string) (*gorm.DB, error) {
 	if err != nil {
 		return nil, err
 	}
+	// is_mutual 字段刚加入时，需要根据已有的关注关系计算好友
+	backfillMutual := db.Migrator().HasTable(&models.Relation{}) &&
+		!db.Migrator().HasColumn(&models.Relation{}, "IsMutual")
+	// 会话表刚加入时，需要根据已有的消息生成会话
+	backfillConversations := db.Migrator().HasTable(&models.Message{}) &&
+		!db.Migrator().HasTable(&models.Conversation{})
 	// 自动将表单模型结构体迁移成数据库表单
 	err = db.AutoMigrate(&models.User{}, &models.UserProfile{},
 		&models.Video{}, &models.Favorite{},
 		&models.Comment{}, &models.Message{},
-		&models.Relation{},
+		&models.Relation{}, &models.WatchHistory{},
+		&models.FeedFeedback{}, &models.Tag{}, &models.VideoTag{},
+		&models.SearchHistory{}, &models.CommentLike{},
+		&models.ContentFilterLog{}, &models.Mention{},
+		&models.Notification{}, &models.NotificationActor{},
+		&models.Collection{}, &models.CollectionVideo{}, &models.PrivacySetting{},
+		&models.FollowSuggestion{}, &models.Block{}, &models.FollowRequest{}, &models.Mute{},
+		&models.Conversation{}, &models.ContentReview{},
 	)
 	if err != nil {
 		return nil, err
 	}
+	if backfillMutual {
+		if err := models.BackfillMutual(db); err != nil {
+			return nil, err
+		}
+	}
+	if backfillConversations {
+		if err := models.BackfillConversations(db); err != nil {
+			return nil, err
+		}
+	}
 	return db, nil
 }
 
@@ -4,9 +4,70 @@ package consts
 
 import "time"
 
+// StatusCodePrivate 列表因为隐私设置不可见时返回的 status_code
+const StatusCodePrivate = 2
+
 const MaxCommentLength = 512
+const MaxPreviewReplies = 3
+const MaxRepliesCount = 20
+const DefaultCommentCount = 20
+const MaxCommentCount = 50
 const MaxVideoSize = 10 * 1024 * 1024
+const MaxVideoTitleLength = 255
 const MaxVideos = 5
+const MaxHistoryCount = 20
+const MaxFeedbackKeywordLength = 20
+const MaxTagsPerVideo = 10
+const MaxTagLength = 64
+const MaxTagSuggestions = 10
+const MaxSearchQueryLength = 50
+const MaxSearchResults = 20
+const MaxSearchCandidates = 200
+const SearchPopularityWeight = 0.3
+const MaxSuggestions = 10
+const MaxSuggestPrefixLength = 20
+const MaxSuggestSourceCount = 10000
+const SuggestRefreshInterval = 10 * time.Minute
+const MaxHotSearches = 10
+const HotSearchWindow = 24 * time.Hour
+const HotSearchBucket = time.Hour
+const MaxSearchHistoryCount = 20
+const MaxMentionsPerText = 10
+const MaxMentionCount = 20
+const MaxNotificationCount = 20
+const MaxNotificationActors = 3
+const NotificationRetention = 90 * 24 * time.Hour
+const NotificationCleanupInterval = time.Hour
+const NotificationCleanupBatch = 1000
+const MaxCollectionsPerUser = 100
+const MaxCollectionNameLength = 30
+const MaxVideosPerCollection = 1000
+const MaxCollectionVideoCount = 20
+const DefaultCollectionCount = 20
+const MaxCollectionCount = 50
+const DefaultRelationCount = 20
+const MaxRelationCount = 50
+const MaxFriendCheckCount = 100
+const MaxStoredSuggestions = 50
+const MaxSuggestionCount = 20
+const SuggestionRefreshInterval = 6 * time.Hour
+const WsSendBuffer = 64
+const DefaultConversationCount = 20
+const MaxConversationCount = 50
+const MessageRecallWindow = 2 * time.Minute
+const MessageEditWindow = 15 * time.Minute
+const RecalledMessageContent = "This message was recalled."
+const WsMaxMessageSize = 512
+const WsPingInterval = 30 * time.Second
+const WsPongWait = 60 * time.Second
+const WsWriteWait = 10 * time.Second
+const DefaultFavoriteCount = 20
+const MaxFavoriteCount = 50
 const AwsBucketName = "dousheng"
 const MinIOBucketName = "dousheng-media"
 const UrlExpiration = 6.5 * 24 * time.Hour
+const SensitiveWordsFile = "config/sensitive_words.txt"
+const SensitiveWordsReloadInterval = time.Minute
+const MaxSignatureLength = 128
+const DefaultReviewCount = 20
+const MaxReviewCount = 50
@@ -4,15 +4,25 @@ import (
 	"app/config"
 	"app/consts"
 	"app/middleware"
+	"app/modules/collection"
 	"app/modules/comment"
 	"app/modules/favorite"
+	"app/modules/filter"
+	"app/modules/history"
+	"app/modules/mention"
 	"app/modules/message"
+	"app/modules/notification"
+	"app/modules/privacy"
 	"app/modules/relation"
+	"app/modules/review"
+	"app/modules/search"
+	"app/modules/tag"
 	"app/modules/user"
 	"app/modules/video"
 	"context"
 	"github.com/minio/minio-go/v7"
 	"log"
+	"os"
 )
 
 func main() {
@@ -50,25 +60,97 @@ func main() {
 		log.Fatalf("failed to connect database: %s\n", err)
 	}
 
+	// 从数据库加载搜索索引
+	if err := search.Rebuild(db); err != nil {
+		log.Fatalf("failed to build search index: %s\n", err)
+	}
+	search.StartSuggestRefresher(db, consts.SuggestRefreshInterval)
+
+	// 加载敏感词库，文件变化时自动重新加载
+	wordsFile := os.Getenv("SENSITIVE_WORDS_FILE")
+	if wordsFile == "" {
+		wordsFile = consts.SensitiveWordsFile
+	}
+	filter.StartReloader(wordsFile, consts.SensitiveWordsReloadInterval)
+
+	// 定期清理超过保留期限的通知
+	notification.StartCleaner(db, consts.NotificationRetention, consts.NotificationCleanupInterval)
+
+	// 定期重新计算推荐关注
+	relation.StartSuggester(db, consts.SuggestionRefreshInterval)
+
 	r := config.InitGinEngine(db)
 
 	r.GET("/douyin/comment/list/", middleware.Authentication(), comment.List)
+	r.GET("/douyin/comment/replies/", middleware.Authentication(), comment.Replies)
+	r.GET("/douyin/mention/list/", middleware.Authentication(), mention.List)
+	r.GET("/douyin/collection/list/", middleware.Authentication(), collection.List)
+	r.GET("/douyin/collection/videos/", middleware.Authentication(), collection.Videos)
+	r.GET("/douyin/privacy/", middleware.Authentication(), privacy.Get)
+	r.GET("/douyin/notification/list/", middleware.Authentication(), notification.List)
+	r.GET("/douyin/notification/unread/", middleware.Authentication(), notification.Unread)
 	r.GET("/douyin/favorite/list/", middleware.Authentication(), favorite.GetLikeVideos)
 	r.GET("/douyin/feed/", video.GetFeed)
+	r.GET("/douyin/history/", middleware.Authentication(), history.List)
 	r.GET("/douyin/message/chat/", middleware.Authentication(), message.GetHistory)
+	r.GET("/douyin/message/ws/", middleware.Authentication(), message.Connect)
+	r.GET("/douyin/message/conversations/", middleware.Authentication(), message.GetConversations)
 	r.GET("/douyin/publish/list/", middleware.Authentication(), video.GetUserVideos)
 	r.GET("/douyin/relation/follow/list/", middleware.Authentication(), relation.GetFollowings)
 	r.GET("/douyin/relation/follower/list/", middleware.Authentication(), relation.GetFollowers)
 	r.GET("/douyin/relation/friend/list/", middleware.Authentication(), relation.GetFriends)
+	r.GET("/douyin/relation/is_friend/", middleware.Authentication(), relation.IsFriend)
+	r.GET("/douyin/relation/suggest/", middleware.Authentication(), relation.Suggest)
+	r.GET("/douyin/relation/block/list/", middleware.Authentication(), relation.GetBlocks)
+	r.GET("/douyin/relation/request/list/", middleware.Authentication(), relation.GetRequests)
+	r.GET("/douyin/relation/mute/list/", middleware.Authentication(), relation.GetMutes)
+	r.GET("/douyin/review/list/", middleware.Authentication(), review.List)
+	r.GET("/douyin/search/", search.Search)
+	r.GET("/douyin/search/history/", middleware.Authentication(), search.GetHistory)
+	r.GET("/douyin/search/hot/", search.GetHot)
+	r.GET("/douyin/search/suggest/", search.Suggest)
+	r.GET("/douyin/tag/", tag.GetTag)
+	r.GET("/douyin/tag/suggest/", tag.Suggest)
 	r.GET("/douyin/user/", middleware.Authentication(), user.GetUser)
 	r.POST("/douyin/comment/action/", middleware.Authentication(), comment.Action)
+	r.POST("/douyin/comment/like/", middleware.Authentication(), comment.Like)
+	r.POST("/douyin/comment/pin/", middleware.Authentication(), comment.Pin)
+	r.POST("/douyin/comment/settings/", middleware.Authentication(), comment.Settings)
+	r.POST("/douyin/notification/read/", middleware.Authentication(), notification.Read)
+	r.POST("/douyin/mention/read/", middleware.Authentication(), mention.Read)
+	r.POST("/douyin/collection/action/", middleware.Authentication(), collection.Action)
+	r.POST("/douyin/collection/video/action/", middleware.Authentication(), collection.VideoAction)
+	r.POST("/douyin/collection/video/move/", middleware.Authentication(), collection.Move)
+	r.POST("/douyin/privacy/action/", middleware.Authentication(), privacy.Update)
+	r.POST("/douyin/review/action/", middleware.Authentication(), review.Action)
 	r.POST("/douyin/favorite/action/", middleware.Authentication(), favorite.Action)
+	r.POST("/douyin/feed/feedback/", middleware.Authentication(), video.Feedback)
+	r.POST("/douyin/history/action/", middleware.Authentication(), history.Report)
+	r.POST("/douyin/history/clear/", middleware.Authentication(), history.Clear)
+	r.POST("/douyin/history/delete/", middleware.Authentication(), history.Delete)
+	r.POST("/douyin/history/pause/", middleware.Authentication(), history.Pause)
 	r.POST("/douyin/message/action/", middleware.Authentication(), message.Send)
+	r.POST("/douyin/message/read/", middleware.Authentication(), message.Read)
+	r.POST("/douyin/message/recall/", middleware.Authentication(), message.Recall)
+	r.POST("/douyin/message/edit/", middleware.Authentication(), message.Edit)
+	r.POST("/douyin/message/delete/", middleware.Authentication(), message.Delete)
 	r.POST("/douyin/publish/action/", middleware.Authentication(), video.PublishToMinIO)
+	r.POST("/douyin/publish/delete/", middleware.Authentication(), video.Delete)
+	r.POST("/douyin/publish/edit/", middleware.Authentication(), video.Edit)
 	//r.POST("/douyin/publish/action/", middleware.Authentication(), video.Publish)
 	r.POST("/douyin/relation/action/", middleware.Authentication(), relation.Action)
+	r.POST("/douyin/relation/suggest/dismiss/", middleware.Authentication(), relation.Dismiss)
+	r.POST("/douyin/relation/block/", middleware.Authentication(), relation.Block)
+	r.POST("/douyin/relation/unblock/", middleware.Authentication(), relation.Unblock)
+	r.POST("/douyin/relation/request/action/", middleware.Authentication(), relation.RequestAction)
+	r.POST("/douyin/relation/mute/", middleware.Authentication(), relation.Mute)
+	r.POST("/douyin/relation/unmute/", middleware.Authentication(), relation.Unmute)
+	r.POST("/douyin/search/history/clear/", middleware.Authentication(), search.ClearHistory)
+	r.POST("/douyin/search/history/delete/", middleware.Authentication(), search.DeleteHistory)
 	r.POST("/douyin/user/login/", user.Login)
 	r.POST("/douyin/user/register/", user.Register)
+	r.POST("/douyin/user/rename/", middleware.Authentication(), user.Rename)
+	r.POST("/douyin/user/signature/", middleware.Authentication(), user.UpdateSignature)
 
 	err = r.Run(":8080")
 	if err != nil {
@@ -0,0 +1,625 @@
+package collection
+
+import (
+	"app/consts"
+	"app/modules/filter"
+	"app/modules/models"
+	"app/utils"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// coverUrls 用窗口函数一次查出每个收藏夹排在第一位的视频的封面
+func coverUrls(db *gorm.DB, collectionIds []uint) map[uint]string {
+	covers := make(map[uint]string)
+	if len(collectionIds) == 0 {
+		return covers
+	}
+	var rows []struct {
+		CollectionID uint
+		CoverUrl     string
+	}
+	ranked := db.Model(&models.CollectionVideo{}).
+		Select("collection_id, video_id, "+
+			"ROW_NUMBER() OVER (PARTITION BY collection_id ORDER BY position, id) AS rn").
+		Where("collection_id IN ?", collectionIds)
+	db.Table("(?) AS ranked", ranked).
+		Select("ranked.collection_id, videos.cover_url").
+		Joins("JOIN videos ON videos.id = ranked.video_id").
+		Where("ranked.rn = 1").Scan(&rows)
+	for _, row := range rows {
+		covers[row.CollectionID] = row.CoverUrl
+	}
+	return covers
+}
+
+func buildCollectionResItem(collection models.Collection, covers map[uint]string) *utils.CollectionResItem {
+	return &utils.CollectionResItem{
+		ID:         collection.ID,
+		UserID:     collection.UserID,
+		Name:       collection.Name,
+		IsPublic:   collection.IsPublic,
+		VideoCount: collection.VideoCount,
+		CoverUrl:   covers[collection.ID],
+	}
+}
+
+// validateName 验证收藏夹名称并过滤敏感词，同一个用户的收藏夹不能重名。
+// 返回处理后的名称，验证失败时已经返回了错误信息
+func validateName(c *gin.Context, db *gorm.DB, userId uint, collectionId uint, name string) (string, bool) {
+	if len(name) == 0 || utf8.RuneCountInString(name) > consts.MaxCollectionNameLength {
+		c.JSON(http.StatusBadRequest, utils.CollectionResponse{
+			StatusCode: 1,
+			StatusMsg:  fmt.Sprintf("Collection name must be between 1 and %d characters.", consts.MaxCollectionNameLength),
+		})
+		return "", false
+	}
+	// 敏感词过滤
+	name, verdict := filter.Apply(db, userId, filter.SourceCollection, name)
+	if verdict == filter.Reject {
+		c.JSON(http.StatusBadRequest, utils.CollectionResponse{
+			StatusCode: 1,
+			StatusMsg:  "Collection name contains sensitive words.",
+		})
+		return "", false
+	}
+	var count int64
+	db.Model(&models.Collection{}).Where("user_id = ? AND name = ? AND id <> ?", userId, name, collectionId).
+		Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusBadRequest, utils.CollectionResponse{
+			StatusCode: 1,
+			StatusMsg:  "Collection name already exists.",
+		})
+		return "", false
+	}
+	return name, true
+}
+
+// ownCollection 验证 collection_id，并且收藏夹需要是当前用户创建的，验证失败时已经返回了错误信息
+func ownCollection(c *gin.Context, db *gorm.DB, userId uint) (models.Collection, bool) {
+	var collection models.Collection
+	collectionIdInt, err := strconv.Atoi(c.DefaultQuery("collection_id", "0"))
+	if err != nil || collectionIdInt < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status_code": 1,
+			"status_msg":  "Invalid collection_id.",
+		})
+		return collection, false
+	}
+	if err := db.First(&collection, collectionIdInt).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status_code": 1,
+			"status_msg":  "Collection not found.",
+		})
+		return collection, false
+	}
+	if collection.UserID != userId {
+		c.JSON(http.StatusForbidden, gin.H{
+			"status_code": 1,
+			"status_msg":  "You do not have permission to modify this collection.",
+		})
+		return collection, false
+	}
+	return collection, true
+}
+
+// Action 管理收藏夹。action_type 为 1 时创建（name, is_public），
+// 为 2 时修改名称或公开状态（collection_id, name, is_public），为 3 时删除（collection_id）
+func Action(c *gin.Context) {
+	// 验证 user_id
+	tokenString := c.DefaultQuery("token", "")
+	userId, err := utils.ValidateToken(tokenString)
+	if err != nil || userId <= 0 {
+		c.JSON(http.StatusBadRequest, utils.CollectionResponse{
+			StatusCode: 1,
+			StatusMsg:  "Invalid user ID.",
+		})
+		return
+	}
+
+	db := c.MustGet("db").(*gorm.DB)
+	name := strings.TrimSpace(c.DefaultQuery("name", ""))
+	isPublic := c.DefaultQuery("is_public", "")
+	if isPublic != "" && isPublic != "0" && isPublic != "1" {
+		c.JSON(http.StatusBadRequest, utils.CollectionResponse{
+			StatusCode: 1,
+			StatusMsg:  "Invalid is_public.",
+		})
+		return
+	}
+
+	switch c.DefaultQuery("action_type", "") {
+	case "1": // 创建收藏夹，默认公开
+		var count int64
+		db.Model(&models.Collection{}).Where("user_id = ?", userId).Count(&count)
+		if count >= consts.MaxCollectionsPerUser {
+			c.JSON(http.StatusBadRequest, utils.CollectionResponse{
+				StatusCode: 1,
+				StatusMsg:  "Too many collections.",
+			})
+			return
+		}
+		name, ok := validateName(c, db, userId, 0, name)
+		if !ok {
+			return
+		}
+		collection := models.Collection{UserID: userId, Name: name, IsPublic: isPublic != "0"}
+		if err := db.Create(&collection).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, utils.CollectionResponse{
+				StatusCode: 1,
+				StatusMsg:  "Failed to create collection.",
+			})
+			return
+		}
+		c.JSON(http.StatusOK, utils.CollectionResponse{
+			StatusCode: 0,
+			StatusMsg:  "Success",
+			Collection: buildCollectionResItem(collection, nil),
+		})
+		return
+	case "2": // 修改收藏夹
+		collection, ok := ownCollection(c, db, userId)
+		if !ok {
+			return
+		}
+		updates := make(map[string]interface{})
+		if name != "" {
+			name, ok := validateName(c, db, userId, collection.ID, name)
+			if !ok {
+				return
+			}
+			updates["name"] = name
+		}
+		if isPublic != "" {
+			updates["is_public"] = isPublic == "1"
+		}
+		if len(updates) > 0 {
+			if err := db.Model(&collection).Updates(updates).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, utils.CollectionResponse{
+					StatusCode: 1,
+					StatusMsg:  "Failed to update collection.",
+				})
+				return
+			}
+		}
+		c.JSON(http.StatusOK, utils.CollectionResponse{
+			StatusCode: 0,
+			StatusMsg:  "Success",
+			Collection: buildCollectionResItem(collection, coverUrls(db, []uint{collection.ID})),
+		})
+		return
+	case "3": // 删除收藏夹以及其中的视频记录
+		collection, ok := ownCollection(c, db, userId)
+		if !ok {
+			return
+		}
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Session(&gorm.Session{SkipHooks: true}).
+				Where("collection_id = ?", collection.ID).Delete(&models.CollectionVideo{}).Error; err != nil {
+				return err
+			}
+			return tx.Delete(&collection).Error
+		}); err != nil {
+			c.JSON(http.StatusInternalServerError, utils.CollectionResponse{
+				StatusCode: 1,
+				StatusMsg:  "Failed to delete collection.",
+			})
+			return
+		}
+	default: // action_type 不合法
+		c.JSON(http.StatusBadRequest, utils.CollectionResponse{
+			StatusCode: 1,
+			StatusMsg:  "Invalid action type.",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.CollectionResponse{
+		StatusCode: 0,
+		StatusMsg:  "Success",
+	})
+}
+
+// List 按创建时间倒序分页列出用户的收藏夹，查看别人的收藏夹时只返回公开的收藏夹。
+// cursor 为上一页返回的 next_cursor，格式为 "创建时间毫秒_ID"
+func List(c *gin.Context) {
+	// 验证 user_id
+	userIdInt, err := strconv.Atoi(c.DefaultQuery("user_id", "0"))
+	if err != nil || userIdInt < 1 {
+		c.JSON(http.StatusBadRequest, utils.CollectionListResponse{
+			StatusCode: 1,
+			StatusMsg:  "Invalid user_id.",
+		})
+		return
+	}
+
+	// 验证每页数量
+	count, err := strconv.Atoi(c.DefaultQuery("count", strconv.Itoa(consts.DefaultCollectionCount)))
+	if err != nil || count < 1 || count > consts.MaxCollectionCount {
+		c.JSON(http.StatusBadRequest, utils.CollectionListResponse{
+			StatusCode: 1,
+			StatusMsg:  "Invalid count.",
+		})
+		return
+	}
+
+	db := c.MustGet("db").(*gorm.DB)
+	tokenString := c.DefaultQuery("token", "")
+	currentUserId, _ := utils.ValidateToken(tokenString)
+
+	query := db.Where("user_id = ?", userIdInt)
+	if currentUserId != uint(userIdInt) {
+		query = query.Where("is_public = ?", true)
+	}
+	if cursor := c.DefaultQuery("cursor", ""); cursor != "" {
+		cursorTime, cursorId, ok := utils.ParseTimeCursor(cursor)
+		if !ok {
+			c.JSON(http.StatusBadRequest, utils.CollectionListResponse{
+				StatusCode: 1,
+				StatusMsg:  "Invalid cursor.",
+			})
+			return
+		}
+		query = query.Where("created_at <= ? AND (created_at < ? OR id < ?)",
+			cursorTime, cursorTime, cursorId)
+	}
+
+	// 多取一条用来判断是否还有下一页
+	var collections []models.Collection
+	if err := query.Order("created_at desc").Order("id desc").
+		Limit(count + 1).Find(&collections).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.CollectionListResponse{
+			StatusCode: 1,
+			StatusMsg:  "Failed to fetch collections.",
+		})
+		return
+	}
+	hasMore := len(collections) > count
+	var nextCursor string
+	if hasMore {
+		collections = collections[:count]
+		last := collections[len(collections)-1]
+		nextCursor = utils.FormatTimeCursor(last.CreatedAt, last.ID)
+	}
+
+	var collectionIds []uint
+	for _, collection := range collections {
+		collectionIds = append(collectionIds, collection.ID)
+	}
+	covers := coverUrls(db, collectionIds)
+
+	var collectionList []utils.CollectionResItem
+	for _, collection := range collections {
+		collectionList = append(collectionList, *buildCollectionResItem(collection, covers))
+	}
+
+	c.JSON(http.StatusOK, utils.CollectionListResponse{
+		StatusCode:     0,
+		StatusMsg:      "Success",
+		CollectionList: collectionList,
+		NextCursor:     nextCursor,
+		HasMore:        hasMore,
+	})
+}
+
+// VideoAction 将视频加入/移出收藏夹，action_type 为 1 时加入（排在最后），为 2 时移出。
+// 操作是幂等的，并且不影响视频的点赞
+func VideoAction(c *gin.Context) {
+	// 验证 user_id
+	tokenString := c.DefaultQuery("token", "")
+	userId, err := utils.ValidateToken(tokenString)
+	if err != nil || userId <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status_code": 1,
+			"status_msg":  "Invalid user ID.",
+		})
+		return
+	}
+
+	db := c.MustGet("db").(*gorm.DB)
+	collection, ok := ownCollection(c, db, userId)
+	if !ok {
+		return
+	}
+
+	// 验证 video_id
+	videoIdInt, err := strconv.Atoi(c.DefaultQuery("video_id", "0"))
+	if err != nil || videoIdInt < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status_code": 1,
+			"status_msg":  "Invalid video_id.",
+		})
+		return
+	}
+
+	switch c.DefaultQuery("action_type", "") {
+	case "1": // 加入收藏夹
+		var video models.Video
+		if err := db.First(&video, videoIdInt).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"status_code": 1,
+				"status_msg":  "Video not found.",
+			})
+			return
+		}
+		// 在事务中锁住收藏夹，保证并发添加时视频数的检查和位置的计算都基于最新的数据
+		var full bool
+		err := db.Transaction(func(tx *gorm.DB) error {
+			var locked models.Collection
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&locked, collection.ID).Error; err != nil {
+				return err
+			}
+			if locked.VideoCount >= consts.MaxVideosPerCollection {
+				full = true
+				return nil
+			}
+			var maxPosition float64
+			if err := tx.Model(&models.CollectionVideo{}).Where("collection_id = ?", collection.ID).
+				Select("COALESCE(MAX(position), 0)").Scan(&maxPosition).Error; err != nil {
+				return err
+			}
+			collectionVideo := models.CollectionVideo{
+				CollectionID: collection.ID,
+				VideoID:      video.ID,
+				Position:     maxPosition + 1,
+			}
+			return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&collectionVideo).Error
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"status_code": 1,
+				"status_msg":  "Failed to add video to collection.",
+			})
+			return
+		}
+		if full {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"status_code": 1,
+				"status_msg":  "Collection is full.",
+			})
+			return
+		}
+	case "2": // 移出收藏夹
+		collectionVideo := models.CollectionVideo{CollectionID: collection.ID, VideoID: uint(videoIdInt)}
+		if err := db.Where("collection_id = ? AND video_id = ?", collection.ID, videoIdInt).
+			Delete(&collectionVideo).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"status_code": 1,
+				"status_msg":  "Failed to remove video from collection.",
+			})
+			return
+		}
+	default: // action_type 不合法
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status_code": 1,
+			"status_msg":  "Invalid action type.",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status_code": 0,
+		"status_msg":  "Success",
+	})
+}
+
+// Move 调整视频在收藏夹中的顺序，将 video_id 移到 after_video_id 后面，after_video_id 为 0 时移到最前面
+func Move(c *gin.Context) {
+	// 验证 user_id
+	tokenString := c.DefaultQuery("token", "")
+	userId, err := utils.ValidateToken(tokenString)
+	if err != nil || userId <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status_code": 1,
+			"status_msg":  "Invalid user ID.",
+		})
+		return
+	}
+
+	db := c.MustGet("db").(*gorm.DB)
+	collection, ok := ownCollection(c, db, userId)
+	if !ok {
+		return
+	}
+
+	videoIdInt, err := strconv.Atoi(c.DefaultQuery("video_id", "0"))
+	if err != nil || videoIdInt < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status_code": 1,
+			"status_msg":  "Invalid video_id.",
+		})
+		return
+	}
+	afterVideoIdInt, err := strconv.Atoi(c.DefaultQuery("after_video_id", "0"))
+	if err != nil || afterVideoIdInt < 0 || afterVideoIdInt == videoIdInt {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status_code": 1,
+			"status_msg":  "Invalid after_video_id.",
+		})
+		return
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		return move(tx, collection.ID, uint(videoIdInt), uint(afterVideoIdInt))
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status_code": 1,
+			"status_msg":  "Video is not in this collection.",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status_code": 1,
+			"status_msg":  "Failed to move video.",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status_code": 0,
+		"status_msg":  "Success",
+	})
+}
+
+// move 将视频的 Position 设为前后两个视频的中间值。浮点数精度用完时，将整个收藏夹重新编号
+func move(tx *gorm.DB, collectionId, videoId, afterVideoId uint) error {
+	var target models.CollectionVideo
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("collection_id = ? AND video_id = ?", collectionId, videoId).First(&target).Error; err != nil {
+		return err
+	}
+
+	// 查询移动之后前后的两个视频
+	others := tx.Where("collection_id = ? AND id <> ?", collectionId, target.ID)
+	var prev, next models.CollectionVideo
+	var hasPrev, hasNext bool
+	if afterVideoId != 0 {
+		if err := tx.Where("collection_id = ? AND video_id = ?", collectionId, afterVideoId).
+			First(&prev).Error; err != nil {
+			return err
+		}
+		hasPrev = true
+		others = others.Where("(position > ? OR (position = ? AND id > ?))", prev.Position, prev.Position, prev.ID)
+	}
+	result := others.Order("position").Order("id").Limit(1).Find(&next)
+	if result.Error != nil {
+		return result.Error
+	}
+	hasNext = result.RowsAffected > 0
+
+	var position float64
+	switch {
+	case hasPrev && hasNext:
+		position = (prev.Position + next.Position) / 2
+	case hasPrev:
+		position = prev.Position + 1
+	case hasNext:
+		position = next.Position - 1
+	default: // 收藏夹中只有这一个视频
+		return nil
+	}
+	if (hasPrev && position <= prev.Position) || (hasNext && position >= next.Position) {
+		return renumber(tx, collectionId, target, afterVideoId)
+	}
+	return tx.Model(&target).Update("position", position).Error
+}
+
+// renumber 按移动之后的顺序将收藏夹中的视频重新编号为 1, 2, 3...
+func renumber(tx *gorm.DB, collectionId uint, target models.CollectionVideo, afterVideoId uint) error {
+	var videos []models.CollectionVideo
+	if err := tx.Where("collection_id = ? AND id <> ?", collectionId, target.ID).
+		Order("position").Order("id").Find(&videos).Error; err != nil {
+		return err
+	}
+	ordered := make([]models.CollectionVideo, 0, len(videos)+1)
+	if afterVideoId == 0 {
+		ordered = append(ordered, target)
+	}
+	for _, v := range videos {
+		ordered = append(ordered, v)
+		if v.VideoID == afterVideoId {
+			ordered = append(ordered, target)
+		}
+	}
+	for i, v := range ordered {
+		if err := tx.Model(&models.CollectionVideo{}).Where("id = ?", v.ID).
+			Update("position", float64(i+1)).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Videos 按收藏夹中的顺序分页列出视频，私密收藏夹只有创建者可以查看。cursor 为已经返回的视频数
+func Videos(c *gin.Context) {
+	// 验证 collection_id
+	collectionIdInt, err := strconv.Atoi(c.DefaultQuery("collection_id", "0"))
+	if err != nil || collectionIdInt < 1 {
+		c.JSON(http.StatusBadRequest, utils.CollectionVideosResponse{
+			StatusCode: 1,
+			StatusMsg:  "Invalid collection_id.",
+		})
+		return
+	}
+
+	// 验证 cursor
+	cursor, err := strconv.Atoi(c.DefaultQuery("cursor", "0"))
+	if err != nil || cursor < 0 {
+		c.JSON(http.StatusBadRequest, utils.CollectionVideosResponse{
+			StatusCode: 1,
+			StatusMsg:  "Invalid cursor.",
+		})
+		return
+	}
+
+	db := c.MustGet("db").(*gorm.DB)
+	tokenString := c.DefaultQuery("token", "")
+	currentUserId, _ := utils.ValidateToken(tokenString)
+
+	// 私密收藏夹对其他用户不可见
+	var collection models.Collection
+	if err := db.First(&collection, collectionIdInt).Error; err != nil ||
+		(!collection.IsPublic && collection.UserID != currentUserId) {
+		c.JSON(http.StatusNotFound, utils.CollectionVideosResponse{
+			StatusCode: 1,
+			StatusMsg:  "Collection not found.",
+		})
+		return
+	}
+
+	// 多取一条用来判断是否还有下一页
+	var videoIds []uint
+	if err := db.Model(&models.CollectionVideo{}).Where("collection_id = ?", collection.ID).
+		Order("position").Order("id").Offset(cursor).Limit(consts.MaxCollectionVideoCount+1).
+		Pluck("video_id", &videoIds).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.CollectionVideosResponse{
+			StatusCode: 1,
+			StatusMsg:  "Failed to fetch videos.",
+		})
+		return
+	}
+	hasMore := len(videoIds) > consts.MaxCollectionVideoCount
+	if hasMore {
+		videoIds = videoIds[:consts.MaxCollectionVideoCount]
+	}
+
+	// 按收藏夹中的顺序返回视频
+	var videos []models.Video
+	if len(videoIds) > 0 {
+		db.Preload("User").Preload("User.Profile").Where("id IN ?", videoIds).Find(&videos)
+	}
+	videoById := make(map[uint]models.Video)
+	for _, v := range videos {
+		videoById[v.ID] = v
+	}
+	ordered := make([]models.Video, 0, len(videos))
+	for _, id := range videoIds {
+		if v, ok := videoById[id]; ok {
+			ordered = append(ordered, v)
+		}
+	}
+
+	var nextCursor int
+	if hasMore {
+		nextCursor = cursor + len(videoIds)
+	}
+
+	c.JSON(http.StatusOK, utils.CollectionVideosResponse{
+		StatusCode: 0,
+		StatusMsg:  "Success",
+		Collection: buildCollectionResItem(collection, coverUrls(db, []uint{collection.ID})),
+		NextCursor: nextCursor,
+		HasMore:    hasMore,
+		VideoList:  utils.BuildVideoResList(db, ordered, currentUserId),
+	})
+}
@@ -0,0 +1,178 @@
+package collection
+
+import (
+	"app/config"
+	"app/modules/models"
+	"app/utils"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	utils.Setup()
+	postSetup()
+	code := m.Run()
+	utils.Teardown()
+	os.Exit(code)
+}
+
+var ActionUrl = "/douyin/collection/action/"
+var VideoActionUrl = "/douyin/collection/video/action/"
+var MoveUrl = "/douyin/collection/video/move/"
+var VideosUrl = "/douyin/collection/videos/"
+var ListUrl = "/douyin/collection/list/"
+var db = utils.GetDb()
+var ownerId, otherId uint
+var videoIds []uint
+
+func postSetup() {
+	owner := models.User{Username: "owner_c", Password: "owner_pass"}
+	db.Create(&owner)
+	ownerId = owner.ID
+	other := models.User{Username: "other_c", Password: "other_pass"}
+	db.Create(&other)
+	otherId = other.ID
+
+	for i := 0; i < 3; i++ {
+		video := models.Video{
+			UserID:      otherId,
+			Title:       "collection video " + strconv.Itoa(i),
+			CoverUrl:    "cover" + strconv.Itoa(i) + ".jpg",
+			PublishTime: time.Now(),
+		}
+		db.Create(&video)
+		videoIds = append(videoIds, video.ID)
+	}
+
+	config.Router.POST(ActionUrl, Action)
+	config.Router.POST(VideoActionUrl, VideoAction)
+	config.Router.POST(MoveUrl, Move)
+	config.Router.GET(VideosUrl, Videos)
+	config.Router.GET(ListUrl, List)
+}
+
+func request(t *testing.T, method, path string, values url.Values, resp interface{}) int {
+	req, _ := http.NewRequest(method, path+"?"+values.Encode(), nil)
+	response := httptest.NewRecorder()
+	config.Router.ServeHTTP(response, req)
+	if resp != nil && response.Code == http.StatusOK {
+		if err := json.Unmarshal(response.Body.Bytes(), resp); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return response.Code
+}
+
+// 测试创建私密收藏夹、添加视频、调整顺序和查看收藏夹中的视频
+func TestCollectionVideos(t *testing.T) {
+	ownerToken, _ := utils.GenerateToken(ownerId)
+	otherToken, _ := utils.GenerateToken(otherId)
+
+	var created utils.CollectionResponse
+	code := request(t, "POST", ActionUrl, url.Values{
+		"token": {ownerToken}, "action_type": {"1"}, "name": {"Recipes"}, "is_public": {"0"},
+	}, &created)
+	assert.Equal(t, http.StatusOK, code)
+	assert.False(t, created.Collection.IsPublic)
+	collectionId := strconv.Itoa(int(created.Collection.ID))
+
+	// 同名的收藏夹不能重复创建
+	code = request(t, "POST", ActionUrl, url.Values{
+		"token": {ownerToken}, "action_type": {"1"}, "name": {"Recipes"},
+	}, nil)
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	// 重复添加同一个视频只计数一次
+	for _, id := range append(videoIds, videoIds[0]) {
+		code = request(t, "POST", VideoActionUrl, url.Values{
+			"token": {ownerToken}, "collection_id": {collectionId},
+			"video_id": {strconv.Itoa(int(id))}, "action_type": {"1"},
+		}, nil)
+		assert.Equal(t, http.StatusOK, code)
+	}
+
+	// 将最后一个视频移到最前面，再将第一个视频移到第二个视频后面
+	code = request(t, "POST", MoveUrl, url.Values{
+		"token": {ownerToken}, "collection_id": {collectionId},
+		"video_id": {strconv.Itoa(int(videoIds[2]))}, "after_video_id": {"0"},
+	}, nil)
+	assert.Equal(t, http.StatusOK, code)
+	code = request(t, "POST", MoveUrl, url.Values{
+		"token": {ownerToken}, "collection_id": {collectionId},
+		"video_id": {strconv.Itoa(int(videoIds[0]))}, "after_video_id": {strconv.Itoa(int(videoIds[1]))},
+	}, nil)
+	assert.Equal(t, http.StatusOK, code)
+
+	var resp utils.CollectionVideosResponse
+	code = request(t, "GET", VideosUrl, url.Values{"token": {ownerToken}, "collection_id": {collectionId}}, &resp)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 3, resp.Collection.VideoCount)
+	assert.Equal(t, "cover2.jpg", resp.Collection.CoverUrl)
+	var order []uint
+	for _, v := range resp.VideoList {
+		order = append(order, v.ID)
+	}
+	assert.Equal(t, []uint{videoIds[2], videoIds[1], videoIds[0]}, order)
+
+	// 加入收藏夹不影响点赞
+	assert.False(t, resp.VideoList[0].IsFavorite)
+
+	// 私密收藏夹对其他用户不可见，也不能被其他用户修改
+	code = request(t, "GET", VideosUrl, url.Values{"token": {otherToken}, "collection_id": {collectionId}}, nil)
+	assert.Equal(t, http.StatusNotFound, code)
+	code = request(t, "POST", VideoActionUrl, url.Values{
+		"token": {otherToken}, "collection_id": {collectionId},
+		"video_id": {strconv.Itoa(int(videoIds[0]))}, "action_type": {"2"},
+	}, nil)
+	assert.Equal(t, http.StatusForbidden, code)
+}
+
+// 测试收藏夹列表的分页，其他用户只能看到公开的收藏夹
+func TestListPaging(t *testing.T) {
+	ownerToken, _ := utils.GenerateToken(ownerId)
+	otherToken, _ := utils.GenerateToken(otherId)
+	var ids []uint
+	for _, c := range []struct{ name, isPublic string }{{"Travel", "1"}, {"Secret", "0"}, {"Music", "1"}} {
+		var created utils.CollectionResponse
+		code := request(t, "POST", ActionUrl, url.Values{
+			"token": {otherToken}, "action_type": {"1"}, "name": {c.name}, "is_public": {c.isPublic},
+		}, &created)
+		assert.Equal(t, http.StatusOK, code)
+		ids = append(ids, created.Collection.ID)
+	}
+
+	// 按页取完列表，返回每页的收藏夹ID
+	listAll := func(token string, count int) [][]uint {
+		var pages [][]uint
+		cursor := ""
+		for {
+			var resp utils.CollectionListResponse
+			code := request(t, "GET", ListUrl, url.Values{
+				"token": {token}, "user_id": {strconv.Itoa(int(otherId))},
+				"count": {strconv.Itoa(count)}, "cursor": {cursor},
+			}, &resp)
+			assert.Equal(t, http.StatusOK, code)
+			var page []uint
+			for _, collection := range resp.CollectionList {
+				page = append(page, collection.ID)
+			}
+			pages = append(pages, page)
+			if !resp.HasMore {
+				return pages
+			}
+			cursor = resp.NextCursor
+		}
+	}
+	assert.Equal(t, [][]uint{{ids[2], ids[1]}, {ids[0]}}, listAll(otherToken, 2))
+	assert.Equal(t, [][]uint{{ids[2]}, {ids[0]}}, listAll(ownerToken, 1))
+
+	code := request(t, "GET", ListUrl, url.Values{"user_id": {strconv.Itoa(int(otherId))}, "cursor": {"bad"}}, nil)
+	assert.Equal(t, http.StatusBadRequest, code)
+}
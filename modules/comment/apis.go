@@ -2,15 +2,64 @@ package comment
 
 import (
 	"app/consts"
+	"app/modules/filter"
+	"app/modules/mention"
 	"app/modules/models"
+	"app/modules/policy"
 	"app/utils"
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 )
 
+// setParent 设置回复所在的楼层、直接回复的评论和被回复的用户
+func setParent(comment *models.Comment, parent models.Comment) {
+	comment.ParentID = parent.ID
+	comment.RootID = parent.RootID
+	if parent.RootID == 0 { // 回复的是一级评论
+		comment.RootID = parent.ID
+	}
+	comment.ReplyToUserID = parent.UserID
+}
+
+// saveComment 创建评论的同时解析评论中的@，二者在同一个事务中完成
+func saveComment(db *gorm.DB, comment *models.Comment) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(comment).Error; err != nil {
+			return err
+		}
+		return mention.Sync(tx, models.MentionComment, comment.ID, comment.VideoID, comment.UserID, comment.Content)
+	})
+}
+
+// PublishReviewed 发布审核通过的评论。被回复的评论在审核期间被删除时不再发布
+func PublishReviewed(db *gorm.DB, review models.ContentReview) error {
+	var video models.Video
+	if err := db.First(&video, review.TargetID).Error; err != nil {
+		return err
+	}
+	comment := models.Comment{
+		UserID:    review.UserID,
+		VideoID:   video.ID,
+		Content:   review.Content,
+		CreatedAt: time.Now(),
+	}
+	if review.ParentID > 0 {
+		var parent models.Comment
+		if err := db.Where("id = ? AND video_id = ? AND is_deleted = ?", review.ParentID, video.ID, false).
+			First(&parent).Error; err != nil {
+			return err
+		}
+		setParent(&comment, parent)
+	}
+	return saveComment(db, &comment)
+}
+
 func Action(c *gin.Context) {
 	// 验证user_id
 	tokenString := c.DefaultQuery("token", "")
@@ -56,6 +105,23 @@ func Action(c *gin.Context) {
 	actionType := c.DefaultQuery("action_type", "")
 	switch actionType {
 	case "1": // 评论
+		// 验证视频的评论设置
+		allowed, reason, err := checkCommentPolicy(db, userId, video)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, utils.CommentResponse{
+				StatusCode: 1,
+				StatusMsg:  "Failed to check comment settings.",
+			})
+			return
+		}
+		if !allowed {
+			c.JSON(http.StatusForbidden, utils.CommentResponse{
+				StatusCode: 1,
+				StatusMsg:  reason,
+			})
+			return
+		}
+
 		commentText := c.DefaultQuery("comment_text", "")
 		// 验证评论长度
 		if len(commentText) == 0 {
@@ -72,6 +138,15 @@ func Action(c *gin.Context) {
 			})
 			return
 		}
+		// 敏感词过滤
+		commentText, verdict := filter.Apply(db, userId, filter.SourceComment, commentText)
+		if verdict == filter.Reject {
+			c.JSON(http.StatusBadRequest, utils.CommentResponse{
+				StatusCode: 1,
+				StatusMsg:  "Comment contains sensitive words.",
+			})
+			return
+		}
 
 		// 在数据库中创建评论
 		comment := models.Comment{
@@ -80,8 +155,51 @@ func Action(c *gin.Context) {
 			Content:   commentText,
 			CreatedAt: time.Now(),
 		}
-		result := db.Create(&comment)
-		if result.Error != nil {
+
+		// 回复评论时，需要验证被回复的评论属于这个视频并且没有被删除
+		parentIdInt, err := strconv.Atoi(c.DefaultQuery("parent_id", "0"))
+		if err != nil || parentIdInt < 0 {
+			c.JSON(http.StatusBadRequest, utils.CommentResponse{
+				StatusCode: 1,
+				StatusMsg:  "Invalid parent ID.",
+			})
+			return
+		}
+		if parentIdInt > 0 {
+			var parent models.Comment
+			if err := db.Where("id = ? AND video_id = ?", parentIdInt, videoIdInt).
+				First(&parent).Error; err != nil {
+				c.JSON(http.StatusNotFound, utils.CommentResponse{
+					StatusCode: 1,
+					StatusMsg:  "Parent comment not found.",
+				})
+				return
+			}
+			if parent.IsDeleted {
+				c.JSON(http.StatusBadRequest, utils.CommentResponse{
+					StatusCode: 1,
+					StatusMsg:  "You can't reply to a deleted comment.",
+				})
+				return
+			}
+			setParent(&comment, parent)
+		}
+		// 需要人工审核的评论先加入审核队列，审核通过之后才发布
+		if verdict == filter.Hold {
+			if err := filter.Enqueue(db, userId, filter.SourceComment, video.ID, comment.ParentID, commentText); err != nil {
+				c.JSON(http.StatusInternalServerError, utils.CommentResponse{
+					StatusCode: 1,
+					StatusMsg:  "Failed to comment.",
+				})
+				return
+			}
+			c.JSON(http.StatusAccepted, utils.CommentResponse{
+				StatusCode: 0,
+				StatusMsg:  "Comment is pending review.",
+			})
+			return
+		}
+		if err := saveComment(db, &comment); err != nil {
 			c.JSON(http.StatusInternalServerError, utils.CommentResponse{
 				StatusCode: 1,
 				StatusMsg:  "Failed to comment.",
@@ -89,28 +207,12 @@ func Action(c *gin.Context) {
 			return
 		}
 
-		author := utils.UserResponse{
-			ID:            user.ID,
-			Name:          user.Username,
-			FollowCount:   user.Profile.FollowCount,
-			FollowerCount: user.Profile.FollowerCount,
-			// TODO: IsFollow
-			Avatar:         user.Profile.Avatar,
-			Background:     user.Profile.Background,
-			Signature:      user.Profile.Signature,
-			TotalFavorited: user.Profile.TotalFavorited,
-			WorkCount:      user.Profile.WorkCount,
-			FavoriteCount:  user.Profile.FavoriteCount,
-		}
+		comment.User = user
+		mentionSpans := utils.MentionSpans(db, models.MentionComment, []uint{comment.ID})
 		c.JSON(http.StatusOK, utils.CommentResponse{
 			StatusCode: 0,
 			StatusMsg:  "Successfully commented.",
-			Comment: &utils.CommentResItem{
-				ID:         comment.ID,
-				User:       author,
-				Content:    comment.Content,
-				CreateDate: comment.CreatedAt,
-			},
+			Comment:    buildCommentResItem(comment, nil, nil, mentionSpans),
 		})
 		return
 	case "2": // 删除评论
@@ -123,25 +225,33 @@ func Action(c *gin.Context) {
 			})
 			return
 		}
-		// 验证要删除的评论是否存在
+		// 验证要删除的评论是否存在，并且属于这个视频
 		var commentToDelete models.Comment
-		if err := db.First(&commentToDelete, commentId).Error; err != nil {
+		if err := db.Where("video_id = ? AND is_deleted = ?", video.ID, false).
+			First(&commentToDelete, commentId).Error; err != nil {
 			c.JSON(http.StatusNotFound, utils.CommentResponse{
 				StatusCode: 1,
 				StatusMsg:  "Target comment not found.",
 			})
 			return
 		}
-		// 验证要删除的评论的评论人是不是当前登录用户
-		if commentToDelete.UserID != userId {
+		// 评论人和视频发布者可以删除评论
+		if commentToDelete.UserID != userId && video.UserID != userId {
 			c.JSON(http.StatusForbidden, utils.CommentResponse{
 				StatusCode: 1,
 				StatusMsg:  "You do not have permission to delete this comment.",
 			})
 			return
 		}
-		// 删除评论
-		if err := db.Delete(&commentToDelete).Error; err != nil {
+		// 删除评论，被删除的评论如果是置顶评论则取消置顶
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&models.Video{}).
+				Where("id = ? AND pinned_comment_id = ?", video.ID, commentToDelete.ID).
+				Update("pinned_comment_id", 0).Error; err != nil {
+				return err
+			}
+			return deleteComment(tx, commentToDelete)
+		}); err != nil {
 			c.JSON(http.StatusInternalServerError, utils.CommentResponse{
 				StatusCode: 1,
 				StatusMsg:  "Failed to delete comment.",
@@ -161,7 +271,202 @@ func Action(c *gin.Context) {
 	})
 }
 
-// List 列出视频下面的所有评论（时间倒序）
+// hasReplies 查询评论下是否还有回复
+func hasReplies(tx *gorm.DB, comment models.Comment) (bool, error) {
+	query := tx.Model(&models.Comment{}).Where("parent_id = ?", comment.ID)
+	if comment.RootID == 0 { // 一级评论需要考虑整个楼层的回复
+		query = query.Or("root_id = ?", comment.ID)
+	}
+	var count int64
+	err := query.Count(&count).Error
+	return count > 0, err
+}
+
+// deleteComment 删除评论。仍有回复的评论替换为占位符，保留楼层结构；
+// 没有回复的评论直接删除，如果它回复的评论是占位符并且已经没有其它回复，一并删除
+func deleteComment(tx *gorm.DB, comment models.Comment) error {
+	replied, err := hasReplies(tx, comment)
+	if err != nil {
+		return err
+	}
+	if replied {
+		return comment.MarkDeleted(tx)
+	}
+	if err := tx.Delete(&comment).Error; err != nil {
+		return err
+	}
+	// 评论已经不存在了，点赞记录直接删除，不需要再更新点赞数
+	if err := tx.Session(&gorm.Session{SkipHooks: true}).
+		Where("comment_id = ?", comment.ID).Delete(&models.CommentLike{}).Error; err != nil {
+		return err
+	}
+
+	// 向上清理已经没有回复的占位符
+	parentId := comment.ParentID
+	for parentId != 0 {
+		var parent models.Comment
+		if err := tx.First(&parent, parentId).Error; err != nil {
+			return err
+		}
+		if !parent.IsDeleted {
+			return nil
+		}
+		if replied, err := hasReplies(tx, parent); err != nil || replied {
+			return err
+		}
+		if err := tx.Delete(&parent).Error; err != nil {
+			return err
+		}
+		parentId = parent.ParentID
+	}
+	return nil
+}
+
+// buildCommentResItem 将评论转换为返回给客户端的结构体，已删除的评论不返回评论者信息
+func buildCommentResItem(comment models.Comment, followedIdSet, likedIdSet map[uint]bool,
+	mentionSpans map[uint][]utils.MentionSpan) *utils.CommentResItem {
+	item := &utils.CommentResItem{
+		ID:            comment.ID,
+		Content:       comment.Content,
+		CreateDate:    comment.CreatedAt,
+		ParentID:      comment.ParentID,
+		RootID:        comment.RootID,
+		ReplyToUserID: comment.ReplyToUserID,
+		ReplyCount:    comment.ReplyCount,
+		LikeCount:     comment.LikeCount,
+		IsLiked:       likedIdSet[comment.ID],
+		IsDeleted:     comment.IsDeleted,
+		Mentions:      mentionSpans[comment.ID],
+	}
+	if !comment.IsDeleted {
+		item.User = utils.BuildUserResponse(comment.User, followedIdSet[comment.UserID])
+	}
+	return item
+}
+
+// commentIds 返回评论的ID列表
+func commentIds(comments []models.Comment) []uint {
+	var ids []uint
+	for _, c := range comments {
+		ids = append(ids, c.ID)
+	}
+	return ids
+}
+
+// commenterIds 返回评论者的ID列表
+func commenterIds(comments []models.Comment) []uint {
+	var ids []uint
+	for _, c := range comments {
+		ids = append(ids, c.UserID)
+	}
+	return ids
+}
+
+// likedCommentIdSet 查询 comments 中有哪些是当前用户点赞过的
+func likedCommentIdSet(db *gorm.DB, currentUserId uint, comments []models.Comment) map[uint]bool {
+	ids := commentIds(comments)
+	var likedIds []uint
+	if currentUserId > 0 && len(ids) > 0 {
+		db.Model(&models.CommentLike{}).
+			Where("user_id = ? AND comment_id IN ?", currentUserId, ids).
+			Pluck("comment_id", &likedIds)
+	}
+	var likedIdSet = make(map[uint]bool)
+	for _, id := range likedIds {
+		likedIdSet[id] = true
+	}
+	return likedIdSet
+}
+
+// followedUserIdSet 查询 comments 的评论者中有哪些是当前用户关注的
+func followedUserIdSet(db *gorm.DB, currentUserId uint, comments []models.Comment) map[uint]bool {
+	var commenterIdsSet = make(map[uint]bool)
+	for _, c := range comments {
+		commenterIdsSet[c.UserID] = true
+	}
+	var commenterIds []uint
+	for id := range commenterIdsSet {
+		commenterIds = append(commenterIds, id)
+	}
+	var followedIds []uint
+	if currentUserId > 0 && len(commenterIds) > 0 {
+		db.Table("relations").
+			Where("from_user_id = ? AND to_user_id IN ?", currentUserId, commenterIds).
+			Pluck("to_user_id", &followedIds)
+	}
+	var followedIdSet = make(map[uint]bool)
+	for _, id := range followedIds {
+		followedIdSet[id] = true
+	}
+	return followedIdSet
+}
+
+// hotScore 评论热度：点赞数和回复数（回复权重更高）随评论发布时间衰减。
+// 发布时间按快照时间而不是 NOW() 计算，同一次翻页的每一页都用第一页的快照时间
+const hotScore = "(like_count + 2 * reply_count + 1) / POW(TIMESTAMPDIFF(HOUR, created_at, ?) + 2, 1.5)"
+
+// formatHotCursor 生成 "快照时间毫秒_热度_ID" 格式的热度排序游标
+func formatHotCursor(snapshot time.Time, score float64, id uint) string {
+	return fmt.Sprintf("%d_%s_%d", snapshot.UnixMilli(), strconv.FormatFloat(score, 'g', -1, 64), id)
+}
+
+// parseHotCursor 解析 "快照时间毫秒_热度_ID" 格式的热度排序游标
+func parseHotCursor(cursor string) (time.Time, float64, uint, bool) {
+	parts := strings.SplitN(cursor, "_", 3)
+	if len(parts) != 3 {
+		return time.Time{}, 0, 0, false
+	}
+	ms, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || ms < 0 {
+		return time.Time{}, 0, 0, false
+	}
+	score, err := strconv.ParseFloat(parts[1], 64)
+	if err != nil {
+		return time.Time{}, 0, 0, false
+	}
+	id, err := strconv.ParseUint(parts[2], 10, 64)
+	if err != nil {
+		return time.Time{}, 0, 0, false
+	}
+	return time.UnixMilli(ms), score, uint(id), true
+}
+
+// listHotIds 按热度倒序、热度相同时按 ID 倒序列出一级评论的 ID，多取一条用来判断是否还有下一页。
+// 热度在快照时间下计算，游标是上一页最后一条评论的热度和 ID
+func listHotIds(db *gorm.DB, video models.Video, snapshot time.Time, cursor string,
+	cursorScore float64, cursorId uint, count int) ([]uint, string, bool, error) {
+	ranked := db.Model(&models.Comment{}).
+		Select("id, "+hotScore+" AS hot_score", snapshot).
+		Where("video_id = ? AND parent_id = 0 AND id <> ?", video.ID, video.PinnedCommentID)
+	query := db.Table("(?) AS ranked", ranked)
+	if cursor != "" {
+		query = query.Where("hot_score < ? OR (hot_score = ? AND id < ?)", cursorScore, cursorScore, cursorId)
+	}
+	var rows []struct {
+		ID       uint
+		HotScore float64
+	}
+	if err := query.Order("hot_score desc").Order("id desc").Limit(count + 1).Scan(&rows).Error; err != nil {
+		return nil, "", false, err
+	}
+	hasMore := len(rows) > count
+	if hasMore {
+		rows = rows[:count]
+	}
+	var nextCursor string
+	if hasMore {
+		last := rows[len(rows)-1]
+		nextCursor = formatHotCursor(snapshot, last.HotScore, last.ID)
+	}
+	var ids []uint
+	for _, row := range rows {
+		ids = append(ids, row.ID)
+	}
+	return ids, nextCursor, hasMore, nil
+}
+
+// List 分页列出视频下面的一级评论（按时间倒序或热度排序），每条评论附带回复数和最早的几条回复。
+// 发布者置顶的评论在第一页的最前面
 func List(c *gin.Context) {
 	// 验证video_id
 	videoId := c.DefaultQuery("video_id", "0")
@@ -186,10 +491,91 @@ func List(c *gin.Context) {
 		return
 	}
 
+	// sort 为 hot 时按热度排序，否则按时间倒序
+	sortBy := c.DefaultQuery("sort", "new")
+	if sortBy != "new" && sortBy != "hot" {
+		c.JSON(http.StatusBadRequest, utils.CommentListResponse{
+			StatusCode: 1,
+			StatusMsg:  "Invalid sort.",
+		})
+		return
+	}
+
+	// 验证每页数量
+	count, err := strconv.Atoi(c.DefaultQuery("count", strconv.Itoa(consts.DefaultCommentCount)))
+	if err != nil || count < 1 || count > consts.MaxCommentCount {
+		c.JSON(http.StatusBadRequest, utils.CommentListResponse{
+			StatusCode: 1,
+			StatusMsg:  "Invalid count.",
+		})
+		return
+	}
+
+	// 按时间排序时，游标为上一页最后一条评论的 "创建时间毫秒_ID"，
+	// 走 idx_video_comment_created 索引，创建时间相同的评论再按 ID 排序保证顺序稳定；
+	// 按热度排序时，游标为 "快照时间毫秒_热度_ID"，翻页期间新发布的评论不会挤占位置导致重复，
+	// 但点赞数和回复数在翻页期间变化的评论仍可能跨过游标，被重复返回或漏掉
+	// 置顶评论不参与分页，只在第一页最前面返回
+	query := db.Preload("User").Preload("User.Profile").
+		Where("video_id = ? AND parent_id = 0 AND id <> ?", videoId, video.PinnedCommentID)
+	cursor := c.DefaultQuery("cursor", "")
 	var commentList []models.Comment
-	result := db.Preload("User").Where("video_id = ?", videoId).
-		Find(&commentList).Order("created_at desc")
-	if result.Error != nil {
+	var nextCursor string
+	var hasMore bool
+	if sortBy == "hot" {
+		snapshot := time.UnixMilli(time.Now().UnixMilli())
+		var cursorScore float64
+		var cursorId uint
+		if cursor != "" {
+			var ok bool
+			snapshot, cursorScore, cursorId, ok = parseHotCursor(cursor)
+			if !ok {
+				c.JSON(http.StatusBadRequest, utils.CommentListResponse{
+					StatusCode: 1,
+					StatusMsg:  "Invalid cursor.",
+				})
+				return
+			}
+		}
+		var ids []uint
+		ids, nextCursor, hasMore, err = listHotIds(db, video, snapshot, cursor, cursorScore, cursorId, count)
+		if err == nil && len(ids) > 0 {
+			var comments []models.Comment
+			err = query.Where("id IN ?", ids).Find(&comments).Error
+			commentById := make(map[uint]models.Comment)
+			for _, comment := range comments {
+				commentById[comment.ID] = comment
+			}
+			for _, id := range ids {
+				if comment, ok := commentById[id]; ok {
+					commentList = append(commentList, comment)
+				}
+			}
+		}
+	} else {
+		if cursor != "" {
+			cursorTime, cursorId, ok := utils.ParseTimeCursor(cursor)
+			if !ok {
+				c.JSON(http.StatusBadRequest, utils.CommentListResponse{
+					StatusCode: 1,
+					StatusMsg:  "Invalid cursor.",
+				})
+				return
+			}
+			query = query.Where("created_at <= ? AND (created_at < ? OR id < ?)",
+				cursorTime, cursorTime, cursorId)
+		}
+
+		// 多取一条用来判断是否还有下一页
+		err = query.Order("created_at desc").Order("id desc").Limit(count + 1).Find(&commentList).Error
+		hasMore = len(commentList) > count
+		if hasMore {
+			commentList = commentList[:count]
+			last := commentList[len(commentList)-1]
+			nextCursor = utils.FormatTimeCursor(last.CreatedAt, last.ID)
+		}
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, utils.CommentListResponse{
 			StatusCode: 1,
 			StatusMsg:  "Failed to fetch comments",
@@ -197,51 +583,216 @@ func List(c *gin.Context) {
 		return
 	}
 
-	// 查询评论列表中有哪些评论者是当前用户关注的
-	tokenString := c.DefaultQuery("token", "")
-	currentUserId, _ := utils.ValidateToken(tokenString)
-	var commenterIdsSet = make(map[uint]bool)
-	for _, c := range commentList {
-		commenterIdsSet[c.UserID] = true
+	if cursor == "" && video.PinnedCommentID != 0 {
+		var pinned models.Comment
+		if err := db.Preload("User").Preload("User.Profile").
+			First(&pinned, video.PinnedCommentID).Error; err == nil {
+			commentList = append([]models.Comment{pinned}, commentList...)
+		}
 	}
-	var commenterIds []uint
-	for id := range commenterIdsSet {
-		commenterIds = append(commenterIds, id)
+
+	// 用窗口函数一次查出每个楼层最早的 MaxPreviewReplies 条回复
+	var rootIds []uint
+	for _, comment := range commentList {
+		if comment.ReplyCount > 0 || comment.IsDeleted {
+			rootIds = append(rootIds, comment.ID)
+		}
 	}
-	var followedIds []uint
-	db.Table("relations").
-		Where("from_user_id = ? AND to_user_id IN ?", currentUserId, commenterIds).
-		Pluck("to_user_id", &followedIds)
-	var followedIdSet = make(map[uint]bool)
-	for _, id := range followedIds {
-		followedIdSet[id] = true
+	var replies []models.Comment
+	if len(rootIds) > 0 {
+		var previewIds []uint
+		ranked := db.Model(&models.Comment{}).
+			Select("id, ROW_NUMBER() OVER (PARTITION BY root_id ORDER BY created_at, id) AS rn").
+			Where("root_id IN ?", rootIds)
+		db.Table("(?) AS ranked", ranked).Where("rn <= ?", consts.MaxPreviewReplies).
+			Pluck("id", &previewIds)
+		if len(previewIds) > 0 {
+			db.Preload("User").Preload("User.Profile").
+				Where("id IN ?", previewIds).Order("created_at").Order("id").Find(&replies)
+		}
+	}
+	repliesByRoot := make(map[uint][]models.Comment)
+	for _, reply := range replies {
+		repliesByRoot[reply.RootID] = append(repliesByRoot[reply.RootID], reply)
 	}
 
+	// 查询评论列表中有哪些评论者是当前用户关注的
+	tokenString := c.DefaultQuery("token", "")
+	currentUserId, _ := utils.ValidateToken(tokenString)
+	allComments := append(append([]models.Comment{}, commentList...), replies...)
+	followedIdSet := followedUserIdSet(db, currentUserId, allComments)
+	likedIdSet := likedCommentIdSet(db, currentUserId, allComments)
+	mentionSpans := utils.MentionSpans(db, models.MentionComment, commentIds(allComments))
+	mutedIdSet := policy.MutedSet(db, currentUserId, commenterIds(allComments))
+
 	var commentListResponses []utils.CommentResItem
 	for _, comment := range commentList {
-		isFollowed := followedIdSet[comment.UserID]
-		commentListResponses = append(commentListResponses, utils.CommentResItem{
-			ID: comment.ID,
-			User: utils.UserResponse{
-				ID:             comment.User.ID,
-				Name:           comment.User.Username,
-				FollowCount:    comment.User.Profile.FollowCount,
-				FollowerCount:  comment.User.Profile.FollowerCount,
-				IsFollow:       isFollowed,
-				Avatar:         comment.User.Profile.Avatar,
-				Background:     comment.User.Profile.Background,
-				Signature:      comment.User.Profile.Signature,
-				TotalFavorited: comment.User.Profile.TotalFavorited,
-				WorkCount:      comment.User.Profile.WorkCount,
-				FavoriteCount:  comment.User.Profile.FavoriteCount,
-			},
-			Content:    comment.Content,
-			CreateDate: comment.CreatedAt,
-		})
+		item := buildCommentResItem(comment, followedIdSet, likedIdSet, mentionSpans)
+		item.IsPinned = comment.ID == video.PinnedCommentID
+		item.IsCollapsed = !comment.IsDeleted && mutedIdSet[comment.UserID]
+		for _, reply := range repliesByRoot[comment.ID] {
+			replyItem := buildCommentResItem(reply, followedIdSet, likedIdSet, mentionSpans)
+			replyItem.IsCollapsed = !reply.IsDeleted && mutedIdSet[reply.UserID]
+			item.Replies = append(item.Replies, *replyItem)
+		}
+		commentListResponses = append(commentListResponses, *item)
 	}
 	c.JSON(http.StatusOK, utils.CommentListResponse{
 		StatusCode:  0,
 		StatusMsg:   "Success",
 		CommentList: &commentListResponses,
+		NextCursor:  nextCursor,
+		HasMore:     hasMore,
+	})
+}
+
+// Replies 按时间顺序分页列出一个楼层的回复，cursor 为上一页返回的 next_cursor
+func Replies(c *gin.Context) {
+	// 验证 comment_id
+	commentIdInt, err := strconv.Atoi(c.DefaultQuery("comment_id", "0"))
+	if err != nil || commentIdInt < 1 {
+		c.JSON(http.StatusBadRequest, utils.CommentRepliesResponse{
+			StatusCode: 1,
+			StatusMsg:  "Invalid comment ID.",
+		})
+		return
+	}
+
+	db := c.MustGet("db").(*gorm.DB)
+
+	// 验证楼层是否存在，comment_id 需要是一级评论
+	var root models.Comment
+	if err := db.Where("root_id = 0").First(&root, commentIdInt).Error; err != nil {
+		c.JSON(http.StatusNotFound, utils.CommentRepliesResponse{
+			StatusCode: 1,
+			StatusMsg:  "Comment not found.",
+		})
+		return
+	}
+
+	// 回复按时间正序排列，游标为上一页最后一条回复的 "创建时间毫秒_ID"，
+	// 走 idx_root_comment 索引，创建时间相同的回复再按 ID 排序
+	query := db.Preload("User").Preload("User.Profile").Where("root_id = ?", root.ID)
+	if cursor := c.DefaultQuery("cursor", ""); cursor != "" {
+		cursorTime, cursorId, ok := utils.ParseTimeCursor(cursor)
+		if !ok {
+			c.JSON(http.StatusBadRequest, utils.CommentRepliesResponse{
+				StatusCode: 1,
+				StatusMsg:  "Invalid cursor.",
+			})
+			return
+		}
+		query = query.Where("created_at >= ? AND (created_at > ? OR id > ?)", cursorTime, cursorTime, cursorId)
+	}
+
+	// 多取一条判断是否还有下一页
+	var replies []models.Comment
+	if err := query.Order("created_at").Order("id").
+		Limit(consts.MaxRepliesCount + 1).Find(&replies).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.CommentRepliesResponse{
+			StatusCode: 1,
+			StatusMsg:  "Failed to fetch replies.",
+		})
+		return
+	}
+	hasMore := len(replies) > consts.MaxRepliesCount
+	if hasMore {
+		replies = replies[:consts.MaxRepliesCount]
+	}
+
+	tokenString := c.DefaultQuery("token", "")
+	currentUserId, _ := utils.ValidateToken(tokenString)
+	followedIdSet := followedUserIdSet(db, currentUserId, replies)
+	likedIdSet := likedCommentIdSet(db, currentUserId, replies)
+	mentionSpans := utils.MentionSpans(db, models.MentionComment, commentIds(replies))
+	mutedIdSet := policy.MutedSet(db, currentUserId, commenterIds(replies))
+
+	var replyList []utils.CommentResItem
+	for _, reply := range replies {
+		item := buildCommentResItem(reply, followedIdSet, likedIdSet, mentionSpans)
+		item.IsCollapsed = !reply.IsDeleted && mutedIdSet[reply.UserID]
+		replyList = append(replyList, *item)
+	}
+
+	var nextCursor string
+	if hasMore {
+		last := replies[len(replies)-1]
+		nextCursor = utils.FormatTimeCursor(last.CreatedAt, last.ID)
+	}
+
+	c.JSON(http.StatusOK, utils.CommentRepliesResponse{
+		StatusCode: 0,
+		StatusMsg:  "Success",
+		NextCursor: nextCursor,
+		HasMore:    hasMore,
+		ReplyList:  replyList,
+	})
+}
+
+// Like 点赞/取消点赞评论，action_type 为 1 时点赞，为 2 时取消点赞，重复操作不会报错
+func Like(c *gin.Context) {
+	// 验证user_id
+	tokenString := c.DefaultQuery("token", "")
+	userId, err := utils.ValidateToken(tokenString)
+	if err != nil || userId <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status_code": 1,
+			"status_msg":  "Invalid User ID.",
+		})
+		return
+	}
+
+	// 验证comment_id
+	commentIdInt, err := strconv.Atoi(c.DefaultQuery("comment_id", "0"))
+	if err != nil || commentIdInt < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status_code": 1,
+			"status_msg":  "Invalid comment ID.",
+		})
+		return
+	}
+
+	db := c.MustGet("db").(*gorm.DB)
+
+	// 验证评论是否存在
+	var comment models.Comment
+	if err := db.Where("is_deleted = ?", false).First(&comment, commentIdInt).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status_code": 1,
+			"status_msg":  "Comment not found.",
+		})
+		return
+	}
+
+	like := models.CommentLike{UserID: userId, CommentID: comment.ID}
+	switch c.DefaultQuery("action_type", "") {
+	case "1": // 点赞，利用 idx_user_comment 唯一索引保证幂等
+		if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&like).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"status_code": 1,
+				"status_msg":  "Failed to like comment.",
+			})
+			return
+		}
+	case "2": // 取消点赞
+		if err := db.Where("user_id = ? AND comment_id = ?", userId, comment.ID).
+			Delete(&like).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"status_code": 1,
+				"status_msg":  "Failed to unlike comment.",
+			})
+			return
+		}
+	default: // action_type 不合法
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status_code": 1,
+			"status_msg":  "Invalid action type.",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status_code": 0,
+		"status_msg":  "Success",
 	})
 }
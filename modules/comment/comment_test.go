@@ -0,0 +1,405 @@
+package comment
+
+import (
+	"app/config"
+	"app/consts"
+	"app/modules/models"
+	"app/utils"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	utils.Setup()
+	postSetup()
+	code := m.Run()
+	utils.Teardown()
+	os.Exit(code)
+}
+
+var ActionUrl = "/douyin/comment/action/"
+var ListUrl = "/douyin/comment/list/"
+var RepliesUrl = "/douyin/comment/replies/"
+var LikeUrl = "/douyin/comment/like/"
+var PinUrl = "/douyin/comment/pin/"
+var SettingsUrl = "/douyin/comment/settings/"
+var db = utils.GetDb()
+var authorId, aliceId, bobId uint
+
+func postSetup() {
+	var ids []uint
+	for _, name := range []string{"author_c", "alice_c", "bob_c"} {
+		user := models.User{Username: name, Password: name + "_pass"}
+		db.Create(&user)
+		ids = append(ids, user.ID)
+	}
+	authorId, aliceId, bobId = ids[0], ids[1], ids[2]
+
+	config.Router.POST(ActionUrl, Action)
+	config.Router.GET(ListUrl, List)
+	config.Router.GET(RepliesUrl, Replies)
+	config.Router.POST(LikeUrl, Like)
+	config.Router.POST(PinUrl, Pin)
+	config.Router.POST(SettingsUrl, Settings)
+}
+
+// newVideo 为每个测试创建单独的视频，互不影响
+func newVideo(title string) models.Video {
+	video := models.Video{UserID: authorId, Title: title, PublishTime: time.Now()}
+	db.Create(&video)
+	return video
+}
+
+func request(method, path string, userId uint, values url.Values) *httptest.ResponseRecorder {
+	token, _ := utils.GenerateToken(userId)
+	values.Set("token", token)
+	req, _ := http.NewRequest(method, path+"?"+values.Encode(), nil)
+	response := httptest.NewRecorder()
+	config.Router.ServeHTTP(response, req)
+	return response
+}
+
+// comment 发布评论，parentId 为 0 时发布一级评论
+func comment(t *testing.T, userId uint, video models.Video, parentId uint, text string) utils.CommentResItem {
+	response := request("POST", ActionUrl, userId, url.Values{
+		"video_id":     {strconv.Itoa(int(video.ID))},
+		"action_type":  {"1"},
+		"comment_text": {text},
+		"parent_id":    {strconv.Itoa(int(parentId))},
+	})
+	assert.Equal(t, http.StatusOK, response.Code)
+	var resp utils.CommentResponse
+	if err := json.Unmarshal(response.Body.Bytes(), &resp); err != nil || resp.Comment == nil {
+		t.Fatal("failed to comment")
+	}
+	return *resp.Comment
+}
+
+func listComments(t *testing.T, video models.Video, values url.Values) utils.CommentListResponse {
+	values.Set("video_id", strconv.Itoa(int(video.ID)))
+	response := request("GET", ListUrl, aliceId, values)
+	assert.Equal(t, http.StatusOK, response.Code)
+	var resp utils.CommentListResponse
+	if err := json.Unmarshal(response.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.CommentList == nil { // 没有评论时 comment_list 为 null
+		resp.CommentList = &[]utils.CommentResItem{}
+	}
+	return resp
+}
+
+// 测试回复的楼层结构、楼层回复列表和删除后占位符的清理
+func TestReplyThreading(t *testing.T) {
+	video := newVideo("threading")
+	root := comment(t, aliceId, video, 0, "first")
+	reply := comment(t, bobId, video, root.ID, "reply to alice")
+	nested := comment(t, aliceId, video, reply.ID, "reply to bob")
+
+	assert.Equal(t, root.ID, reply.RootID)
+	assert.Equal(t, aliceId, reply.ReplyToUserID)
+	assert.Equal(t, root.ID, nested.RootID)
+	assert.Equal(t, reply.ID, nested.ParentID)
+	assert.Equal(t, bobId, nested.ReplyToUserID)
+
+	// 回复不存在的评论
+	response := request("POST", ActionUrl, bobId, url.Values{
+		"video_id": {strconv.Itoa(int(video.ID))}, "action_type": {"1"},
+		"comment_text": {"hello"}, "parent_id": {"100000"},
+	})
+	assert.Equal(t, http.StatusNotFound, response.Code)
+
+	// 一级评论列表只有楼层本身，附带回复数和回复
+	list := *listComments(t, video, url.Values{}).CommentList
+	assert.Len(t, list, 1)
+	assert.Equal(t, 2, list[0].ReplyCount)
+	assert.Len(t, list[0].Replies, 2)
+
+	// 楼层回复按时间正序排列，只能查询一级评论的回复
+	response = request("GET", RepliesUrl, aliceId, url.Values{"comment_id": {strconv.Itoa(int(root.ID))}})
+	assert.Equal(t, http.StatusOK, response.Code)
+	var replies utils.CommentRepliesResponse
+	json.Unmarshal(response.Body.Bytes(), &replies)
+	assert.Len(t, replies.ReplyList, 2)
+	assert.Equal(t, reply.ID, replies.ReplyList[0].ID)
+	assert.Equal(t, nested.ID, replies.ReplyList[1].ID)
+	response = request("GET", RepliesUrl, aliceId, url.Values{"comment_id": {strconv.Itoa(int(reply.ID))}})
+	assert.Equal(t, http.StatusNotFound, response.Code)
+
+	// 删除仍有回复的一级评论，替换为占位符
+	deleteComment := func(userId uint, commentId uint) {
+		response := request("POST", ActionUrl, userId, url.Values{
+			"video_id": {strconv.Itoa(int(video.ID))}, "action_type": {"2"},
+			"comment_id": {strconv.Itoa(int(commentId))},
+		})
+		assert.Equal(t, http.StatusOK, response.Code)
+	}
+	deleteComment(aliceId, root.ID)
+	list = *listComments(t, video, url.Values{}).CommentList
+	assert.Len(t, list, 1)
+	assert.True(t, list[0].IsDeleted)
+	assert.Equal(t, models.DeletedCommentContent, list[0].Content)
+
+	// 删除所有回复后占位符也被删除
+	deleteComment(aliceId, nested.ID)
+	deleteComment(bobId, reply.ID)
+	assert.Len(t, *listComments(t, video, url.Values{}).CommentList, 0)
+	var count int64
+	db.Model(&models.Comment{}).Where("video_id = ?", video.ID).Count(&count)
+	assert.Equal(t, int64(0), count)
+}
+
+// 测试创建时间相同的回复在分页边界上不会重复或漏掉
+func TestRepliesTiedCreatedAt(t *testing.T) {
+	video := newVideo("tied replies")
+	root := comment(t, aliceId, video, 0, "root")
+	createdAt := time.Now().Truncate(time.Millisecond)
+	total := consts.MaxRepliesCount + 5
+	for i := 0; i < total; i++ {
+		db.Create(&models.Comment{UserID: bobId, VideoID: video.ID, ParentID: root.ID, RootID: root.ID,
+			ReplyToUserID: aliceId, Content: "tied", CreatedAt: createdAt})
+	}
+
+	seen := make(map[uint]bool)
+	values := url.Values{"comment_id": {strconv.Itoa(int(root.ID))}}
+	for page := 0; page < 3; page++ {
+		response := request("GET", RepliesUrl, aliceId, values)
+		assert.Equal(t, http.StatusOK, response.Code)
+		var resp utils.CommentRepliesResponse
+		json.Unmarshal(response.Body.Bytes(), &resp)
+		for _, reply := range resp.ReplyList {
+			assert.False(t, seen[reply.ID])
+			seen[reply.ID] = true
+		}
+		if !resp.HasMore {
+			break
+		}
+		values.Set("cursor", resp.NextCursor)
+	}
+	assert.Equal(t, total, len(seen))
+
+	values.Set("cursor", "abc")
+	assert.Equal(t, http.StatusBadRequest, request("GET", RepliesUrl, aliceId, values).Code)
+}
+
+// 测试重复点赞和重复取消点赞不会重复计数
+func TestLikeIdempotent(t *testing.T) {
+	video := newVideo("likes")
+	target := comment(t, aliceId, video, 0, "like me")
+	like := func(actionType string) {
+		response := request("POST", LikeUrl, bobId, url.Values{
+			"comment_id": {strconv.Itoa(int(target.ID))}, "action_type": {actionType},
+		})
+		assert.Equal(t, http.StatusOK, response.Code)
+	}
+	likeCount := func() int {
+		var c models.Comment
+		db.First(&c, target.ID)
+		return c.LikeCount
+	}
+
+	like("1")
+	like("1")
+	assert.Equal(t, 1, likeCount())
+	var likes int64
+	db.Model(&models.CommentLike{}).Where("comment_id = ?", target.ID).Count(&likes)
+	assert.Equal(t, int64(1), likes)
+
+	like("2")
+	like("2")
+	assert.Equal(t, 0, likeCount())
+}
+
+// 测试按时间倒序和按热度排序
+func TestListOrder(t *testing.T) {
+	video := newVideo("order")
+	older := models.Comment{UserID: aliceId, VideoID: video.ID, Content: "older", CreatedAt: time.Now().Add(-time.Minute)}
+	newer := models.Comment{UserID: bobId, VideoID: video.ID, Content: "newer", CreatedAt: time.Now()}
+	db.Create(&older)
+	db.Create(&newer)
+	for _, userId := range []uint{authorId, bobId} {
+		response := request("POST", LikeUrl, userId, url.Values{
+			"comment_id": {strconv.Itoa(int(older.ID))}, "action_type": {"1"},
+		})
+		assert.Equal(t, http.StatusOK, response.Code)
+	}
+
+	list := *listComments(t, video, url.Values{"sort": {"new"}}).CommentList
+	assert.Equal(t, []uint{newer.ID, older.ID}, []uint{list[0].ID, list[1].ID})
+	list = *listComments(t, video, url.Values{"sort": {"hot"}}).CommentList
+	assert.Equal(t, []uint{older.ID, newer.ID}, []uint{list[0].ID, list[1].ID})
+	assert.Equal(t, 2, list[0].LikeCount)
+
+	// 按热度排序时游标为 "快照时间毫秒_热度_ID"，翻页期间新发布的热门评论不会让上一页的评论重复出现
+	resp := listComments(t, video, url.Values{"sort": {"hot"}, "count": {"1"}})
+	assert.True(t, resp.HasMore)
+	assert.Equal(t, older.ID, (*resp.CommentList)[0].ID)
+	_, _, cursorId, ok := parseHotCursor(resp.NextCursor)
+	assert.True(t, ok)
+	assert.Equal(t, older.ID, cursorId)
+	db.Create(&models.Comment{UserID: aliceId, VideoID: video.ID, Content: "hottest", LikeCount: 100})
+	resp = listComments(t, video, url.Values{"sort": {"hot"}, "count": {"1"}, "cursor": {resp.NextCursor}})
+	assert.Len(t, *resp.CommentList, 1)
+	assert.Equal(t, newer.ID, (*resp.CommentList)[0].ID)
+	assert.False(t, resp.HasMore)
+
+	// 旧的偏移量游标不再有效
+	response := request("GET", ListUrl, aliceId, url.Values{
+		"video_id": {strconv.Itoa(int(video.ID))}, "sort": {"hot"}, "cursor": {"1"},
+	})
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+}
+
+// 测试创建时间相同的评论在分页边界上不会重复或漏掉
+func TestListTiedCreatedAt(t *testing.T) {
+	video := newVideo("tied")
+	createdAt := time.Now().Truncate(time.Millisecond)
+	total := 5
+	for i := 0; i < total; i++ {
+		db.Create(&models.Comment{UserID: aliceId, VideoID: video.ID, Content: "tied", CreatedAt: createdAt})
+	}
+
+	seen := make(map[uint]bool)
+	values := url.Values{"count": {"2"}}
+	for page := 0; page < total; page++ {
+		resp := listComments(t, video, values)
+		for _, c := range *resp.CommentList {
+			assert.False(t, seen[c.ID])
+			seen[c.ID] = true
+		}
+		if !resp.HasMore {
+			break
+		}
+		values.Set("cursor", resp.NextCursor)
+	}
+	assert.Equal(t, total, len(seen))
+
+	// 格式错误的游标
+	response := request("GET", ListUrl, aliceId, url.Values{
+		"video_id": {strconv.Itoa(int(video.ID))}, "cursor": {"abc"},
+	})
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+}
+
+// 测试置顶评论：只在第一页最前面出现一次，只能置顶一级评论，只有发布者可以置顶
+func TestPin(t *testing.T) {
+	video := newVideo("pin")
+	first := comment(t, aliceId, video, 0, "first")
+	second := comment(t, bobId, video, 0, "second")
+	third := comment(t, aliceId, video, 0, "third")
+	reply := comment(t, bobId, video, first.ID, "reply")
+	pin := func(userId uint, actionType string, commentId uint) int {
+		return request("POST", PinUrl, userId, url.Values{
+			"video_id":    {strconv.Itoa(int(video.ID))},
+			"action_type": {actionType},
+			"comment_id":  {strconv.Itoa(int(commentId))},
+		}).Code
+	}
+
+	assert.Equal(t, http.StatusOK, pin(authorId, "1", first.ID))
+	list := *listComments(t, video, url.Values{}).CommentList
+	assert.Len(t, list, 3)
+	assert.Equal(t, first.ID, list[0].ID)
+	assert.True(t, list[0].IsPinned)
+	assert.Equal(t, third.ID, list[1].ID)
+	assert.False(t, list[1].IsPinned)
+
+	// 置顶评论不参与分页，之后的页面不会重复出现
+	page := listComments(t, video, url.Values{"count": {"1"}})
+	assert.Len(t, *page.CommentList, 2)
+	assert.Equal(t, third.ID, (*page.CommentList)[1].ID)
+	assert.True(t, page.HasMore)
+	page = listComments(t, video, url.Values{"count": {"1"}, "cursor": {page.NextCursor}})
+	assert.Len(t, *page.CommentList, 1)
+	assert.Equal(t, second.ID, (*page.CommentList)[0].ID)
+	assert.False(t, page.HasMore)
+
+	// 不能置顶回复，其他用户不能置顶
+	assert.Equal(t, http.StatusBadRequest, pin(authorId, "1", reply.ID))
+	assert.Equal(t, http.StatusForbidden, pin(aliceId, "1", second.ID))
+
+	// 取消置顶后恢复按时间排序
+	assert.Equal(t, http.StatusOK, pin(authorId, "2", 0))
+	list = *listComments(t, video, url.Values{}).CommentList
+	assert.Len(t, list, 3)
+	assert.Equal(t, third.ID, list[0].ID)
+	assert.Equal(t, first.ID, list[2].ID)
+	assert.False(t, list[2].IsPinned)
+}
+
+// 测试评论权限：关闭评论后只有发布者可以评论，仅关注者可评论时需要关注发布者
+func TestCommentsDisabled(t *testing.T) {
+	video := newVideo("settings")
+	settings := func(userId uint, policy int) int {
+		return request("POST", SettingsUrl, userId, url.Values{
+			"video_id": {strconv.Itoa(int(video.ID))},
+			"policy":   {strconv.Itoa(policy)},
+		}).Code
+	}
+	post := func(userId uint) int {
+		return request("POST", ActionUrl, userId, url.Values{
+			"video_id":     {strconv.Itoa(int(video.ID))},
+			"action_type":  {"1"},
+			"comment_text": {"hello"},
+		}).Code
+	}
+
+	assert.Equal(t, http.StatusForbidden, settings(aliceId, models.CommentPolicyOff))
+	assert.Equal(t, http.StatusBadRequest, settings(authorId, 4))
+
+	assert.Equal(t, http.StatusOK, settings(authorId, models.CommentPolicyOff))
+	assert.Equal(t, http.StatusForbidden, post(aliceId))
+	assert.Equal(t, http.StatusOK, post(authorId))
+
+	assert.Equal(t, http.StatusOK, settings(authorId, models.CommentPolicyFollowers))
+	assert.Equal(t, http.StatusForbidden, post(aliceId))
+	relation := models.Relation{FromUserId: aliceId, ToUserId: authorId}
+	db.Create(&relation)
+	defer db.Delete(&relation)
+	assert.Equal(t, http.StatusOK, post(aliceId))
+	assert.Equal(t, http.StatusForbidden, post(bobId))
+}
+
+// 测试删除评论和回复时撤回通知，评论中@的通知也一起撤回
+func TestDeleteRetractsNotifications(t *testing.T) {
+	video := newVideo("retract")
+	notifications := func(userId uint, notifyType int, targetId uint) int64 {
+		var count int64
+		db.Model(&models.Notification{}).
+			Where("user_id = ? AND type = ? AND target_id = ?", userId, notifyType, targetId).Count(&count)
+		return count
+	}
+	deleteComment := func(userId uint, commentId uint) {
+		response := request("POST", ActionUrl, userId, url.Values{
+			"video_id": {strconv.Itoa(int(video.ID))}, "action_type": {"2"},
+			"comment_id": {strconv.Itoa(int(commentId))},
+		})
+		assert.Equal(t, http.StatusOK, response.Code)
+	}
+
+	root := comment(t, aliceId, video, 0, "hello @bob_c")
+	reply := comment(t, bobId, video, root.ID, "reply")
+	assert.Equal(t, int64(1), notifications(authorId, models.NotifyComment, video.ID))
+	assert.Equal(t, int64(1), notifications(aliceId, models.NotifyReply, root.ID))
+	assert.Equal(t, int64(1), notifications(bobId, models.NotifyMention, video.ID))
+
+	// 同一个用户在这个视频下还有其它评论时保留通知
+	other := comment(t, aliceId, video, 0, "again")
+	deleteComment(aliceId, other.ID)
+	assert.Equal(t, int64(1), notifications(authorId, models.NotifyComment, video.ID))
+
+	// 仍有回复的评论替换为占位符，同样撤回评论通知和@的通知
+	deleteComment(aliceId, root.ID)
+	assert.Equal(t, int64(0), notifications(authorId, models.NotifyComment, video.ID))
+	assert.Equal(t, int64(0), notifications(bobId, models.NotifyMention, video.ID))
+
+	deleteComment(bobId, reply.ID)
+	assert.Equal(t, int64(0), notifications(aliceId, models.NotifyReply, root.ID))
+}
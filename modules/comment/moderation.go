@@ -0,0 +1,186 @@
+package comment
+
+import (
+	"app/modules/models"
+	"app/modules/policy"
+	"app/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"net/http"
+	"strconv"
+)
+
+// checkCommentPolicy 根据视频的评论设置检查用户能否评论，不能评论时返回原因
+func checkCommentPolicy(db *gorm.DB, userId uint, video models.Video) (bool, string, error) {
+	// 发布者本人始终可以评论
+	if userId == video.UserID {
+		return true, "", nil
+	}
+
+	// 任意一方拉黑了另一方时不能评论
+	blocked, err := policy.IsBlocked(db, userId, video.UserID)
+	if err != nil {
+		return false, "", err
+	}
+	if blocked {
+		return false, "You can't comment on this video.", nil
+	}
+
+	switch video.CommentPolicy {
+	case models.CommentPolicyOff:
+		return false, "Comments are turned off for this video.", nil
+	case models.CommentPolicyFollowers:
+		var follows int64
+		if err := db.Model(&models.Relation{}).
+			Where("from_user_id = ? AND to_user_id = ?", userId, video.UserID).
+			Count(&follows).Error; err != nil {
+			return false, "", err
+		}
+		if follows == 0 {
+			return false, "Only followers of the author can comment on this video.", nil
+		}
+	case models.CommentPolicyFriends:
+		isFriend, err := models.IsFriend(db, userId, video.UserID)
+		if err != nil {
+			return false, "", err
+		}
+		if !isFriend {
+			return false, "Only friends of the author can comment on this video.", nil
+		}
+	}
+	return true, "", nil
+}
+
+// ownVideo 验证 token 和 video_id，并且视频需要是当前用户发布的，验证失败时已经返回了错误信息
+func ownVideo(c *gin.Context, db *gorm.DB) (models.Video, bool) {
+	var video models.Video
+	tokenString := c.DefaultQuery("token", "")
+	userId, err := utils.ValidateToken(tokenString)
+	if err != nil || userId <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status_code": 1,
+			"status_msg":  "Invalid User ID.",
+		})
+		return video, false
+	}
+
+	videoIdInt, err := strconv.Atoi(c.DefaultQuery("video_id", "0"))
+	if err != nil || videoIdInt < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status_code": 1,
+			"status_msg":  "Invalid Video ID.",
+		})
+		return video, false
+	}
+	if err := db.First(&video, videoIdInt).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status_code": 1,
+			"status_msg":  "Video not found.",
+		})
+		return video, false
+	}
+	if video.UserID != userId {
+		c.JSON(http.StatusForbidden, gin.H{
+			"status_code": 1,
+			"status_msg":  "Only the author can manage comments of this video.",
+		})
+		return video, false
+	}
+	return video, true
+}
+
+// Pin 视频发布者置顶/取消置顶评论，action_type 为 1 时置顶，为 2 时取消置顶。
+// 每个视频只能置顶一条一级评论，置顶新的评论会替换之前的置顶
+func Pin(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	video, ok := ownVideo(c, db)
+	if !ok {
+		return
+	}
+
+	switch c.DefaultQuery("action_type", "") {
+	case "1": // 置顶
+		commentIdInt, err := strconv.Atoi(c.DefaultQuery("comment_id", "0"))
+		if err != nil || commentIdInt < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"status_code": 1,
+				"status_msg":  "Invalid comment ID.",
+			})
+			return
+		}
+		var comment models.Comment
+		if err := db.Where("video_id = ? AND is_deleted = ?", video.ID, false).
+			First(&comment, commentIdInt).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"status_code": 1,
+				"status_msg":  "Comment not found.",
+			})
+			return
+		}
+		if comment.ParentID != 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"status_code": 1,
+				"status_msg":  "Only top-level comments can be pinned.",
+			})
+			return
+		}
+		if err := db.Model(&video).Update("pinned_comment_id", comment.ID).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"status_code": 1,
+				"status_msg":  "Failed to pin comment.",
+			})
+			return
+		}
+	case "2": // 取消置顶
+		if err := db.Model(&video).Update("pinned_comment_id", 0).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"status_code": 1,
+				"status_msg":  "Failed to unpin comment.",
+			})
+			return
+		}
+	default: // action_type 不合法
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status_code": 1,
+			"status_msg":  "Invalid action type.",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status_code": 0,
+		"status_msg":  "Success",
+	})
+}
+
+// Settings 视频发布者修改评论权限，policy 为 0 所有人可评论，1 仅关注者，2 仅互关好友，3 关闭评论。
+// 修改权限不影响已经发布的评论
+func Settings(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	video, ok := ownVideo(c, db)
+	if !ok {
+		return
+	}
+
+	commentPolicy, err := strconv.Atoi(c.DefaultQuery("policy", ""))
+	if err != nil || commentPolicy < models.CommentPolicyEveryone || commentPolicy > models.CommentPolicyOff {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status_code": 1,
+			"status_msg":  "Invalid policy.",
+		})
+		return
+	}
+
+	if err := db.Model(&video).Update("comment_policy", commentPolicy).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status_code": 1,
+			"status_msg":  "Failed to update comment settings.",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status_code": 0,
+		"status_msg":  "Success",
+	})
+}
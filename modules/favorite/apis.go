@@ -1,76 +1,70 @@
 package favorite
 
 import (
+	"app/consts"
 	"app/modules/models"
+	"app/modules/privacy"
 	"app/utils"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"net/http"
+	"sort"
 	"strconv"
 )
 
+// Action 点赞/取消点赞视频，action_type 为 1 时点赞，为 2 时取消点赞。
+// 操作是幂等的：依赖 idx_user_video 唯一索引，重复点赞和取消不会报错，也不会重复计数
 func Action(c *gin.Context) {
-	videoId := c.DefaultQuery("video_id", "0")
+	// 验证 user_id
 	tokenString := c.DefaultQuery("token", "")
-	actionType := c.DefaultQuery("action_type", "")
+	userId, err := utils.ValidateToken(tokenString)
+	if err != nil || userId <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status_code": 1,
+			"status_msg":  "Invalid user ID.",
+		})
+		return
+	}
 
-	// validate video_id
-	videoIdInt, err := strconv.Atoi(videoId)
+	// 验证 video_id
+	videoIdInt, err := strconv.Atoi(c.DefaultQuery("video_id", "0"))
 	if err != nil || videoIdInt < 1 {
 		c.JSON(http.StatusBadRequest, gin.H{
-			"status_code":    1,
-			"status_message": "Invalid video_id",
+			"status_code": 1,
+			"status_msg":  "Invalid video_id",
 		})
 		return
 	}
 
-	userId, _ := utils.ValidateToken(tokenString)
 	db := c.MustGet("db").(*gorm.DB)
 
-	// validate action type and perform action accordingly
-	switch actionType {
-	case "1": // Favorite
-		favorite := models.Favorite{
-			UserID:  userId,
-			VideoID: uint(videoIdInt),
-		}
-		// Check if this video has been liked by current user
-		var count int64
-		db.Model(&models.Favorite{}).Where("user_id = ? AND video_id = ?", userId, videoIdInt).Count(&count)
-		if count > 0 {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"status_code": 1,
-				"status_msg":  "Already favortited",
-			})
-			return
-		}
-		// Failed to like for some reason
-		if err := db.Create(&favorite).Error; err != nil {
+	// 验证视频是否存在
+	var video models.Video
+	if err := db.First(&video, videoIdInt).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status_code": 1,
+			"status_msg":  "Video not found.",
+		})
+		return
+	}
+
+	favorite := models.Favorite{
+		UserID:  userId,
+		VideoID: video.ID,
+	}
+	switch c.DefaultQuery("action_type", "") {
+	case "1": // 点赞，已经点赞过时不插入新记录，AfterCreate 也不会更新计数
+		if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&favorite).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"status_code": 1,
 				"status_msg":  "Failed to favorite",
 			})
 			return
 		}
-	case "2": // Un-favorite
-		// If current user hasn't liked this video, unlike is not allowed. TODO: any better method?
-		var count int64
-		db.Model(&models.Favorite{}).Where("user_id = ? AND video_id = ?", userId, videoIdInt).Count(&count)
-		if count == 0 {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"status_code": 1,
-				"status_msg":  "Not favorite yet",
-			})
-			return
-		}
-		// TODO: Cannot unlike other's like
-
-		favoriteToDelete := models.Favorite{
-			UserID:  userId,
-			VideoID: uint(videoIdInt),
-		}
-		if err := db.Where("user_id = ? AND video_id = ?", userId, videoIdInt).
-			Delete(&favoriteToDelete).Error; err != nil {
+	case "2": // 取消点赞，没有点赞过时不会删除记录，AfterDelete 也不会更新计数
+		if err := db.Where("user_id = ? AND video_id = ?", userId, video.ID).
+			Delete(&favorite).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"status_code": 1,
 				"status_msg":  "Failed to un-favorite",
@@ -107,9 +101,55 @@ func GetLikeVideos(c *gin.Context) {
 
 	db := c.MustGet("db").(*gorm.DB)
 
-	// Get all favorite records where user_id = userId
+	// 验证隐私设置
+	if !privacy.CheckList(c, db, uint(userId), privacy.FavoriteList) {
+		return
+	}
+
+	// 验证每页数量
+	count, err := strconv.Atoi(c.DefaultQuery("count", strconv.Itoa(consts.DefaultFavoriteCount)))
+	if err != nil || count < 1 || count > consts.MaxFavoriteCount {
+		c.JSON(http.StatusBadRequest, utils.FavoriteListResponse{
+			StatusCode: 1,
+			StatusMsg:  "Invalid count.",
+		})
+		return
+	}
+
+	// 按点赞时间倒序分页，游标为上一页最后一条点赞记录的 "点赞时间毫秒_ID"，走 idx_user_created 索引
+	query := db.Where("user_id = ?", userId)
+	if cursor := c.DefaultQuery("cursor", ""); cursor != "" {
+		cursorTime, cursorId, ok := utils.ParseTimeCursor(cursor)
+		if !ok {
+			c.JSON(http.StatusBadRequest, utils.FavoriteListResponse{
+				StatusCode: 1,
+				StatusMsg:  "Invalid cursor.",
+			})
+			return
+		}
+		query = query.Where("created_at <= ? AND (created_at < ? OR id < ?)",
+			cursorTime, cursorTime, cursorId)
+	}
+
+	// 多取一条用来判断是否还有下一页
 	var favorites []models.Favorite
-	db.Where("user_id = ?", userId).Order("created_at desc").Find(&favorites)
+	if err := query.Order("created_at desc").Order("id desc").
+		Limit(count + 1).Find(&favorites).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.FavoriteListResponse{
+			StatusCode: 1,
+			StatusMsg:  "Failed to fetch favorites.",
+		})
+		return
+	}
+	hasMore := len(favorites) > count
+	if hasMore {
+		favorites = favorites[:count]
+	}
+	var nextCursor string
+	if len(favorites) > 0 {
+		last := favorites[len(favorites)-1]
+		nextCursor = utils.FormatTimeCursor(last.CreatedAt, last.ID)
+	}
 
 	// Get video id list
 	var videoIds []uint
@@ -117,61 +157,30 @@ func GetLikeVideos(c *gin.Context) {
 		videoIds = append(videoIds, fav.VideoID)
 	}
 
-	// Get all videos liked by user id
+	// 查询当前页的视频，并按点赞时间排序
 	var videos []models.Video
-	db.Preload("User").Preload("User.Profile").
-		Where("id IN (?)", videoIds).Find(&videos)
-
-	// 查询视频列表中有哪些视频发布者是当前用户关注的
-	tokenString := c.DefaultQuery("token", "")
-	currentUserId, _ := utils.ValidateToken(tokenString)
-	var creatorIdsSet = make(map[uint]bool)
-	for _, v := range videos {
-		creatorIdsSet[v.UserID] = true
+	if len(videoIds) > 0 {
+		db.Preload("User").Preload("User.Profile").
+			Where("id IN (?)", videoIds).Find(&videos)
 	}
-	var creatorIds []uint
-	for id := range creatorIdsSet {
-		creatorIds = append(creatorIds, id)
-	}
-	var followedIds []uint
-	db.Table("relations").
-		Where("from_user_id = ? AND to_user_id IN ?", currentUserId, creatorIds).
-		Pluck("to_user_id", &followedIds)
-	var followedIdSet = make(map[uint]bool)
-	for _, id := range followedIds {
-		followedIdSet[id] = true
+	order := make(map[uint]int)
+	for i, id := range videoIds {
+		order[id] = i
 	}
+	sort.Slice(videos, func(i, j int) bool {
+		return order[videos[i].ID] < order[videos[j].ID]
+	})
 
-	var videoResList []utils.VideoResItem
-	for _, v := range videos {
-		isFollowed := followedIdSet[v.UserID]
-		videoResList = append(videoResList, utils.VideoResItem{
-			ID:            v.ID,
-			PlayUrl:       v.PlayUrl,
-			CoverUrl:      v.CoverUrl,
-			FavoriteCount: v.FavoriteCount,
-			CommentCount:  v.CommentCount,
-			Title:         v.Title,
-			IsFavorite:    true,
-			Author: utils.UserResponse{
-				ID:             v.User.ID,
-				Name:           v.User.Username,
-				IsFollow:       isFollowed,
-				Avatar:         v.User.Profile.Avatar,
-				Background:     v.User.Profile.Background,
-				Signature:      v.User.Profile.Signature,
-				FollowCount:    v.User.Profile.FollowCount,
-				FollowerCount:  v.User.Profile.FollowerCount,
-				TotalFavorited: v.User.Profile.TotalFavorited,
-				WorkCount:      v.User.Profile.WorkCount,
-				FavoriteCount:  v.User.Profile.FavoriteCount,
-			},
-		})
-	}
+	// is_favorite 和 is_follow 都相对于当前登录用户
+	tokenString := c.DefaultQuery("token", "")
+	currentUserId, _ := utils.ValidateToken(tokenString)
+	videoResList := utils.BuildVideoResList(db, videos, currentUserId)
 
-	c.JSON(http.StatusOK, utils.VideoResponse{
+	c.JSON(http.StatusOK, utils.FavoriteListResponse{
 		StatusCode: 0,
 		StatusMsg:  "Success",
+		NextCursor: nextCursor,
+		HasMore:    hasMore,
 		VideoList:  videoResList,
 	})
 }
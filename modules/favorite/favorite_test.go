@@ -0,0 +1,113 @@
+package favorite
+
+import (
+	"app/config"
+	"app/modules/models"
+	"app/utils"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	utils.Setup()
+	postSetup()
+	code := m.Run()
+	utils.Teardown()
+	os.Exit(code)
+}
+
+var ActionUrl = "/douyin/favorite/action/"
+var db = utils.GetDb()
+var authorId, fanId uint
+var videoId uint
+
+func postSetup() {
+	author := models.User{Username: "author_f", Password: "author_pass"}
+	db.Create(&author)
+	authorId = author.ID
+	fan := models.User{Username: "fan_f", Password: "fan_pass"}
+	db.Create(&fan)
+	fanId = fan.ID
+
+	video := models.Video{UserID: authorId, Title: "favorite video", PublishTime: time.Now()}
+	db.Create(&video)
+	videoId = video.ID
+
+	config.Router.POST(ActionUrl, Action)
+}
+
+func favoriteAction(token string, videoId uint, actionType string) int {
+	values := url.Values{}
+	values.Add("token", token)
+	values.Add("video_id", strconv.Itoa(int(videoId)))
+	values.Add("action_type", actionType)
+	req, _ := http.NewRequest("POST", ActionUrl+"?"+values.Encode(), nil)
+	response := httptest.NewRecorder()
+	config.Router.ServeHTTP(response, req)
+	return response.Code
+}
+
+// assertCounts 验证点赞记录数以及视频、发布者和点赞者的计数
+func assertCounts(t *testing.T, expected int) {
+	var rows int64
+	db.Model(&models.Favorite{}).Where("user_id = ? AND video_id = ?", fanId, videoId).Count(&rows)
+	assert.Equal(t, int64(expected), rows)
+
+	var video models.Video
+	db.First(&video, videoId)
+	assert.Equal(t, uint(expected), video.FavoriteCount)
+
+	var author, fan models.UserProfile
+	db.Where("user_id = ?", authorId).First(&author)
+	assert.Equal(t, expected, author.TotalFavorited)
+	db.Where("user_id = ?", fanId).First(&fan)
+	assert.Equal(t, expected, fan.FavoriteCount)
+}
+
+// 测试并发重复点赞和取消点赞，计数只变化一次
+func TestConcurrentAction(t *testing.T) {
+	token, err := utils.GenerateToken(fanId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, step := range []struct {
+		actionType string
+		expected   int
+	}{{"1", 1}, {"2", 0}} {
+		var wg sync.WaitGroup
+		codes := make([]int, 10)
+		for i := range codes {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				codes[i] = favoriteAction(token, videoId, step.actionType)
+			}(i)
+		}
+		wg.Wait()
+		for _, code := range codes {
+			assert.Equal(t, http.StatusOK, code)
+		}
+		assertCounts(t, step.expected)
+	}
+}
+
+// 测试无效的 token 和不存在的视频
+func TestInvalidAction(t *testing.T) {
+	assert.Equal(t, http.StatusBadRequest, favoriteAction("invalid", videoId, "1"))
+
+	token, err := utils.GenerateToken(fanId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, http.StatusNotFound, favoriteAction(token, videoId+1000, "1"))
+	assert.Equal(t, http.StatusBadRequest, favoriteAction(token, videoId, "3"))
+	assertCounts(t, 0)
+}
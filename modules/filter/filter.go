@@ -0,0 +1,237 @@
+package filter
+
+import (
+	"app/modules/models"
+	"bufio"
+	"fmt"
+	"gorm.io/gorm"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 用户生成内容的来源，记录在命中日志中
+const (
+	SourceComment    = "comment"
+	SourceMessage    = "message"
+	SourceTitle      = "title"
+	SourceSignature  = "signature"
+	SourceSearch     = "search"
+	SourceUsername   = "username"
+	SourceCollection = "collection"
+)
+
+// maxPinyinVariants 一个敏感词最多生成的拼音变体数，避免过长的词生成过多变体
+const maxPinyinVariants = 256
+
+var actionNames = map[string]int{
+	"mask":   ActionMask,
+	"review": ActionReview,
+	"reject": ActionReject,
+}
+
+// ParseWords 解析词库，每行格式为 "敏感词,分类,处理方式[,拼音]"，# 开头的行为注释。
+// 处理方式为 mask / review / reject。拼音为每个字的拼音，用空格分隔，
+// 会生成汉字和拼音混合的所有变体，例如 "赌博,gambling,reject,du bo"
+// 可以识别 "赌博"、"dubo"、"赌bo"、"du博"
+func ParseWords(lines []string) ([]Word, error) {
+	var words []Word
+	for n, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, ",")
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("line %d: expected word,category,action", n+1)
+		}
+		text := strings.TrimSpace(fields[0])
+		category := strings.TrimSpace(fields[1])
+		action, ok := actionNames[strings.TrimSpace(fields[2])]
+		if !ok {
+			return nil, fmt.Errorf("line %d: unknown action %q", n+1, fields[2])
+		}
+		variants := []string{text}
+		if len(fields) >= 4 {
+			syllables := strings.Fields(fields[3])
+			chars := []rune(text)
+			if len(syllables) != len(chars) {
+				return nil, fmt.Errorf("line %d: pinyin does not match the word", n+1)
+			}
+			variants = pinyinVariants(chars, syllables)
+		}
+		for _, v := range variants {
+			words = append(words, Word{Text: v, Category: category, Action: action})
+		}
+	}
+	return words, nil
+}
+
+// pinyinVariants 生成每个字分别用汉字或拼音表示的所有组合
+func pinyinVariants(chars []rune, syllables []string) []string {
+	variants := []string{""}
+	for i, ch := range chars {
+		if len(variants)*2 > maxPinyinVariants {
+			for j := range variants {
+				variants[j] += string(ch)
+			}
+			continue
+		}
+		next := make([]string, 0, len(variants)*2)
+		for _, v := range variants {
+			next = append(next, v+string(ch), v+syllables[i])
+		}
+		variants = next
+	}
+	return variants
+}
+
+var (
+	mu      sync.RWMutex
+	matcher = NewMatcher(nil)
+)
+
+// Load 从文件加载词库并替换当前使用的自动机
+func Load(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	words, err := ParseWords(lines)
+	if err != nil {
+		return err
+	}
+
+	m := NewMatcher(words)
+	mu.Lock()
+	matcher = m
+	mu.Unlock()
+	log.Printf("Loaded %d sensitive words from %s", len(words), path)
+	return nil
+}
+
+// StartReloader 加载词库，并定期检查文件的修改时间，文件变化后重新加载
+func StartReloader(path string, interval time.Duration) {
+	if err := Load(path); err != nil {
+		log.Printf("Failed to load sensitive words. Err: %s", err)
+	}
+	go func() {
+		var lastModified time.Time
+		if info, err := os.Stat(path); err == nil {
+			lastModified = info.ModTime()
+		}
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			info, err := os.Stat(path)
+			if err != nil || !info.ModTime().After(lastModified) {
+				continue
+			}
+			lastModified = info.ModTime()
+			// 加载失败时继续使用旧的词库
+			if err := Load(path); err != nil {
+				log.Printf("Failed to reload sensitive words. Err: %s", err)
+			}
+		}
+	}()
+}
+
+// Result 检测结果，Text 为处理后的文本，Action 为命中的最高优先级处理方式（0 表示未命中）
+type Result struct {
+	Text    string
+	Action  int
+	Matches []Match
+}
+
+// Check 检测文本中的敏感词
+func Check(text string) Result {
+	mu.RLock()
+	m := matcher
+	mu.RUnlock()
+
+	result := Result{Text: text, Matches: m.FindAll(text)}
+	var toMask []Match
+	for _, match := range result.Matches {
+		if match.Word.Action > result.Action {
+			result.Action = match.Word.Action
+		}
+		if match.Word.Action == ActionMask {
+			toMask = append(toMask, match)
+		}
+	}
+	if result.Action != ActionReject && len(toMask) > 0 {
+		result.Text = Mask(text, toMask)
+	}
+	return result
+}
+
+// Apply 的检测结论
+const (
+	Pass   = iota // 可以直接发布
+	Hold          // 需要加入审核队列，审核通过之前不能发布
+	Reject        // 不允许发布
+)
+
+// reviewSources 公开展示、命中 review 敏感词时需要人工审核的内容来源，
+// 其它来源（搜索词、用户名、收藏夹名）只记录日志后放行
+var reviewSources = map[string]bool{
+	SourceComment:   true,
+	SourceMessage:   true,
+	SourceTitle:     true,
+	SourceSignature: true,
+}
+
+// Apply 检测用户生成的文本并记录命中日志，返回处理后的文本以及检测结论
+func Apply(db *gorm.DB, userId uint, source string, text string) (string, int) {
+	result := Check(text)
+	if len(result.Matches) == 0 {
+		return text, Pass
+	}
+
+	var logs []models.ContentFilterLog
+	for _, match := range result.Matches {
+		logs = append(logs, models.ContentFilterLog{
+			UserID:   userId,
+			Source:   source,
+			Content:  text,
+			Word:     match.Word.Text,
+			Category: match.Word.Category,
+			Action:   match.Word.Action,
+			Rejected: result.Action == ActionReject,
+		})
+	}
+	if err := db.Create(&logs).Error; err != nil {
+		log.Printf("Failed to save content filter logs. Err: %s", err)
+	}
+
+	switch {
+	case result.Action == ActionReject:
+		return result.Text, Reject
+	case result.Action == ActionReview && reviewSources[source]:
+		return result.Text, Hold
+	}
+	return result.Text, Pass
+}
+
+// Enqueue 把需要人工审核的内容加入审核队列，targetId 和 parentId 的含义见 models.ContentReview
+func Enqueue(db *gorm.DB, userId uint, source string, targetId uint, parentId uint, content string) error {
+	return db.Create(&models.ContentReview{
+		UserID:   userId,
+		Source:   source,
+		TargetID: targetId,
+		ParentID: parentId,
+		Content:  content,
+	}).Error
+}
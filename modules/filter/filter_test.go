@@ -0,0 +1,86 @@
+package filter
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func newTestMatcher(t *testing.T, lines ...string) *Matcher {
+	words, err := ParseWords(lines)
+	assert.NoError(t, err)
+	return NewMatcher(words)
+}
+
+// 测试通过全角字符、插入空格和标点、大小写等方式绕过检测
+func TestFindAllNormalize(t *testing.T) {
+	m := newTestMatcher(t, "赌博,gambling,reject", "spam,spam,mask")
+
+	assert.Len(t, m.FindAll("一起赌博吗"), 1)
+	assert.Len(t, m.FindAll("一起赌 博吗"), 1)
+	assert.Len(t, m.FindAll("一起赌.*.博吗"), 1)
+	assert.Len(t, m.FindAll("ＳＰＡＭ"), 1)
+	assert.Len(t, m.FindAll("S p-A m"), 1)
+	assert.Empty(t, m.FindAll("一起赌球吗"))
+
+	// 命中的位置对应原文，包括中间插入的字符
+	matches := m.FindAll("一起赌 博吗")
+	assert.Equal(t, 2, matches[0].Start)
+	assert.Equal(t, 5, matches[0].End)
+}
+
+// 测试拼音变体
+func TestPinyinVariants(t *testing.T) {
+	m := newTestMatcher(t, "赌博,gambling,reject,du bo")
+
+	for _, text := range []string{"赌博", "dubo", "赌bo", "du博", "D u B o"} {
+		assert.Len(t, m.FindAll(text), 1, text)
+	}
+}
+
+// 测试不同的处理方式
+func TestCheck(t *testing.T) {
+	mu.Lock()
+	old := matcher
+	matcher = newTestMatcher(t, "赌博,gambling,reject", "傻瓜,abuse,mask", "加微信,spam,review")
+	mu.Unlock()
+	defer func() {
+		mu.Lock()
+		matcher = old
+		mu.Unlock()
+	}()
+
+	// 未命中
+	result := Check("今天天气不错")
+	assert.Equal(t, 0, result.Action)
+	assert.Equal(t, "今天天气不错", result.Text)
+
+	// 替换为 *，插入的空格也一起替换
+	result = Check("你这个傻 瓜")
+	assert.Equal(t, ActionMask, result.Action)
+	assert.Equal(t, "你这个***", result.Text)
+
+	// 待审核的内容不做替换，由 Apply 加入审核队列
+	result = Check("加微信聊")
+	assert.Equal(t, ActionReview, result.Action)
+	assert.Equal(t, "加微信聊", result.Text)
+
+	// 同时命中多个词时按最严格的处理
+	result = Check("傻瓜才去赌博")
+	assert.Equal(t, ActionReject, result.Action)
+	assert.Len(t, result.Matches, 2)
+}
+
+// 测试词库格式错误
+func TestParseWordsError(t *testing.T) {
+	_, err := ParseWords([]string{"赌博,gambling"})
+	assert.Error(t, err)
+	_, err = ParseWords([]string{"赌博,gambling,delete"})
+	assert.Error(t, err)
+	_, err = ParseWords([]string{"赌博,gambling,reject,du"})
+	assert.Error(t, err)
+
+	// 注释和空行会被忽略
+	words, err := ParseWords([]string{"# comment", "", "赌博,gambling,reject"})
+	assert.NoError(t, err)
+	assert.Len(t, words, 1)
+}
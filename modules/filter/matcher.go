@@ -0,0 +1,150 @@
+package filter
+
+import (
+	"unicode"
+)
+
+// 命中敏感词后的处理方式，数值越大优先级越高
+const (
+	ActionMask   = 1 // 用 * 替换敏感词后放行
+	ActionReview = 2 // 加入审核队列，审核通过之前不发布
+	ActionReject = 3 // 拒绝
+)
+
+// Word 词库中的一个敏感词
+type Word struct {
+	Text     string // 归一化之后的敏感词
+	Category string
+	Action   int
+}
+
+// Match 一次命中，Start/End 为在原文中的字符（rune）下标，End 不包含
+type Match struct {
+	Word  Word
+	Start int
+	End   int
+}
+
+// normalize 归一化文本，用来识别各种变体：全角转半角、英文转小写，
+// 并去掉空白、标点、符号和零宽字符，避免用插入字符的方式绕过。
+// 返回归一化后的字符以及每个字符在原文中的下标
+func normalize(text string) ([]rune, []int) {
+	var runes []rune
+	var positions []int
+	i := 0
+	for _, r := range text {
+		switch {
+		case r == 0x3000: // 全角空格
+			r = ' '
+		case r >= 0xFF01 && r <= 0xFF5E: // 全角字符
+			r -= 0xFEE0
+		}
+		if !unicode.IsSpace(r) && !unicode.IsPunct(r) && !unicode.IsSymbol(r) &&
+			!unicode.Is(unicode.Cf, r) {
+			runes = append(runes, unicode.ToLower(r))
+			positions = append(positions, i)
+		}
+		i++
+	}
+	return runes, positions
+}
+
+type acNode struct {
+	next    map[rune]int
+	fail    int
+	outputs []int // 在该节点结束的敏感词
+}
+
+// Matcher 基于 Aho-Corasick 自动机的多模式匹配，构建后只读，可以并发使用
+type Matcher struct {
+	nodes []acNode
+	words []Word
+}
+
+// NewMatcher 用敏感词构建自动机，敏感词会先经过和待检测文本相同的归一化
+func NewMatcher(words []Word) *Matcher {
+	m := &Matcher{nodes: []acNode{{next: make(map[rune]int)}}}
+	for _, w := range words {
+		runes, _ := normalize(w.Text)
+		if len(runes) == 0 {
+			continue
+		}
+		w.Text = string(runes)
+		node := 0
+		for _, r := range runes {
+			child, ok := m.nodes[node].next[r]
+			if !ok {
+				child = len(m.nodes)
+				m.nodes = append(m.nodes, acNode{next: make(map[rune]int)})
+				m.nodes[node].next[r] = child
+			}
+			node = child
+		}
+		m.nodes[node].outputs = append(m.nodes[node].outputs, len(m.words))
+		m.words = append(m.words, w)
+	}
+
+	// 按层构建失配指针
+	var queue []int
+	for _, child := range m.nodes[0].next {
+		queue = append(queue, child)
+	}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		for r, child := range m.nodes[node].next {
+			fail := m.nodes[node].fail
+			for fail != 0 {
+				if _, ok := m.nodes[fail].next[r]; ok {
+					break
+				}
+				fail = m.nodes[fail].fail
+			}
+			if target, ok := m.nodes[fail].next[r]; ok && target != child {
+				m.nodes[child].fail = target
+			}
+			m.nodes[child].outputs = append(m.nodes[child].outputs, m.nodes[m.nodes[child].fail].outputs...)
+			queue = append(queue, child)
+		}
+	}
+	return m
+}
+
+// FindAll 找出文本中所有命中的敏感词
+func (m *Matcher) FindAll(text string) []Match {
+	runes, positions := normalize(text)
+	var matches []Match
+	node := 0
+	for i, r := range runes {
+		for node != 0 {
+			if _, ok := m.nodes[node].next[r]; ok {
+				break
+			}
+			node = m.nodes[node].fail
+		}
+		if next, ok := m.nodes[node].next[r]; ok {
+			node = next
+		}
+		for _, idx := range m.nodes[node].outputs {
+			w := m.words[idx]
+			length := len([]rune(w.Text))
+			matches = append(matches, Match{
+				Word:  w,
+				Start: positions[i-length+1],
+				End:   positions[i] + 1,
+			})
+		}
+	}
+	return matches
+}
+
+// Mask 将命中的片段（包括其中插入的分隔字符）替换为 *
+func Mask(text string, matches []Match) string {
+	runes := []rune(text)
+	for _, match := range matches {
+		for i := match.Start; i < match.End; i++ {
+			runes[i] = '*'
+		}
+	}
+	return string(runes)
+}
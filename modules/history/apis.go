@@ -0,0 +1,312 @@
+package history
+
+import (
+	"app/consts"
+	"app/modules/models"
+	"app/utils"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Report 客户端播放过程中上报播放进度，记录最后观看时间和进度
+func Report(c *gin.Context) {
+	// 验证 user_id
+	tokenString := c.DefaultQuery("token", "")
+	userId, err := utils.ValidateToken(tokenString)
+	if err != nil || userId <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status_code": 1,
+			"status_msg":  "Invalid user ID.",
+		})
+		return
+	}
+
+	// 验证 video_id
+	videoIdString := c.DefaultQuery("video_id", "0")
+	videoIdInt, err := strconv.Atoi(videoIdString)
+	if err != nil || videoIdInt < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status_code": 1,
+			"status_msg":  "Invalid video_id.",
+		})
+		return
+	}
+
+	// 验证 position_ms
+	positionMs, err := strconv.ParseInt(c.DefaultQuery("position_ms", "0"), 10, 64)
+	if err != nil || positionMs < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status_code": 1,
+			"status_msg":  "Invalid position_ms.",
+		})
+		return
+	}
+
+	db := c.MustGet("db").(*gorm.DB)
+
+	// 用户暂停了观看历史，不做记录
+	var profile models.UserProfile
+	if err := db.Where("user_id = ?", userId).First(&profile).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status_code": 1,
+			"status_msg":  "User not found.",
+		})
+		return
+	}
+	if profile.HistoryPaused {
+		c.JSON(http.StatusOK, gin.H{
+			"status_code": 0,
+			"status_msg":  "History is paused.",
+		})
+		return
+	}
+
+	// 验证视频是否存在
+	var video models.Video
+	if err := db.First(&video, videoIdInt).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status_code": 1,
+			"status_msg":  "Video not found.",
+		})
+		return
+	}
+
+	// 利用 idx_user_video_history 唯一索引，存在则更新进度和观看时间，否则插入
+	record := models.WatchHistory{
+		UserID:     userId,
+		VideoID:    video.ID,
+		PositionMs: positionMs,
+		WatchedAt:  time.Now().UnixMilli(),
+	}
+	if err := db.Clauses(clause.OnConflict{
+		DoUpdates: clause.AssignmentColumns([]string{"position_ms", "watched_at"}),
+	}).Create(&record).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status_code": 1,
+			"status_msg":  "Failed to save watch history.",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status_code": 0,
+		"status_msg":  "Success",
+	})
+}
+
+// List 按最后观看时间倒序返回观看历史，cursor 为上一页返回的 next_cursor
+func List(c *gin.Context) {
+	// 验证 user_id
+	tokenString := c.DefaultQuery("token", "")
+	userId, err := utils.ValidateToken(tokenString)
+	if err != nil || userId <= 0 {
+		c.JSON(http.StatusBadRequest, utils.HistoryResponse{
+			StatusCode: 1,
+			StatusMsg:  "Invalid user ID.",
+		})
+		return
+	}
+
+	db := c.MustGet("db").(*gorm.DB)
+	query := db.Preload("Video").Preload("Video.User").Preload("Video.User.Profile").
+		Where("user_id = ?", userId)
+
+	// 游标为上一页最后一条记录的 "观看时间毫秒_ID"，为空时从最新的记录开始
+	if cursor := c.DefaultQuery("cursor", ""); cursor != "" {
+		cursorTime, cursorId, ok := utils.ParseTimeCursor(cursor)
+		if !ok {
+			c.JSON(http.StatusBadRequest, utils.HistoryResponse{
+				StatusCode: 1,
+				StatusMsg:  "Invalid cursor.",
+			})
+			return
+		}
+		watchedAt := cursorTime.UnixMilli()
+		query = query.Where("watched_at <= ? AND (watched_at < ? OR id < ?)", watchedAt, watchedAt, cursorId)
+	}
+
+	// 多取一条用来判断是否还有下一页
+	var records []models.WatchHistory
+	if err := query.Order("watched_at desc").Order("id desc").
+		Limit(consts.MaxHistoryCount + 1).Find(&records).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.HistoryResponse{
+			StatusCode: 1,
+			StatusMsg:  "Failed to fetch watch history.",
+		})
+		return
+	}
+	hasMore := len(records) > consts.MaxHistoryCount
+	if hasMore {
+		records = records[:consts.MaxHistoryCount]
+	}
+
+	// 视频已被删除的历史记录不返回
+	var videos []models.Video
+	var watched []models.WatchHistory
+	for _, r := range records {
+		if r.Video.ID == 0 {
+			continue
+		}
+		videos = append(videos, r.Video)
+		watched = append(watched, r)
+	}
+	var historyResList []utils.HistoryResItem
+	for i, item := range utils.BuildVideoResList(db, videos, userId) {
+		historyResList = append(historyResList, utils.HistoryResItem{
+			VideoResItem:     item,
+			ResumePositionMs: watched[i].PositionMs,
+			WatchedAt:        watched[i].WatchedAt,
+		})
+	}
+
+	// 计算 nextCursor
+	var nextCursor string
+	if len(records) > 0 {
+		last := records[len(records)-1]
+		nextCursor = utils.FormatTimeCursor(time.UnixMilli(last.WatchedAt), last.ID)
+	}
+
+	c.JSON(http.StatusOK, utils.HistoryResponse{
+		StatusCode: 0,
+		StatusMsg:  "Success",
+		NextCursor: nextCursor,
+		HasMore:    hasMore,
+		VideoList:  historyResList,
+	})
+}
+
+// Delete 删除一条观看历史
+func Delete(c *gin.Context) {
+	// 验证 user_id
+	tokenString := c.DefaultQuery("token", "")
+	userId, err := utils.ValidateToken(tokenString)
+	if err != nil || userId <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status_code": 1,
+			"status_msg":  "Invalid user ID.",
+		})
+		return
+	}
+
+	// 验证 video_id
+	videoIdString := c.DefaultQuery("video_id", "0")
+	videoIdInt, err := strconv.Atoi(videoIdString)
+	if err != nil || videoIdInt < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status_code": 1,
+			"status_msg":  "Invalid video_id.",
+		})
+		return
+	}
+
+	db := c.MustGet("db").(*gorm.DB)
+	tx := db.Where("user_id = ? AND video_id = ?", userId, videoIdInt).
+		Delete(&models.WatchHistory{})
+	if tx.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status_code": 1,
+			"status_msg":  "Failed to delete watch history.",
+		})
+		return
+	}
+	if tx.RowsAffected == 0 { // 删除了0条记录，说明这条历史不存在
+		c.JSON(http.StatusNotFound, gin.H{
+			"status_code": 1,
+			"status_msg":  "Watch history not found.",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status_code": 0,
+		"status_msg":  "Success",
+	})
+}
+
+// Clear 清空当前用户的全部观看历史
+func Clear(c *gin.Context) {
+	// 验证 user_id
+	tokenString := c.DefaultQuery("token", "")
+	userId, err := utils.ValidateToken(tokenString)
+	if err != nil || userId <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status_code": 1,
+			"status_msg":  "Invalid user ID.",
+		})
+		return
+	}
+
+	db := c.MustGet("db").(*gorm.DB)
+	if err := db.Where("user_id = ?", userId).Delete(&models.WatchHistory{}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status_code": 1,
+			"status_msg":  "Failed to clear watch history.",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status_code": 0,
+		"status_msg":  "Success",
+	})
+}
+
+// Pause 暂停/恢复记录观看历史，action_type 1 为暂停，2 为恢复
+func Pause(c *gin.Context) {
+	// 验证 user_id
+	tokenString := c.DefaultQuery("token", "")
+	userId, err := utils.ValidateToken(tokenString)
+	if err != nil || userId <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status_code": 1,
+			"status_msg":  "Invalid user ID.",
+		})
+		return
+	}
+
+	var paused bool
+	switch c.DefaultQuery("action_type", "") {
+	case "1": // 暂停
+		paused = true
+	case "2": // 恢复
+		paused = false
+	default: // 错误的 action_type
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status_code": 1,
+			"status_msg":  "Invalid action_type.",
+		})
+		return
+	}
+
+	db := c.MustGet("db").(*gorm.DB)
+	tx := db.Model(&models.UserProfile{}).Where("user_id = ?", userId).
+		UpdateColumn("history_paused", paused)
+	if tx.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status_code": 1,
+			"status_msg":  "Failed to update history setting.",
+		})
+		return
+	}
+	if tx.RowsAffected == 0 {
+		// MySQL 在值未变化时 RowsAffected 为 0，需要区分用户不存在的情况
+		var profile models.UserProfile
+		if err := db.Where("user_id = ?", userId).First(&profile).Error; errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"status_code": 1,
+				"status_msg":  "User not found.",
+			})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status_code": 0,
+		"status_msg":  "Success",
+	})
+}
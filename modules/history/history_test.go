@@ -0,0 +1,162 @@
+package history
+
+import (
+	"app/config"
+	"app/consts"
+	"app/modules/models"
+	"app/utils"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	utils.Setup()
+	postSetup()
+	code := m.Run()
+	utils.Teardown()
+	os.Exit(code)
+}
+
+var ReportUrl = "/douyin/history/action/"
+var ListUrl = "/douyin/history/"
+var PauseUrl = "/douyin/history/pause/"
+var db = utils.GetDb()
+var jordanId uint
+var videoId uint
+
+func postSetup() {
+	jordan := models.User{
+		Username: "jordan",
+		Password: "jordan_pass",
+		Profile:  models.UserProfile{Avatar: "jordan.jpg"},
+	}
+	db.Create(&jordan)
+	jordanId = jordan.ID
+
+	video := models.Video{
+		UserID:      jordan.ID,
+		Title:       "history video",
+		PublishTime: time.Now(),
+	}
+	db.Create(&video)
+	videoId = video.ID
+}
+
+// 测试上报播放进度并读取观看历史
+func TestReportAndList(t *testing.T) {
+	config.Router.POST(ReportUrl, Report)
+	config.Router.GET(ListUrl, List)
+
+	token, err := utils.GenerateToken(jordanId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 连续上报两次，只保留最后一次的进度
+	for _, position := range []string{"1000", "5000"} {
+		values := url.Values{}
+		values.Add("token", token)
+		values.Add("video_id", strconv.Itoa(int(videoId)))
+		values.Add("position_ms", position)
+		req, _ := http.NewRequest("POST", ReportUrl+"?"+values.Encode(), nil)
+		response := httptest.NewRecorder()
+		config.Router.ServeHTTP(response, req)
+		assert.Equal(t, http.StatusOK, response.Code)
+	}
+
+	values := url.Values{}
+	values.Add("token", token)
+	req, _ := http.NewRequest("GET", ListUrl+"?"+values.Encode(), nil)
+	response := httptest.NewRecorder()
+	config.Router.ServeHTTP(response, req)
+	assert.Equal(t, http.StatusOK, response.Code)
+
+	var resp utils.HistoryResponse
+	if err := json.Unmarshal(response.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, len(resp.VideoList))
+	assert.Equal(t, int64(5000), resp.VideoList[0].ResumePositionMs)
+	assert.False(t, resp.HasMore)
+
+	// 非法的播放进度
+	values.Set("video_id", strconv.Itoa(int(videoId)))
+	values.Set("position_ms", "-1")
+	req, _ = http.NewRequest("POST", ReportUrl+"?"+values.Encode(), nil)
+	response = httptest.NewRecorder()
+	config.Router.ServeHTTP(response, req)
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+}
+
+// 测试暂停记录观看历史
+func TestPause(t *testing.T) {
+	config.Router.POST(PauseUrl, Pause)
+
+	token, err := utils.GenerateToken(jordanId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := url.Values{}
+	values.Add("token", token)
+	values.Add("action_type", "1")
+	req, _ := http.NewRequest("POST", PauseUrl+"?"+values.Encode(), nil)
+	response := httptest.NewRecorder()
+	config.Router.ServeHTTP(response, req)
+	assert.Equal(t, http.StatusOK, response.Code)
+
+	var profile models.UserProfile
+	db.Where("user_id = ?", jordanId).First(&profile)
+	assert.True(t, profile.HistoryPaused)
+
+	// 恢复记录
+	values.Set("action_type", "2")
+	req, _ = http.NewRequest("POST", PauseUrl+"?"+values.Encode(), nil)
+	response = httptest.NewRecorder()
+	config.Router.ServeHTTP(response, req)
+	assert.Equal(t, http.StatusOK, response.Code)
+}
+
+// 测试观看时间相同的记录在分页边界上不会漏掉
+func TestListTiedWatchedAt(t *testing.T) {
+	tied := models.User{Username: "tied", Password: "tied_pass"}
+	db.Create(&tied)
+	watchedAt := time.Now().UnixMilli()
+	total := consts.MaxHistoryCount + 5
+	for i := 0; i < total; i++ {
+		video := models.Video{UserID: tied.ID, Title: "tied video", PublishTime: time.Now()}
+		db.Create(&video)
+		db.Create(&models.WatchHistory{UserID: tied.ID, VideoID: video.ID, WatchedAt: watchedAt})
+	}
+
+	token, _ := utils.GenerateToken(tied.ID)
+	seen := make(map[uint]bool)
+	cursor := ""
+	for page := 0; page < 3; page++ {
+		values := url.Values{"token": {token}, "cursor": {cursor}}
+		req, _ := http.NewRequest("GET", ListUrl+"?"+values.Encode(), nil)
+		response := httptest.NewRecorder()
+		config.Router.ServeHTTP(response, req)
+		assert.Equal(t, http.StatusOK, response.Code)
+
+		var resp utils.HistoryResponse
+		if err := json.Unmarshal(response.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		for _, v := range resp.VideoList {
+			assert.False(t, seen[v.ID])
+			seen[v.ID] = true
+		}
+		if !resp.HasMore {
+			break
+		}
+		cursor = resp.NextCursor
+	}
+	assert.Equal(t, total, len(seen))
+}
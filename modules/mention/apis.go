@@ -0,0 +1,268 @@
+package mention
+
+import (
+	"app/consts"
+	"app/modules/models"
+	"app/modules/policy"
+	"app/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Sync 解析评论或视频标题中的@并同步到 mentions 表，需要在创建/编辑内容的同一个事务中调用。
+// 不存在的用户名、@自己以及和发布者之间有拉黑关系（任意一方拉黑另一方）的用户按普通文本处理
+func Sync(tx *gorm.DB, source int, targetId, videoId, fromUserId uint, text string) error {
+	parsed := utils.ParseMentions(text, consts.MaxMentionsPerText)
+
+	// 将用户名解析为用户ID
+	idByName := make(map[string]uint)
+	if len(parsed) > 0 {
+		var names []string
+		for _, p := range parsed {
+			names = append(names, p.Username)
+		}
+		var users []models.User
+		if err := tx.Scopes(policy.NotBlocked("users.id", fromUserId)).
+			Select("id", "username").Where("username IN ?", names).Find(&users).Error; err != nil {
+			return err
+		}
+		for _, u := range users {
+			idByName[strings.ToLower(u.Username)] = u.ID
+		}
+	}
+
+	// 查询之前的@
+	var existing []models.Mention
+	if err := tx.Where("source = ? AND target_id = ?", source, targetId).Find(&existing).Error; err != nil {
+		return err
+	}
+	existingByUser := make(map[uint]models.Mention)
+	for _, m := range existing {
+		existingByUser[m.UserID] = m
+	}
+
+	// 已经被@过的用户只更新位置，保留原来的已读状态和时间
+	var created []models.Mention
+	kept := make(map[uint]bool)
+	for _, p := range parsed {
+		userId, ok := idByName[strings.ToLower(p.Username)]
+		if !ok || userId == fromUserId || kept[userId] {
+			continue
+		}
+		kept[userId] = true
+		if old, ok := existingByUser[userId]; ok {
+			if old.Start != p.Start || old.End != p.End {
+				if err := tx.Model(&old).Updates(map[string]interface{}{
+					"start": p.Start,
+					"end":   p.End,
+				}).Error; err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		created = append(created, models.Mention{
+			UserID:     userId,
+			FromUserID: fromUserId,
+			Source:     source,
+			TargetID:   targetId,
+			VideoID:    videoId,
+			Start:      p.Start,
+			End:        p.End,
+		})
+	}
+
+	// 删除文本中已经没有的@
+	var removed []models.Mention
+	for _, m := range existing {
+		if !kept[m.UserID] {
+			removed = append(removed, m)
+		}
+	}
+	if len(removed) > 0 {
+		// 逐条删除以便触发 AfterDelete 撤回通知
+		if err := tx.Delete(&removed).Error; err != nil {
+			return err
+		}
+	}
+	if len(created) > 0 {
+		return tx.Create(&created).Error
+	}
+	return nil
+}
+
+// List 分页列出当前用户被@的评论和视频，按时间倒序，cursor 为上一页返回的 next_cursor。
+// 查看列表不会标记已读，需要调用 Read
+func List(c *gin.Context) {
+	// 验证 user_id
+	tokenString := c.DefaultQuery("token", "")
+	userId, err := utils.ValidateToken(tokenString)
+	if err != nil || userId <= 0 {
+		c.JSON(http.StatusBadRequest, utils.MentionListResponse{
+			StatusCode: 1,
+			StatusMsg:  "Invalid user ID.",
+		})
+		return
+	}
+
+	// 验证 cursor，0 表示第一页
+	cursor, err := strconv.Atoi(c.DefaultQuery("cursor", "0"))
+	if err != nil || cursor < 0 {
+		c.JSON(http.StatusBadRequest, utils.MentionListResponse{
+			StatusCode: 1,
+			StatusMsg:  "Invalid cursor.",
+		})
+		return
+	}
+
+	db := c.MustGet("db").(*gorm.DB)
+
+	var unreadCount int64
+	db.Model(&models.Mention{}).Where("user_id = ? AND is_read = ?", userId, false).Count(&unreadCount)
+
+	// ID 与创建时间同序，直接用 ID 作为游标。多取一条判断是否还有下一页
+	query := db.Where("user_id = ?", userId)
+	if cursor > 0 {
+		query = query.Where("id < ?", cursor)
+	}
+	var mentions []models.Mention
+	if err := query.Order("id desc").Limit(consts.MaxMentionCount + 1).Find(&mentions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.MentionListResponse{
+			StatusCode: 1,
+			StatusMsg:  "Failed to fetch mentions.",
+		})
+		return
+	}
+	hasMore := len(mentions) > consts.MaxMentionCount
+	if hasMore {
+		mentions = mentions[:consts.MaxMentionCount]
+	}
+
+	// 批量查询@我的用户、评论内容和视频标题
+	var fromUserIds, commentIds, videoIds []uint
+	for _, m := range mentions {
+		fromUserIds = append(fromUserIds, m.FromUserID)
+		if m.Source == models.MentionComment {
+			commentIds = append(commentIds, m.TargetID)
+		} else {
+			videoIds = append(videoIds, m.TargetID)
+		}
+	}
+	userById := make(map[uint]models.User)
+	if len(fromUserIds) > 0 {
+		var users []models.User
+		db.Preload("Profile").Where("id IN ?", fromUserIds).Find(&users)
+		for _, u := range users {
+			userById[u.ID] = u
+		}
+	}
+	contentById := make(map[uint]string)
+	if len(commentIds) > 0 {
+		var comments []models.Comment
+		db.Select("id", "content").Where("id IN ?", commentIds).Find(&comments)
+		for _, comment := range comments {
+			contentById[comment.ID] = comment.Content
+		}
+	}
+	titleById := make(map[uint]string)
+	if len(videoIds) > 0 {
+		var videos []models.Video
+		db.Select("id", "title").Where("id IN ?", videoIds).Find(&videos)
+		for _, v := range videos {
+			titleById[v.ID] = v.Title
+		}
+	}
+	commentSpans := utils.MentionSpans(db, models.MentionComment, commentIds)
+	titleSpans := utils.MentionSpans(db, models.MentionTitle, videoIds)
+
+	// 查询当前用户关注了哪些@我的用户
+	var followedIds []uint
+	if len(fromUserIds) > 0 {
+		db.Table("relations").
+			Where("from_user_id = ? AND to_user_id IN ?", userId, fromUserIds).
+			Pluck("to_user_id", &followedIds)
+	}
+	var followedIdSet = make(map[uint]bool)
+	for _, id := range followedIds {
+		followedIdSet[id] = true
+	}
+
+	var mentionList []utils.MentionResItem
+	for _, m := range mentions {
+		item := utils.MentionResItem{
+			ID:         m.ID,
+			FromUser:   utils.BuildUserResponse(userById[m.FromUserID], followedIdSet[m.FromUserID]),
+			Source:     m.Source,
+			VideoID:    m.VideoID,
+			IsRead:     m.IsRead,
+			CreateDate: m.CreatedAt,
+		}
+		if m.Source == models.MentionComment {
+			item.CommentID = m.TargetID
+			item.Content = contentById[m.TargetID]
+			item.Mentions = commentSpans[m.TargetID]
+		} else {
+			item.Content = titleById[m.TargetID]
+			item.Mentions = titleSpans[m.TargetID]
+		}
+		mentionList = append(mentionList, item)
+	}
+
+	var nextCursor uint
+	if len(mentions) > 0 {
+		nextCursor = mentions[len(mentions)-1].ID
+	}
+
+	c.JSON(http.StatusOK, utils.MentionListResponse{
+		StatusCode:  0,
+		StatusMsg:   "Success",
+		UnreadCount: unreadCount,
+		NextCursor:  nextCursor,
+		HasMore:     hasMore,
+		MentionList: mentionList,
+	})
+}
+
+// Read 将当前用户被@的记录标记为已读，mention_id 不为空时只标记这一条，否则标记全部
+func Read(c *gin.Context) {
+	// 验证 user_id
+	tokenString := c.DefaultQuery("token", "")
+	userId, err := utils.ValidateToken(tokenString)
+	if err != nil || userId <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status_code": 1,
+			"status_msg":  "Invalid user ID.",
+		})
+		return
+	}
+
+	db := c.MustGet("db").(*gorm.DB)
+	query := db.Model(&models.Mention{}).Where("user_id = ? AND is_read = ?", userId, false)
+	if mentionId := c.DefaultQuery("mention_id", ""); mentionId != "" {
+		id, err := strconv.Atoi(mentionId)
+		if err != nil || id < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"status_code": 1,
+				"status_msg":  "Invalid mention_id.",
+			})
+			return
+		}
+		query = query.Where("id = ?", id)
+	}
+
+	if err := query.Update("is_read", true).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status_code": 1,
+			"status_msg":  "Failed to mark mentions as read.",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status_code": 0,
+		"status_msg":  "Success",
+	})
+}
@@ -0,0 +1,133 @@
+package mention
+
+import (
+	"app/config"
+	"app/modules/models"
+	"app/utils"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	utils.Setup()
+	postSetup()
+	code := m.Run()
+	utils.Teardown()
+	os.Exit(code)
+}
+
+var ListUrl = "/douyin/mention/list/"
+var ReadUrl = "/douyin/mention/read/"
+var db = utils.GetDb()
+var aliceId, bobId, carolId uint
+
+func postSetup() {
+	var ids []uint
+	for _, name := range []string{"alice_m", "bob_m", "carol_m"} {
+		user := models.User{Username: name, Password: name + "_pass"}
+		db.Create(&user)
+		ids = append(ids, user.ID)
+	}
+	aliceId, bobId, carolId = ids[0], ids[1], ids[2]
+
+	config.Router.GET(ListUrl, List)
+	config.Router.POST(ReadUrl, Read)
+}
+
+func request(method, path string, userId uint, values url.Values) *httptest.ResponseRecorder {
+	token, _ := utils.GenerateToken(userId)
+	values.Set("token", token)
+	req, _ := http.NewRequest(method, path+"?"+values.Encode(), nil)
+	response := httptest.NewRecorder()
+	config.Router.ServeHTTP(response, req)
+	return response
+}
+
+func listMentions(t *testing.T, userId uint) utils.MentionListResponse {
+	response := request("GET", ListUrl, userId, url.Values{})
+	assert.Equal(t, http.StatusOK, response.Code)
+	var resp utils.MentionListResponse
+	if err := json.Unmarshal(response.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+// newComment 创建一条评论并同步其中的@
+func newComment(t *testing.T, userId uint, text string) models.Comment {
+	video := models.Video{UserID: userId, Title: "mention video", PublishTime: time.Now()}
+	db.Create(&video)
+	comment := models.Comment{UserID: userId, VideoID: video.ID, Content: text, CreatedAt: time.Now()}
+	db.Create(&comment)
+	assert.NoError(t, Sync(db, models.MentionComment, comment.ID, video.ID, userId, text))
+	return comment
+}
+
+// 测试和发布者之间有拉黑关系的用户不会被@，无论是哪一方拉黑
+func TestSyncSkipsBlocked(t *testing.T) {
+	db.Create(&models.Block{UserID: carolId, BlockedUserID: aliceId})
+	defer db.Where("user_id = ?", carolId).Delete(&models.Block{})
+
+	comment := newComment(t, aliceId, "@bob_m @carol_m hi")
+	var mentioned []uint
+	db.Model(&models.Mention{}).Where("source = ? AND target_id = ?", models.MentionComment, comment.ID).
+		Pluck("user_id", &mentioned)
+	assert.Equal(t, []uint{bobId}, mentioned)
+
+	comment = newComment(t, carolId, "@alice_m @bob_m hi")
+	mentioned = nil
+	db.Model(&models.Mention{}).Where("source = ? AND target_id = ?", models.MentionComment, comment.ID).
+		Pluck("user_id", &mentioned)
+	assert.Equal(t, []uint{bobId}, mentioned)
+}
+
+// 测试查看列表不会标记已读，调用 Read 之后才标记
+func TestRead(t *testing.T) {
+	newComment(t, bobId, "@carol_m read me")
+	newComment(t, aliceId, "@carol_m read me too")
+
+	resp := listMentions(t, carolId)
+	assert.Equal(t, int64(2), resp.UnreadCount)
+	resp = listMentions(t, carolId)
+	assert.Equal(t, int64(2), resp.UnreadCount)
+	assert.False(t, resp.MentionList[0].IsRead)
+
+	// 标记一条
+	response := request("POST", ReadUrl, carolId, url.Values{"mention_id": {"0"}})
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+	response = request("POST", ReadUrl, carolId, url.Values{
+		"mention_id": {strconv.Itoa(int(resp.MentionList[0].ID))},
+	})
+	assert.Equal(t, http.StatusOK, response.Code)
+	resp = listMentions(t, carolId)
+	assert.Equal(t, int64(1), resp.UnreadCount)
+	assert.True(t, resp.MentionList[0].IsRead)
+
+	// 标记全部
+	response = request("POST", ReadUrl, carolId, url.Values{})
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, int64(0), listMentions(t, carolId).UnreadCount)
+}
+
+// 测试文本中去掉的@会撤回通知
+func TestSyncRetractsRemoved(t *testing.T) {
+	comment := newComment(t, aliceId, "@bob_m look")
+	notifications := func() int64 {
+		var count int64
+		db.Model(&models.Notification{}).
+			Where("user_id = ? AND type = ? AND target_id = ?", bobId, models.NotifyMention, comment.VideoID).
+			Count(&count)
+		return count
+	}
+	assert.Equal(t, int64(1), notifications())
+
+	assert.NoError(t, Sync(db, models.MentionComment, comment.ID, comment.VideoID, aliceId, "look"))
+	assert.Equal(t, int64(0), notifications())
+}
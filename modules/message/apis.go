@@ -1,8 +1,13 @@
 package message
 
 import (
+	"app/consts"
+	"app/modules/filter"
 	"app/modules/models"
+	"app/modules/policy"
+	"app/modules/realtime"
 	"app/utils"
+	"errors"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 	"log"
@@ -11,6 +16,78 @@ import (
 	"time"
 )
 
+// buildMessageResItem 生成 viewerId 看到的消息，撤回的消息显示为提示语，删除的消息不返回内容
+func buildMessageResItem(message models.Message, viewerId uint, isRead bool) utils.MessageResItem {
+	item := utils.MessageResItem{
+		ID:         message.ID,
+		ToUserId:   message.ToUserID,
+		FromUserId: message.FromUserID,
+		Content:    message.Content,
+		CreateTime: message.CreatedAt,
+		IsRead:     isRead,
+		IsEdited:   message.EditedAt > 0,
+		UpdateTime: message.UpdatedAt,
+	}
+	if message.DeletedBy(viewerId) {
+		item.Content = ""
+		item.IsDeleted = true
+	} else if message.Recalled {
+		item.Content = consts.RecalledMessageContent
+		item.IsRecalled = true
+	}
+	return item
+}
+
+// isRead 判断消息是否已读，readCursor 为接收者的已读游标
+func isRead(message models.Message, readCursor uint) bool {
+	return message.ID <= readCursor
+}
+
+// sendMessage 把消息存入数据库，并实时推送给对方，同时推送给发送者的其它设备
+func sendMessage(db *gorm.DB, fromUserId uint, toUserId uint, content string) error {
+	message := models.Message{
+		FromUserID: fromUserId,
+		ToUserID:   toUserId,
+		Content:    content,
+		CreatedAt:  time.Now().UnixMilli(),
+	}
+	if err := db.Create(&message).Error; err != nil {
+		return err
+	}
+	realtime.Publish(realtime.Event{
+		Type: realtime.EventMessage,
+		Data: buildMessageResItem(message, fromUserId, false),
+	}, toUserId, fromUserId)
+	return nil
+}
+
+// PublishReviewed 发送审核通过的消息或者应用审核通过的编辑。
+// 审核期间任意一方拉黑了另一方时不再发送，消息被撤回时不再编辑
+func PublishReviewed(db *gorm.DB, review models.ContentReview) error {
+	if review.ParentID > 0 {
+		var message models.Message
+		if err := db.Where("from_user_id = ?", review.UserID).First(&message, review.ParentID).Error; err != nil {
+			return err
+		}
+		edited, err := editMessage(db, &message, review.Content)
+		if err != nil {
+			return err
+		}
+		if !edited {
+			return errors.New("message has been recalled")
+		}
+		return nil
+	}
+	blocked, err := policy.IsBlocked(db, review.UserID, review.TargetID)
+	if err != nil {
+		return err
+	}
+	if blocked {
+		return errors.New("users have blocked each other")
+	}
+	return sendMessage(db, review.UserID, review.TargetID, review.Content)
+}
+
 func Send(c *gin.Context) {
 	// 获取token
 	tokenString := c.DefaultQuery("token", "")
@@ -53,6 +130,10 @@ func Send(c *gin.Context) {
 		})
 		return
 	}
+	// 任意一方拉黑了另一方时不能发送私信
+	if !policy.CheckInteraction(c, db, fromUserId, toUser.ID, "You can't send message to this user.") {
+		return
+	}
 
 	// 验证 action_type
 	actionType := c.DefaultQuery("action_type", "")
@@ -73,17 +154,34 @@ func Send(c *gin.Context) {
 		})
 		return
 	}
+	// 敏感词过滤
+	content, verdict := filter.Apply(db, fromUserId, filter.SourceMessage, content)
+	if verdict == filter.Reject {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status_code": 1,
+			"status_msg":  "Message contains sensitive words.",
+		})
+		return
+	}
 
-	// 发送消息
-	// 创建消息对象
-	message := models.Message{
-		FromUserID: fromUserId,
-		ToUserID:   toUser.ID,
-		Content:    content,
-		CreatedAt:  time.Now().UnixMilli(),
+	// 需要人工审核的消息先加入审核队列，审核通过之后才发送
+	if verdict == filter.Hold {
+		if err := filter.Enqueue(db, fromUserId, filter.SourceMessage, toUser.ID, 0, content); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"status_code": 1,
+				"status_msg":  "Failed to send message.",
+			})
+			return
+		}
+		c.JSON(http.StatusAccepted, gin.H{
+			"status_code": 0,
+			"status_msg":  "Message is pending review.",
+		})
+		return
 	}
-	// 消息存入数据库
-	if db.Create(&message).Error != nil {
+
+	// 发送消息
+	if sendMessage(db, fromUserId, toUser.ID, content) != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"status_code": 1,
 			"status_msg":  "Failed to send message.",
@@ -145,14 +243,34 @@ func GetHistory(c *gin.Context) {
 		return
 	}
 
-	preMsgTimeString := c.DefaultQuery("pre_msg_time", "")
-	preMsgTime, _ := strconv.ParseInt(preMsgTimeString, 10, 64)
+	// 传入 sync_time 时返回之后有变化的消息，包括新消息和被撤回、编辑、删除的消息，
+	// 否则返回 pre_msg_time 之后发送的消息，不包括当前用户删除了的消息。
+	// sync_time 为上次返回的 "更新时间毫秒_ID"，同一毫秒内更新的消息再按 ID 区分，不会漏掉
+	conversation := db.Where("from_user_id = ? AND to_user_id = ?", fromUserId, toUser.ID).
+		Or("from_user_id = ? AND to_user_id = ?", toUser.ID, fromUserId)
+	query := db.Where(conversation)
+	var syncTime int64
+	var syncId uint
+	if cursor := c.DefaultQuery("sync_time", ""); cursor != "" {
+		cursorTime, cursorId, ok := utils.ParseTimeCursor(cursor)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"status_code": 1,
+				"status_msg":  "Invalid sync_time.",
+			})
+			return
+		}
+		syncTime, syncId = cursorTime.UnixMilli(), cursorId
+		query = query.Where("updated_at >= ? AND (updated_at > ? OR id > ?)", syncTime, syncTime, syncId).
+			Order("updated_at").Order("id")
+	} else {
+		preMsgTimeString := c.DefaultQuery("pre_msg_time", "")
+		preMsgTime, _ := strconv.ParseInt(preMsgTimeString, 10, 64)
+		query = query.Where("created_at > ?", preMsgTime).Scopes(models.NotDeletedBy(fromUserId)).Order("created_at")
+	}
 
 	var chatHistory []models.Message
-	if err := db.Where("from_user_id = ? AND to_user_id = ? AND created_at > ?", fromUserId, toUserId, preMsgTime).
-		Or("from_user_id = ? AND to_user_id = ? AND created_at > ?", toUserId, fromUserId, preMsgTime).
-		Order("created_at").
-		Find(&chatHistory).Error; err != nil {
+	if err := query.Find(&chatHistory).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"status_code": 1,
 			"status_msg":  "Failed to fetch chat history.",
@@ -160,19 +278,23 @@ func GetHistory(c *gin.Context) {
 		log.Println("Failed to fetch chat history.")
 		return
 	}
+	// 自己发的消息看对方的已读游标，对方发的消息看自己的已读游标
+	myRead, peerRead := models.ReadCursors(db, fromUserId, toUser.ID)
 	var messageResItems []utils.MessageResItem
 	for _, message := range chatHistory {
-		messageResItems = append(messageResItems, utils.MessageResItem{
-			ID:         message.ID,
-			ToUserId:   message.ToUserID,
-			FromUserId: message.FromUserID,
-			Content:    message.Content,
-			CreateTime: message.CreatedAt,
-		})
+		readCursor := myRead
+		if message.FromUserID == fromUserId {
+			readCursor = peerRead
+		}
+		messageResItems = append(messageResItems, buildMessageResItem(message, fromUserId, isRead(message, readCursor)))
+		if message.UpdatedAt > syncTime || (message.UpdatedAt == syncTime && message.ID > syncId) {
+			syncTime, syncId = message.UpdatedAt, message.ID
+		}
 	}
 	c.JSON(http.StatusOK, utils.MessageHistoryResponse{
 		StatusCode:  0,
 		StatusMsg:   "Success",
 		MessageList: messageResItems,
+		SyncTime:    utils.FormatTimeCursor(time.UnixMilli(syncTime), syncId),
 	})
 }
@@ -0,0 +1,246 @@
+package message
+
+import (
+	"app/consts"
+	"app/modules/filter"
+	"app/modules/models"
+	"app/modules/realtime"
+	"app/utils"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// findMessage 验证 token 和 message_id，返回当前用户和消息。
+// senderOnly 为 true 时只有发送者可以操作，否则双方都可以操作
+func findMessage(c *gin.Context, db *gorm.DB, senderOnly bool) (uint, models.Message, bool) {
+	var message models.Message
+	tokenString := c.DefaultQuery("token", "")
+	userId, err := utils.ValidateToken(tokenString)
+	if err != nil || userId <= 0 {
+		c.JSON(http.StatusBadRequest, utils.MessageResponse{
+			StatusCode: 1,
+			StatusMsg:  "Invalid user ID.",
+		})
+		return 0, message, false
+	}
+
+	messageId, err := strconv.Atoi(c.DefaultQuery("message_id", ""))
+	if err != nil || messageId <= 0 {
+		c.JSON(http.StatusBadRequest, utils.MessageResponse{
+			StatusCode: 1,
+			StatusMsg:  "Invalid message ID.",
+		})
+		return 0, message, false
+	}
+
+	// 不是会话参与者的用户也返回不存在，不暴露消息 ID
+	err = db.First(&message, messageId).Error
+	if err == nil && (message.DeletedBy(userId) ||
+		(message.FromUserID != userId && message.ToUserID != userId)) {
+		err = gorm.ErrRecordNotFound
+	}
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, utils.MessageResponse{
+				StatusCode: 1,
+				StatusMsg:  "Message not found.",
+			})
+			return 0, message, false
+		}
+		c.JSON(http.StatusInternalServerError, utils.MessageResponse{
+			StatusCode: 1,
+			StatusMsg:  "Failed to fetch message.",
+		})
+		return 0, message, false
+	}
+
+	if senderOnly && message.FromUserID != userId {
+		c.JSON(http.StatusForbidden, utils.MessageResponse{
+			StatusCode: 1,
+			StatusMsg:  "You can only change your own messages.",
+		})
+		return 0, message, false
+	}
+	return userId, message, true
+}
+
+// withinWindow 判断消息是否还在可以撤回/编辑的时间内
+func withinWindow(message models.Message, window time.Duration) bool {
+	return time.Now().UnixMilli()-message.CreatedAt <= window.Milliseconds()
+}
+
+// publishUpdate 将消息的变化推送给 userIds，每个用户看到的消息分别生成
+func publishUpdate(db *gorm.DB, message models.Message, userIds ...uint) {
+	recipientRead, _ := models.ReadCursors(db, message.ToUserID, message.FromUserID)
+	for _, userId := range userIds {
+		realtime.Publish(realtime.Event{
+			Type: realtime.EventUpdate,
+			Data: buildMessageResItem(message, userId, isRead(message, recipientRead)),
+		}, userId)
+	}
+}
+
+// Recall 撤回消息，只有发送者可以在 MessageRecallWindow 内撤回，双方都只能看到撤回提示
+func Recall(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userId, message, ok := findMessage(c, db, true)
+	if !ok {
+		return
+	}
+	if message.Recalled {
+		c.JSON(http.StatusBadRequest, utils.MessageResponse{
+			StatusCode: 1,
+			StatusMsg:  "Message has already been recalled.",
+		})
+		return
+	}
+	if !withinWindow(message, consts.MessageRecallWindow) {
+		c.JSON(http.StatusForbidden, utils.MessageResponse{
+			StatusCode: 1,
+			StatusMsg:  fmt.Sprintf("Message can only be recalled within %d minutes.", int(consts.MessageRecallWindow.Minutes())),
+		})
+		return
+	}
+
+	if err := models.RecallMessage(db, &message); err != nil {
+		c.JSON(http.StatusInternalServerError, utils.MessageResponse{
+			StatusCode: 1,
+			StatusMsg:  "Failed to recall message.",
+		})
+		return
+	}
+	publishUpdate(db, message, message.ToUserID, userId)
+
+	c.JSON(http.StatusOK, utils.MessageResponse{
+		StatusCode: 0,
+		StatusMsg:  "Message recalled.",
+		Message:    buildMessageResItem(message, userId, false),
+	})
+}
+
+// editMessage 修改消息内容并推送给双方，撤回和编辑同时发生时以撤回为准。
+// 返回消息是否被修改，message 会更新为数据库中最新的内容
+func editMessage(db *gorm.DB, message *models.Message, content string) (bool, error) {
+	result := db.Model(message).Where("recalled = ?", false).Updates(map[string]interface{}{
+		"content":   content,
+		"edited_at": time.Now().UnixMilli(),
+	})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if err := db.First(message, message.ID).Error; err != nil {
+		return false, err
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	publishUpdate(db, *message, message.ToUserID, message.FromUserID)
+	return true, nil
+}
+
+// Edit 编辑消息，只有发送者可以在 MessageEditWindow 内编辑，编辑后的消息带有已编辑标记
+func Edit(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userId, message, ok := findMessage(c, db, true)
+	if !ok {
+		return
+	}
+	if message.Recalled {
+		c.JSON(http.StatusBadRequest, utils.MessageResponse{
+			StatusCode: 1,
+			StatusMsg:  "Recalled messages can't be edited.",
+		})
+		return
+	}
+	if !withinWindow(message, consts.MessageEditWindow) {
+		c.JSON(http.StatusForbidden, utils.MessageResponse{
+			StatusCode: 1,
+			StatusMsg:  fmt.Sprintf("Message can only be edited within %d minutes.", int(consts.MessageEditWindow.Minutes())),
+		})
+		return
+	}
+
+	// 验证 content，和发送时的规则相同
+	content := c.DefaultQuery("content", "")
+	if len(content) == 0 || len(content) > 512 {
+		c.JSON(http.StatusBadRequest, utils.MessageResponse{
+			StatusCode: 1,
+			StatusMsg:  "Message length must be between 1 and 512.",
+		})
+		return
+	}
+	content, verdict := filter.Apply(db, userId, filter.SourceMessage, content)
+	if verdict == filter.Reject {
+		c.JSON(http.StatusBadRequest, utils.MessageResponse{
+			StatusCode: 1,
+			StatusMsg:  "Message contains sensitive words.",
+		})
+		return
+	}
+
+	// 需要人工审核的编辑先加入审核队列，审核通过之后才生效
+	if verdict == filter.Hold {
+		if err := filter.Enqueue(db, userId, filter.SourceMessage, message.ToUserID, message.ID, content); err != nil {
+			c.JSON(http.StatusInternalServerError, utils.MessageResponse{
+				StatusCode: 1,
+				StatusMsg:  "Failed to edit message.",
+			})
+			return
+		}
+		c.JSON(http.StatusAccepted, utils.MessageResponse{
+			StatusCode: 0,
+			StatusMsg:  "Message edit is pending review.",
+			Message:    buildMessageResItem(message, userId, false),
+		})
+		return
+	}
+
+	if _, err := editMessage(db, &message, content); err != nil {
+		c.JSON(http.StatusInternalServerError, utils.MessageResponse{
+			StatusCode: 1,
+			StatusMsg:  "Failed to edit message.",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.MessageResponse{
+		StatusCode: 0,
+		StatusMsg:  "Message edited.",
+		Message:    buildMessageResItem(message, userId, false),
+	})
+}
+
+// Delete 删除消息，只对当前用户隐藏，对方仍然可以看到
+func Delete(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userId, message, ok := findMessage(c, db, false)
+	if !ok {
+		return
+	}
+
+	column := "to_deleted"
+	if message.FromUserID == userId {
+		column = "from_deleted"
+	}
+	// 同时更新 updated_at，当前用户的其它设备同步时会收到删除
+	if err := db.Model(&message).Update(column, true).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.MessageResponse{
+			StatusCode: 1,
+			StatusMsg:  "Failed to delete message.",
+		})
+		return
+	}
+	db.First(&message, message.ID)
+	publishUpdate(db, message, userId)
+
+	c.JSON(http.StatusOK, utils.MessageResponse{
+		StatusCode: 0,
+		StatusMsg:  "Message deleted.",
+		Message:    buildMessageResItem(message, userId, false),
+	})
+}
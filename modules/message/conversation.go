@@ -0,0 +1,222 @@
+package message
+
+import (
+	"app/consts"
+	"app/modules/models"
+	"app/modules/policy"
+	"app/modules/realtime"
+	"app/utils"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// GetConversations 查询当前用户的会话列表，按最近一条消息的时间倒序排列，
+// 每个会话带上对方的用户信息、最近一条消息和未读数
+func GetConversations(c *gin.Context) {
+	tokenString := c.DefaultQuery("token", "")
+	userId, err := utils.ValidateToken(tokenString)
+	if err != nil || userId <= 0 {
+		c.JSON(http.StatusBadRequest, utils.ConversationListResponse{
+			StatusCode: 1,
+			StatusMsg:  "Invalid user ID.",
+		})
+		return
+	}
+
+	// 验证每页数量
+	count, err := strconv.Atoi(c.DefaultQuery("count", strconv.Itoa(consts.DefaultConversationCount)))
+	if err != nil || count < 1 || count > consts.MaxConversationCount {
+		c.JSON(http.StatusBadRequest, utils.ConversationListResponse{
+			StatusCode: 1,
+			StatusMsg:  "Invalid count.",
+		})
+		return
+	}
+
+	db := c.MustGet("db").(*gorm.DB)
+
+	// 走 idx_user_active 索引，不显示有拉黑关系的用户
+	query := db.Where("user_id = ?", userId).
+		Scopes(policy.NotBlocked("conversations.peer_id", userId))
+
+	// 游标为上一页最后一个会话的 "最近消息时间毫秒_会话ID"
+	if cursor := c.DefaultQuery("cursor", ""); cursor != "" {
+		cursorTime, cursorId, ok := utils.ParseTimeCursor(cursor)
+		if !ok {
+			c.JSON(http.StatusBadRequest, utils.ConversationListResponse{
+				StatusCode: 1,
+				StatusMsg:  "Invalid cursor.",
+			})
+			return
+		}
+		lastActive := cursorTime.UnixMilli()
+		query = query.Where("last_active_at <= ? AND (last_active_at < ? OR id < ?)", lastActive, lastActive, cursorId)
+	}
+
+	// 多取一条用来判断是否还有下一页
+	var conversations []models.Conversation
+	if err := query.Order("last_active_at desc").Order("id desc").Limit(count + 1).Find(&conversations).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ConversationListResponse{
+			StatusCode: 1,
+			StatusMsg:  "Failed to fetch conversations.",
+		})
+		return
+	}
+	hasMore := len(conversations) > count
+	if hasMore {
+		conversations = conversations[:count]
+	}
+	var nextCursor string
+	if len(conversations) > 0 {
+		last := conversations[len(conversations)-1]
+		nextCursor = utils.FormatTimeCursor(time.UnixMilli(last.LastActiveAt), last.ID)
+	}
+
+	// 批量查询对方的用户信息、最近一条消息、对方的已读游标和当前用户关注了其中哪些用户
+	var peerIds, messageIds []uint
+	for _, conversation := range conversations {
+		peerIds = append(peerIds, conversation.PeerID)
+		messageIds = append(messageIds, conversation.LastMessageID)
+	}
+	userById := make(map[uint]models.User)
+	messageById := make(map[uint]models.Message)
+	peerReadById := make(map[uint]uint)
+	followedIdSet := make(map[uint]bool)
+	if len(conversations) > 0 {
+		var users []models.User
+		db.Preload("Profile").Where("id IN ?", peerIds).Find(&users)
+		for _, u := range users {
+			userById[u.ID] = u
+		}
+		var messages []models.Message
+		db.Where("id IN ?", messageIds).Find(&messages)
+		for _, m := range messages {
+			messageById[m.ID] = m
+		}
+		var peerConversations []models.Conversation
+		db.Where("user_id IN ? AND peer_id = ?", peerIds, userId).Find(&peerConversations)
+		for _, pc := range peerConversations {
+			peerReadById[pc.UserID] = pc.ReadMessageID
+		}
+		var followedIds []uint
+		db.Model(&models.Relation{}).Where("from_user_id = ? AND to_user_id IN ?", userId, peerIds).
+			Pluck("to_user_id", &followedIds)
+		for _, id := range followedIds {
+			followedIdSet[id] = true
+		}
+	}
+
+	var conversationList []utils.ConversationResItem
+	for _, conversation := range conversations {
+		user, ok := userById[conversation.PeerID]
+		if !ok {
+			continue
+		}
+		message := messageById[conversation.LastMessageID]
+		readCursor := conversation.ReadMessageID
+		if message.FromUserID == userId {
+			readCursor = peerReadById[conversation.PeerID]
+		}
+		conversationList = append(conversationList, utils.ConversationResItem{
+			User:           utils.BuildUserResponse(user, followedIdSet[user.ID]),
+			LastMessage:    buildMessageResItem(message, userId, isRead(message, readCursor)),
+			LastActiveTime: conversation.LastActiveAt,
+			UnreadCount:    conversation.UnreadCount,
+		})
+	}
+
+	c.JSON(http.StatusOK, utils.ConversationListResponse{
+		StatusCode:       0,
+		StatusMsg:        "Success",
+		NextCursor:       nextCursor,
+		HasMore:          hasMore,
+		ConversationList: conversationList,
+	})
+}
+
+// Read 将和 to_user_id 会话的已读游标前移到 message_id，没有传 message_id 时读到最近一条消息。
+// 已读游标前移后推送已读回执给对方，同时推送给自己的其它设备以同步未读数
+func Read(c *gin.Context) {
+	tokenString := c.DefaultQuery("token", "")
+	userId, err := utils.ValidateToken(tokenString)
+	if err != nil || userId <= 0 {
+		c.JSON(http.StatusBadRequest, utils.ReadResponse{
+			StatusCode: 1,
+			StatusMsg:  "Invalid user ID.",
+		})
+		return
+	}
+
+	peerId, err := strconv.Atoi(c.DefaultQuery("to_user_id", ""))
+	if err != nil || peerId <= 0 {
+		c.JSON(http.StatusBadRequest, utils.ReadResponse{
+			StatusCode: 1,
+			StatusMsg:  "Invalid target user ID.",
+		})
+		return
+	}
+
+	db := c.MustGet("db").(*gorm.DB)
+	var conversation models.Conversation
+	if err := db.Where("user_id = ? AND peer_id = ?", userId, peerId).First(&conversation).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, utils.ReadResponse{
+				StatusCode: 1,
+				StatusMsg:  "Conversation not found.",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, utils.ReadResponse{
+			StatusCode: 1,
+			StatusMsg:  "Failed to fetch conversation.",
+		})
+		return
+	}
+
+	// 验证 message_id，不能超过最近一条消息
+	messageId := conversation.LastMessageID
+	if value := c.DefaultQuery("message_id", ""); value != "" {
+		id, err := strconv.Atoi(value)
+		if err != nil || id <= 0 {
+			c.JSON(http.StatusBadRequest, utils.ReadResponse{
+				StatusCode: 1,
+				StatusMsg:  "Invalid message ID.",
+			})
+			return
+		}
+		if uint(id) < messageId {
+			messageId = uint(id)
+		}
+	}
+
+	previousRead := conversation.ReadMessageID
+	conversation, err = models.MarkRead(db, userId, uint(peerId), messageId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ReadResponse{
+			StatusCode: 1,
+			StatusMsg:  "Failed to mark conversation as read.",
+		})
+		return
+	}
+	if conversation.ReadMessageID > previousRead {
+		realtime.Publish(realtime.Event{
+			Type: realtime.EventRead,
+			Data: utils.ReadReceipt{
+				UserId:        userId,
+				PeerId:        uint(peerId),
+				ReadMessageId: conversation.ReadMessageID,
+			},
+		}, uint(peerId), userId)
+	}
+
+	c.JSON(http.StatusOK, utils.ReadResponse{
+		StatusCode:    0,
+		StatusMsg:     "Success",
+		ReadMessageId: conversation.ReadMessageID,
+		UnreadCount:   conversation.UnreadCount,
+	})
+}
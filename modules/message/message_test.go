@@ -2,14 +2,18 @@ package message
 
 import (
 	"app/config"
+	"app/consts"
 	"app/modules/models"
 	"app/utils"
+	"encoding/json"
 	"github.com/stretchr/testify/assert"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"os"
+	"strconv"
 	"testing"
+	"time"
 )
 
 // Golang的测试会检测到每个包的TestMain函数，首先执行它
@@ -23,6 +27,11 @@ func TestMain(m *testing.M) {
 
 var SendUrl = "/douyin/message/action/"
 var GetHistoryUrl = "/douyin/message/chat/"
+var ConversationsUrl = "/douyin/message/conversations/"
+var ReadUrl = "/douyin/message/read/"
+var RecallUrl = "/douyin/message/recall/"
+var EditUrl = "/douyin/message/edit/"
+var DeleteUrl = "/douyin/message/delete/"
 var db = utils.GetDb()
 
 func postSetup() {
@@ -142,3 +151,181 @@ func TestGetHistory(t *testing.T) {
 
 	assert.Equal(t, http.StatusBadRequest, response.Code)
 }
+
+// 测试会话列表的未读数和已读回执
+func TestConversations(t *testing.T) {
+	config.Router.GET(ConversationsUrl, GetConversations)
+	config.Router.POST(ReadUrl, Read)
+
+	jordanToken, _ := utils.GenerateToken(1)
+	michaelToken, _ := utils.GenerateToken(2)
+	getConversations := func(token string) utils.ConversationListResponse {
+		req, _ := http.NewRequest("GET", ConversationsUrl+"?"+url.Values{"token": {token}}.Encode(), nil)
+		response := httptest.NewRecorder()
+		config.Router.ServeHTTP(response, req)
+		assert.Equal(t, http.StatusOK, response.Code)
+		var res utils.ConversationListResponse
+		json.Unmarshal(response.Body.Bytes(), &res)
+		return res
+	}
+
+	var unreadBefore int
+	if res := getConversations(michaelToken); len(res.ConversationList) > 0 {
+		unreadBefore = res.ConversationList[0].UnreadCount
+	}
+	message := models.Message{FromUserID: 1, ToUserID: 2, Content: "did you see my message?"}
+	db.Create(&message)
+
+	// 接收者的未读数+1，发送者的未读数不变
+	res := getConversations(michaelToken)
+	assert.Len(t, res.ConversationList, 1)
+	assert.Equal(t, uint(1), res.ConversationList[0].User.ID)
+	assert.Equal(t, unreadBefore+1, res.ConversationList[0].UnreadCount)
+	assert.Equal(t, message.ID, res.ConversationList[0].LastMessage.ID)
+	res = getConversations(jordanToken)
+	assert.Len(t, res.ConversationList, 1)
+	assert.False(t, res.ConversationList[0].LastMessage.IsRead)
+
+	// 接收者标记已读后未读数清零，发送者看到已读
+	req, _ := http.NewRequest("POST", ReadUrl+"?"+url.Values{"token": {michaelToken}, "to_user_id": {"1"}}.Encode(), nil)
+	response := httptest.NewRecorder()
+	config.Router.ServeHTTP(response, req)
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, 0, getConversations(michaelToken).ConversationList[0].UnreadCount)
+	assert.True(t, getConversations(jordanToken).ConversationList[0].LastMessage.IsRead)
+
+	// 没有会话的用户
+	req, _ = http.NewRequest("POST", ReadUrl+"?"+url.Values{"token": {michaelToken}, "to_user_id": {"100"}}.Encode(), nil)
+	response = httptest.NewRecorder()
+	config.Router.ServeHTTP(response, req)
+	assert.Equal(t, http.StatusNotFound, response.Code)
+}
+
+// 测试标记已读时重新计算的未读数不包括已经撤回的消息，路由在 TestConversations 中注册
+func TestReadSkipsRecalled(t *testing.T) {
+	michaelToken, _ := utils.GenerateToken(2)
+	read := func(values url.Values) {
+		values.Set("token", michaelToken)
+		values.Set("to_user_id", "1")
+		req, _ := http.NewRequest("POST", ReadUrl+"?"+values.Encode(), nil)
+		response := httptest.NewRecorder()
+		config.Router.ServeHTTP(response, req)
+		assert.Equal(t, http.StatusOK, response.Code)
+	}
+	unreadCount := func() int {
+		var conversation models.Conversation
+		db.Where("user_id = ? AND peer_id = ?", 2, 1).First(&conversation)
+		return conversation.UnreadCount
+	}
+
+	read(url.Values{})
+	first := models.Message{FromUserID: 1, ToUserID: 2, Content: "first"}
+	second := models.Message{FromUserID: 1, ToUserID: 2, Content: "second"}
+	third := models.Message{FromUserID: 1, ToUserID: 2, Content: "third"}
+	for _, m := range []*models.Message{&first, &second, &third} {
+		db.Create(m)
+	}
+	assert.Equal(t, 3, unreadCount())
+
+	// 撤回一条后只剩两条未读，读到第一条后只有第三条未读
+	assert.NoError(t, models.RecallMessage(db, &second))
+	assert.Equal(t, 2, unreadCount())
+	read(url.Values{"message_id": {strconv.Itoa(int(first.ID))}})
+	assert.Equal(t, 1, unreadCount())
+}
+
+// 测试撤回、编辑、删除消息，以及按 sync_time 同步变化
+func TestMessageChanges(t *testing.T) {
+	config.Router.POST(RecallUrl, Recall)
+	config.Router.POST(EditUrl, Edit)
+	config.Router.POST(DeleteUrl, Delete)
+
+	jordanToken, _ := utils.GenerateToken(1)
+	michaelToken, _ := utils.GenerateToken(2)
+	request := func(path string, values url.Values) int {
+		req, _ := http.NewRequest("POST", path+"?"+values.Encode(), nil)
+		response := httptest.NewRecorder()
+		config.Router.ServeHTTP(response, req)
+		return response.Code
+	}
+	getHistory := func(token string, values url.Values) utils.MessageHistoryResponse {
+		values.Set("token", token)
+		values.Set("to_user_id", "2")
+		if token == michaelToken {
+			values.Set("to_user_id", "1")
+		}
+		req, _ := http.NewRequest("GET", GetHistoryUrl+"?"+values.Encode(), nil)
+		response := httptest.NewRecorder()
+		config.Router.ServeHTTP(response, req)
+		assert.Equal(t, http.StatusOK, response.Code)
+		var res utils.MessageHistoryResponse
+		json.Unmarshal(response.Body.Bytes(), &res)
+		return res
+	}
+	findMessage := func(res utils.MessageHistoryResponse, id uint) *utils.MessageResItem {
+		for i := range res.MessageList {
+			if res.MessageList[i].ID == id {
+				return &res.MessageList[i]
+			}
+		}
+		return nil
+	}
+
+	now := time.Now().UnixMilli()
+	recalled := models.Message{FromUserID: 1, ToUserID: 2, Content: "oops", CreatedAt: now}
+	edited := models.Message{FromUserID: 1, ToUserID: 2, Content: "helo", CreatedAt: now}
+	deleted := models.Message{FromUserID: 2, ToUserID: 1, Content: "bye", CreatedAt: now}
+	old := models.Message{FromUserID: 1, ToUserID: 2, Content: "old", CreatedAt: now - time.Hour.Milliseconds()}
+	for _, m := range []*models.Message{&recalled, &edited, &deleted, &old} {
+		db.Create(m)
+	}
+	syncTime := getHistory(jordanToken, url.Values{}).SyncTime
+	time.Sleep(2 * time.Millisecond)
+
+	id := func(m models.Message) string { return strconv.Itoa(int(m.ID)) }
+	// 只有发送者可以撤回和编辑，超过时间不能撤回和编辑
+	assert.Equal(t, http.StatusForbidden, request(RecallUrl, url.Values{"token": {michaelToken}, "message_id": {id(recalled)}}))
+	assert.Equal(t, http.StatusForbidden, request(RecallUrl, url.Values{"token": {jordanToken}, "message_id": {id(old)}}))
+	assert.Equal(t, http.StatusForbidden, request(EditUrl, url.Values{"token": {jordanToken}, "message_id": {id(old)}, "content": {"new"}}))
+	assert.Equal(t, http.StatusOK, request(RecallUrl, url.Values{"token": {jordanToken}, "message_id": {id(recalled)}}))
+	assert.Equal(t, http.StatusBadRequest, request(EditUrl, url.Values{"token": {jordanToken}, "message_id": {id(recalled)}, "content": {"new"}}))
+	assert.Equal(t, http.StatusOK, request(EditUrl, url.Values{"token": {jordanToken}, "message_id": {id(edited)}, "content": {"hello"}}))
+	// 接收者也可以删除，只对自己隐藏
+	assert.Equal(t, http.StatusOK, request(DeleteUrl, url.Values{"token": {jordanToken}, "message_id": {id(deleted)}}))
+	assert.Equal(t, http.StatusNotFound, request(DeleteUrl, url.Values{"token": {jordanToken}, "message_id": {id(deleted)}}))
+
+	// 双方都看到撤回提示和编辑后的内容
+	for _, token := range []string{jordanToken, michaelToken} {
+		res := getHistory(token, url.Values{})
+		assert.True(t, findMessage(res, recalled.ID).IsRecalled)
+		assert.Equal(t, consts.RecalledMessageContent, findMessage(res, recalled.ID).Content)
+		assert.True(t, findMessage(res, edited.ID).IsEdited)
+		assert.Equal(t, "hello", findMessage(res, edited.ID).Content)
+	}
+	assert.Nil(t, findMessage(getHistory(jordanToken, url.Values{}), deleted.ID))
+	assert.Equal(t, "bye", findMessage(getHistory(michaelToken, url.Values{}), deleted.ID).Content)
+
+	// 按 sync_time 只同步有变化的消息，删除的消息带有删除标记
+	res := getHistory(jordanToken, url.Values{"sync_time": {syncTime}})
+	assert.Len(t, res.MessageList, 3)
+	assert.True(t, findMessage(res, deleted.ID).IsDeleted)
+	assert.Nil(t, findMessage(res, old.ID))
+	assert.Len(t, getHistory(jordanToken, url.Values{"sync_time": {res.SyncTime}}).MessageList, 0)
+
+	// 和上次同步的最后一条消息在同一毫秒内更新的消息也会同步
+	cursorTime, _, ok := utils.ParseTimeCursor(res.SyncTime)
+	assert.True(t, ok)
+	tied := models.Message{FromUserID: 2, ToUserID: 1, Content: "same ms", UpdatedAt: cursorTime.UnixMilli()}
+	db.Create(&tied)
+	synced := getHistory(jordanToken, url.Values{"sync_time": {res.SyncTime}})
+	assert.Len(t, synced.MessageList, 1)
+	assert.NotNil(t, findMessage(synced, tied.ID))
+	assert.Len(t, getHistory(jordanToken, url.Values{"sync_time": {synced.SyncTime}}).MessageList, 0)
+
+	// 无效的 sync_time
+	values := url.Values{"token": {jordanToken}, "to_user_id": {"2"}, "sync_time": {"bad"}}
+	req, _ := http.NewRequest("GET", GetHistoryUrl+"?"+values.Encode(), nil)
+	response := httptest.NewRecorder()
+	config.Router.ServeHTTP(response, req)
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+}
@@ -0,0 +1,41 @@
+package message
+
+import (
+	"app/modules/realtime"
+	"app/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"log"
+	"net/http"
+)
+
+// 客户端是 App 而不是浏览器页面，不检查 Origin
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	CheckOrigin: func(r *http.Request) bool {
+		return true
+	},
+}
+
+// Connect 建立 WebSocket 连接，和其它接口一样通过 token 参数认证。
+// 连接建立后服务端推送新消息等事件，客户端断线重连后需要通过聊天记录接口的 sync_time 补齐断线期间的变化
+func Connect(c *gin.Context) {
+	tokenString := c.DefaultQuery("token", "")
+	userId, err := utils.ValidateToken(tokenString)
+	if err != nil || userId <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status_code": 1,
+			"status_msg":  "Invalid user ID.",
+		})
+		return
+	}
+
+	// 升级失败时 upgrader 已经返回了错误响应
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		log.Printf("Failed to upgrade websocket connection. Err: %s", err)
+		return
+	}
+	realtime.NewClient(userId, conn).Serve(realtime.DefaultHub)
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// Block 拉黑记录，UserID 拉黑了 BlockedUserID。拉黑是单向的，
+// 但关注、私信、评论、视频流和搜索的限制对双方都生效
+type Block struct {
+	ID            uint      `gorm:"primaryKey"`
+	UserID        uint      `gorm:"index:idx_user_blocked,unique;index:idx_user_block_created,priority:1;not null"`
+	BlockedUserID uint      `gorm:"index:idx_user_blocked,unique;index;not null"`
+	CreatedAt     time.Time `gorm:"index:idx_user_block_created,priority:2"`
+}
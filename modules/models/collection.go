@@ -0,0 +1,46 @@
+package models
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+// Collection 用户创建的收藏夹，和点赞（Favorite）相互独立。封面取收藏夹中排在第一位的视频的封面
+type Collection struct {
+	ID         uint      `gorm:"primaryKey"`
+	UserID     uint      `gorm:"index:idx_user_collection_name,unique;index:idx_user_collection_created,priority:1;not null"`
+	Name       string    `gorm:"type:varchar(64);index:idx_user_collection_name,unique;not null"`
+	IsPublic   bool      `gorm:"not null"`
+	VideoCount int       `gorm:"default:0;not null"`
+	CreatedAt  time.Time `gorm:"index:idx_user_collection_created,priority:2"`
+	UpdatedAt  time.Time
+}
+
+// CollectionVideo 收藏夹中的视频，按 Position 从小到大排列。
+// 调整顺序时取前后两个视频 Position 的中间值，不需要修改其它视频
+type CollectionVideo struct {
+	ID           uint    `gorm:"primaryKey"`
+	CollectionID uint    `gorm:"index:idx_collection_video,unique;index:idx_collection_position;not null"`
+	VideoID      uint    `gorm:"index:idx_collection_video,unique;index;not null"`
+	Position     float64 `gorm:"index:idx_collection_position;not null"`
+	CreatedAt    time.Time
+}
+
+// 添加/移除视频都是幂等的，只有确实插入/删除了记录时才更新收藏夹的视频数
+
+func (cv *CollectionVideo) AfterCreate(tx *gorm.DB) (err error) {
+	if tx.Statement.DB.RowsAffected == 0 { // 视频已经在收藏夹中
+		return nil
+	}
+	return tx.Model(&Collection{}).Where("id = ?", cv.CollectionID).
+		UpdateColumn("video_count", gorm.Expr("video_count + 1")).Error
+}
+
+func (cv *CollectionVideo) AfterDelete(tx *gorm.DB) (err error) {
+	if tx.Statement.DB.RowsAffected == 0 { // 视频不在收藏夹中
+		return nil
+	}
+	return tx.Model(&Collection{}).Where("id = ?", cv.CollectionID).
+		UpdateColumn("video_count", gorm.Expr(
+			"CASE WHEN video_count > 0 THEN video_count - 1 ELSE 0 END")).Error
+}
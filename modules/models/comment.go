@@ -6,26 +6,122 @@ import (
 	"time"
 )
 
+// DeletedCommentContent 被删除但仍有回复的评论，内容替换为占位符
+const DeletedCommentContent = "[deleted]"
+
+// Comment 评论和回复。一级评论的 ParentID 和 RootID 都为 0；
+// 回复的 RootID 为所在楼层的一级评论，ParentID 为直接回复的评论。
+// 视频的 comment_count 和一级评论的 reply_count 都只统计未删除的评论
 type Comment struct {
-	ID        uint `gorm:"primary_key;not null"`
-	UserID    uint `gorm:"not null"`
-	User      User `gorm:"foreignKey:UserID"`
-	VideoID   uint `gorm:"index:idx_video_comment_created;not null"`
-	Content   string
-	CreatedAt time.Time `gorm:"index:idx_video_comment_created"`
+	ID            uint `gorm:"primary_key;not null"`
+	UserID        uint `gorm:"not null"`
+	User          User `gorm:"foreignKey:UserID"`
+	VideoID       uint `gorm:"index:idx_video_comment_created;not null"`
+	ParentID      uint `gorm:"index;default:0;not null"`
+	RootID        uint `gorm:"index:idx_root_comment;default:0;not null"`
+	ReplyToUserID uint `gorm:"default:0;not null"`
+	ReplyCount    int  `gorm:"default:0;not null"` // 楼层内的回复数，只有一级评论有值
+	LikeCount     int  `gorm:"default:0;not null"` // 点赞数
+	IsDeleted     bool `gorm:"default:false;not null"`
+	Content       string
+	CreatedAt     time.Time `gorm:"index:idx_video_comment_created;index:idx_root_comment"`
 }
 
 func (f *Comment) AfterCreate(tx *gorm.DB) (err error) {
 	fmt.Println("Video ID: ", f.VideoID)
-	tx.Model(&Video{}).Where("id = ?", f.VideoID).
-		UpdateColumn("comment_count", gorm.Expr("comment_count + 1"))
-	return
+	if err = tx.Model(&Video{}).Where("id = ?", f.VideoID).
+		UpdateColumn("comment_count", gorm.Expr("comment_count + 1")).Error; err != nil {
+		return err
+	}
+	// 一级评论通知视频发布者，回复通知被回复的用户
+	if f.RootID == 0 {
+		var video Video
+		if err = tx.Select("id", "user_id").First(&video, f.VideoID).Error; err != nil {
+			return err
+		}
+		return Notify(tx, video.UserID, NotifyComment, f.VideoID, f.UserID)
+	}
+	// 回复需要更新所在楼层的回复数
+	if err = tx.Model(&Comment{}).Where("id = ?", f.RootID).
+		UpdateColumn("reply_count", gorm.Expr("reply_count + 1")).Error; err != nil {
+		return err
+	}
+	return Notify(tx, f.ReplyToUserID, NotifyReply, f.RootID, f.UserID)
 }
 
 func (f *Comment) AfterDelete(tx *gorm.DB) (err error) {
 	fmt.Println("Video ID: ", f.VideoID)
-	tx.Model(&Video{}).Where("id = ?", f.VideoID).
+	// 已经替换为占位符的评论在标记删除时已经更新过计数和@
+	if f.IsDeleted {
+		return nil
+	}
+	if err = f.deleteMentions(tx); err != nil {
+		return err
+	}
+	return f.decreaseCounts(tx)
+}
+
+// MarkDeleted 将仍有回复的评论替换为占位符，并像删除一样更新计数
+func (f *Comment) MarkDeleted(tx *gorm.DB) error {
+	if err := tx.Model(f).Updates(map[string]interface{}{
+		"is_deleted": true,
+		"content":    DeletedCommentContent,
+	}).Error; err != nil {
+		return err
+	}
+	if err := f.deleteMentions(tx); err != nil {
+		return err
+	}
+	return f.decreaseCounts(tx)
+}
+
+// deleteMentions 删除评论中的@，逐条删除以便触发 Mention 的 AfterDelete 撤回通知
+func (f *Comment) deleteMentions(tx *gorm.DB) error {
+	var mentions []Mention
+	if err := tx.Where("source = ? AND target_id = ?", MentionComment, f.ID).Find(&mentions).Error; err != nil {
+		return err
+	}
+	if len(mentions) == 0 {
+		return nil
+	}
+	return tx.Delete(&mentions).Error
+}
+
+// decreaseCounts 视频的评论数 - 1，回复还需要将所在楼层的回复数 - 1，并撤回评论产生的通知
+func (f *Comment) decreaseCounts(tx *gorm.DB) (err error) {
+	if err = tx.Model(&Video{}).Where("id = ?", f.VideoID).
 		UpdateColumn("comment_count", gorm.Expr(
-			"CASE WHEN comment_count > 0 THEN comment_count - 1 ELSE 0 END"))
-	return
+			"CASE WHEN comment_count > 0 THEN comment_count - 1 ELSE 0 END")).Error; err != nil {
+		return err
+	}
+	if f.RootID != 0 {
+		if err = tx.Model(&Comment{}).Where("id = ?", f.RootID).
+			UpdateColumn("reply_count", gorm.Expr(
+				"CASE WHEN reply_count > 0 THEN reply_count - 1 ELSE 0 END")).Error; err != nil {
+			return err
+		}
+	}
+	return f.retractNotification(tx)
+}
+
+// retractNotification 撤回 AfterCreate 发出的评论或回复通知。
+// 同一个用户在同一个视频下还有其它一级评论（或者在同一个楼层还有回复同一个人的评论）时保留通知
+func (f *Comment) retractNotification(tx *gorm.DB) error {
+	query := tx.Model(&Comment{}).Where("user_id = ? AND is_deleted = ? AND id <> ?", f.UserID, false, f.ID)
+	notifyType, userId, targetId := NotifyReply, f.ReplyToUserID, f.RootID
+	if f.RootID == 0 {
+		var video Video
+		if err := tx.Select("id", "user_id").Limit(1).Find(&video, f.VideoID).Error; err != nil || video.ID == 0 {
+			return err
+		}
+		notifyType, userId, targetId = NotifyComment, video.UserID, f.VideoID
+		query = query.Where("video_id = ? AND root_id = 0", f.VideoID)
+	} else {
+		query = query.Where("root_id = ? AND reply_to_user_id = ?", f.RootID, f.ReplyToUserID)
+	}
+	var count int64
+	if err := query.Count(&count).Error; err != nil || count > 0 {
+		return err
+	}
+	return Retract(tx, userId, notifyType, targetId, f.UserID)
 }
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+type CommentLike struct {
+	ID        uint `gorm:"primary_key"`
+	UserID    uint `gorm:"index:idx_user_comment,unique"`
+	CommentID uint `gorm:"index:idx_user_comment,unique;index"`
+	CreatedAt time.Time
+}
+
+// 创建Hook，在点赞/取消点赞评论后，自动给评论的like_count +/- 1。
+// 点赞和取消点赞都是幂等的，只有确实插入/删除了记录时才更新计数
+
+func (l *CommentLike) AfterCreate(tx *gorm.DB) (err error) {
+	if tx.Statement.DB.RowsAffected == 0 { // 已经点赞过，没有插入新记录
+		return nil
+	}
+	return tx.Model(&Comment{}).Where("id = ?", l.CommentID).
+		UpdateColumn("like_count", gorm.Expr("like_count + 1")).Error
+}
+
+func (l *CommentLike) AfterDelete(tx *gorm.DB) (err error) {
+	if tx.Statement.DB.RowsAffected == 0 { // 还没有点赞过，没有删除记录
+		return nil
+	}
+	return tx.Model(&Comment{}).Where("id = ?", l.CommentID).
+		UpdateColumn("like_count", gorm.Expr(
+			"CASE WHEN like_count > 0 THEN like_count - 1 ELSE 0 END")).Error
+}
@@ -0,0 +1,93 @@
+package models
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Conversation 会话，每两个聊过天的用户之间有两行，双方各一行，由 Message 的 hook 维护。
+// ReadMessageID 为已读游标，对方发来的 ID 不大于它的消息均为已读，对方据此显示 "已读"
+type Conversation struct {
+	ID            uint  `gorm:"primaryKey"`
+	UserID        uint  `gorm:"index:idx_user_peer,unique;index:idx_user_active,priority:1;not null"`
+	PeerID        uint  `gorm:"index:idx_user_peer,unique;not null"`
+	LastMessageID uint  `gorm:"not null"`
+	LastActiveAt  int64 `gorm:"index:idx_user_active,priority:2;not null"` // 最近一条消息的时间（毫秒）
+	UnreadCount   int   `gorm:"default:0;not null"`
+	ReadMessageID uint  `gorm:"default:0;not null"`
+}
+
+// upsertConversation 更新 userId 和 peerId 会话的最近一条消息，unread 为 true 时未读数+1
+func upsertConversation(tx *gorm.DB, userId, peerId uint, message *Message, unread bool) error {
+	conversation := Conversation{
+		UserID:        userId,
+		PeerID:        peerId,
+		LastMessageID: message.ID,
+		LastActiveAt:  message.CreatedAt,
+	}
+	updates := map[string]interface{}{
+		"last_message_id": message.ID,
+		"last_active_at":  message.CreatedAt,
+	}
+	if unread {
+		conversation.UnreadCount = 1
+		updates["unread_count"] = gorm.Expr("unread_count + 1")
+	}
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "peer_id"}},
+		DoUpdates: clause.Assignments(updates),
+	}).Create(&conversation).Error
+}
+
+// MarkRead 将 userId 和 peerId 会话的已读游标前移到 messageId 并重新计算未读数，游标只会前移
+func MarkRead(db *gorm.DB, userId, peerId, messageId uint) (Conversation, error) {
+	var conversation Conversation
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id = ? AND peer_id = ?", userId, peerId).First(&conversation).Error; err != nil {
+			return err
+		}
+		if messageId <= conversation.ReadMessageID {
+			return nil
+		}
+		var unread int64
+		if err := tx.Model(&Message{}).
+			Where("from_user_id = ? AND to_user_id = ? AND id > ? AND recalled = ?", peerId, userId, messageId, false).
+			Count(&unread).Error; err != nil {
+			return err
+		}
+		conversation.ReadMessageID = messageId
+		conversation.UnreadCount = int(unread)
+		return tx.Model(&conversation).Updates(map[string]interface{}{
+			"read_message_id": conversation.ReadMessageID,
+			"unread_count":    conversation.UnreadCount,
+		}).Error
+	})
+	return conversation, err
+}
+
+// ReadCursors 查询 userId 和 peerId 双方的已读游标
+func ReadCursors(db *gorm.DB, userId, peerId uint) (mine, peer uint) {
+	var conversations []Conversation
+	db.Where("user_id = ? AND peer_id = ?", userId, peerId).
+		Or("user_id = ? AND peer_id = ?", peerId, userId).Find(&conversations)
+	for _, c := range conversations {
+		if c.UserID == userId {
+			mine = c.ReadMessageID
+		} else {
+			peer = c.ReadMessageID
+		}
+	}
+	return mine, peer
+}
+
+// BackfillConversations 根据已有的消息生成会话，在会话表刚加入时调用。
+// 之前的消息没有已读状态，全部视为已读
+func BackfillConversations(db *gorm.DB) error {
+	return db.Exec("INSERT INTO conversations " +
+		"(user_id, peer_id, last_message_id, last_active_at, unread_count, read_message_id) " +
+		"SELECT user_id, peer_id, MAX(id), MAX(created_at), 0, MAX(id) FROM (" +
+		"SELECT from_user_id AS user_id, to_user_id AS peer_id, id, created_at FROM messages " +
+		"UNION ALL SELECT to_user_id, from_user_id, id, created_at FROM messages) m " +
+		"GROUP BY user_id, peer_id").Error
+}
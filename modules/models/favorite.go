@@ -6,18 +6,23 @@ import (
 )
 
 type Favorite struct {
-	ID        uint `gorm:"primary_key"`
-	UserID    uint `gorm:"index:idx_user_video,unique"`
-	VideoID   uint `gorm:"index:idx_user_video,unique"`
-	CreatedAt time.Time
+	ID        uint      `gorm:"primary_key"`
+	UserID    uint      `gorm:"index:idx_user_video,unique;index:idx_user_created,priority:1"`
+	VideoID   uint      `gorm:"index:idx_user_video,unique"`
+	CreatedAt time.Time `gorm:"index:idx_user_created,priority:2"`
 }
 
 // 创建Hook，在点赞/取消点赞记录生成后，自动给: 1. 视频的favorite_count +/- 1
 // 2. 为视频发布者的Profile中的获赞数量TotalFavorited +/- 1
 // 3. 为点赞者的Profile中的喜欢数FavoriteCount +/- 1
-// 如果更新失败，点赞/取消点赞也会被回滚，保持数据一致性
+// 如果更新失败，点赞/取消点赞也会被回滚，保持数据一致性。
+// 点赞和取消点赞都是幂等的，只有确实插入/删除了记录时才更新计数
 
 func (f *Favorite) AfterCreate(tx *gorm.DB) (err error) {
+	if tx.Statement.DB.RowsAffected == 0 { // 已经点赞过，没有插入新记录
+		return nil
+	}
+
 	// 加载Video，以获取发布该视频的用户
 	var video Video
 	if err = tx.First(&video, f.VideoID).Error; err != nil {
@@ -42,10 +47,15 @@ func (f *Favorite) AfterCreate(tx *gorm.DB) (err error) {
 		return err
 	}
 
-	return nil
+	// 通知视频发布者
+	return Notify(tx, video.UserID, NotifyLike, f.VideoID, f.UserID)
 }
 
 func (f *Favorite) AfterDelete(tx *gorm.DB) (err error) {
+	if tx.Statement.DB.RowsAffected == 0 { // 还没有点赞过，没有删除记录
+		return nil
+	}
+
 	// 加载Video，以获取发布该视频的用户
 	var video Video
 	if err = tx.First(&video, f.VideoID).Error; err != nil {
@@ -73,5 +83,6 @@ func (f *Favorite) AfterDelete(tx *gorm.DB) (err error) {
 		return err
 	}
 
-	return nil
+	// 从视频发布者未读的通知中撤回
+	return Retract(tx, video.UserID, NotifyLike, f.VideoID, f.UserID)
 }
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// 负反馈的原因
+const (
+	FeedbackVideo   = 1 // 对这个视频不感兴趣
+	FeedbackCreator = 2 // 不看这个作者
+	FeedbackKeyword = 3 // 屏蔽标题中含有关键词的视频
+)
+
+// FeedFeedback 用户对视频流的负反馈，GetFeed 查询时会据此过滤视频。
+// 根据 Reason 的不同，只有 VideoID / CreatorID / Keyword 中的一个有值
+type FeedFeedback struct {
+	ID        uint   `gorm:"primaryKey"`
+	UserID    uint   `gorm:"index:idx_user_feedback,unique,priority:1;not null"`
+	Reason    int    `gorm:"index:idx_user_feedback,unique,priority:2;not null"`
+	VideoID   uint   `gorm:"index:idx_user_feedback,unique,priority:3;default:0;not null"`
+	CreatorID uint   `gorm:"index:idx_user_feedback,unique,priority:4;default:0;not null"`
+	Keyword   string `gorm:"index:idx_user_feedback,unique,priority:5;type:varchar(64);default:'';not null"`
+	CreatedAt time.Time
+}
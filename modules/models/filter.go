@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// ContentFilterLog 敏感词命中日志，供审核人员查看。需要人工审核的内容在 ContentReview 中
+type ContentFilterLog struct {
+	ID        uint   `gorm:"primaryKey"`
+	UserID    uint   `gorm:"index;not null"`
+	Source    string `gorm:"type:varchar(32);not null"` // 内容来源：评论、私信、标题、签名
+	Content   string `gorm:"type:text"`
+	Word      string `gorm:"type:varchar(128)"`
+	Category  string `gorm:"type:varchar(64);index"`
+	Action    int    `gorm:"index;not null"`
+	Rejected  bool   `gorm:"default:false;not null"`
+	CreatedAt time.Time
+}
+
+// 审核状态
+const (
+	ReviewPending  = 0
+	ReviewApproved = 1
+	ReviewRejected = 2
+)
+
+// ContentReview 命中 review 敏感词、等待人工审核的内容。审核通过之前内容不会发布：
+// 评论和私信在审核通过后才创建，标题和签名在审核通过后才生效。
+// 评论的 TargetID 为视频ID，ParentID 为被回复的评论ID；私信的 TargetID 为接收者ID，
+// 编辑消息时 ParentID 为被编辑的消息ID；标题的 TargetID 为视频ID；签名不使用这两个字段
+type ContentReview struct {
+	ID         uint   `gorm:"primaryKey"`
+	UserID     uint   `gorm:"index;not null"`            // 发布内容的用户
+	Source     string `gorm:"type:varchar(32);not null"` // 内容来源：评论、私信、标题、签名
+	TargetID   uint   `gorm:"default:0;not null"`
+	ParentID   uint   `gorm:"default:0;not null"`
+	Content    string `gorm:"type:text"`
+	Status     int    `gorm:"index;default:0;not null"` // 审核状态
+	ReviewerID uint   `gorm:"default:0;not null"`
+	CreatedAt  time.Time
+	ReviewedAt *time.Time
+}
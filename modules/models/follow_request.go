@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// FollowRequest 关注私密账号时的关注申请，同意后才创建 Relation，关注数和粉丝数也在同意后才变化
+type FollowRequest struct {
+	ID         uint      `gorm:"primaryKey"`
+	FromUserID uint      `gorm:"index:idx_from_to_request,unique;not null"`
+	ToUserID   uint      `gorm:"index:idx_from_to_request,unique;index:idx_to_request_created,priority:1;not null"`
+	CreatedAt  time.Time `gorm:"index:idx_to_request_created,priority:2"`
+}
@@ -0,0 +1,12 @@
+package models
+
+// WatchHistory 观看历史，每个用户对每个视频只保留一条记录，
+// 记录最后一次观看的时间和播放进度，用于"继续观看"
+type WatchHistory struct {
+	ID         uint  `gorm:"primaryKey"`
+	UserID     uint  `gorm:"index:idx_user_video_history,unique;index:idx_user_watched,priority:1;not null"`
+	VideoID    uint  `gorm:"index:idx_user_video_history,unique;not null"`
+	Video      Video `gorm:"foreignKey:VideoID"`
+	PositionMs int64 `gorm:"default:0;not null"`                         // 播放进度（毫秒）
+	WatchedAt  int64 `gorm:"index:idx_user_watched,priority:2;not null"` // 最后观看时间（毫秒时间戳）
+}
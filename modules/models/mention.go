@@ -0,0 +1,47 @@
+package models
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+// 被@的位置
+const (
+	MentionComment = 1 // 评论，TargetID 为评论ID
+	MentionTitle   = 2 // 视频标题，TargetID 为视频ID
+)
+
+// Mention 评论或视频标题中的@。同一段文本中每个用户只记录第一次出现的位置，
+// Start 和 End 为 "@用户名" 在文本中的字符（rune）下标，用户改名后客户端仍可以按 UserID 渲染链接
+type Mention struct {
+	ID         uint      `gorm:"primaryKey"`
+	UserID     uint      `gorm:"index:idx_user_mention;index:idx_target_user,unique;not null"` // 被@的用户
+	FromUserID uint      `gorm:"not null"`
+	Source     int       `gorm:"index:idx_target_user,unique;not null"`
+	TargetID   uint      `gorm:"index:idx_target_user,unique;not null"`
+	VideoID    uint      `gorm:"index;not null"`
+	Start      int       `gorm:"not null"`
+	End        int       `gorm:"not null"`
+	IsRead     bool      `gorm:"default:false;not null"`
+	CreatedAt  time.Time `gorm:"index:idx_user_mention"`
+}
+
+// AfterCreate 通知被@的用户
+func (m *Mention) AfterCreate(tx *gorm.DB) error {
+	return Notify(tx, m.UserID, NotifyMention, m.VideoID, m.FromUserID)
+}
+
+// AfterDelete 撤回@的通知，同一个用户在这个视频中还有其它@时保留。
+// 按条件批量删除时拿不到被删除的记录，需要先查出来再删除
+func (m *Mention) AfterDelete(tx *gorm.DB) error {
+	if m.UserID == 0 {
+		return nil
+	}
+	var count int64
+	if err := tx.Model(&Mention{}).
+		Where("user_id = ? AND from_user_id = ? AND video_id = ? AND id <> ?", m.UserID, m.FromUserID, m.VideoID, m.ID).
+		Count(&count).Error; err != nil || count > 0 {
+		return err
+	}
+	return Retract(tx, m.UserID, NotifyMention, m.VideoID, m.FromUserID)
+}
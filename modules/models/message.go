@@ -1,9 +1,62 @@
 package models
 
+import "gorm.io/gorm"
+
+// Message 私信。撤回后清空 Content，编辑后记录 EditedAt，删除只对删除的一方隐藏。
+// UpdatedAt 在创建、撤回、编辑和删除时更新，客户端按它同步消息的变化
 type Message struct {
-	ID         uint `gorm:"primary_key"`
-	FromUserID uint `gorm:"index:idx_from_to_created,priority:1;index:idx_to_from_created,priority:2"`
-	ToUserID   uint `gorm:"index:idx_from_to_created,priority:2;index:idx_to_from_created,priority:1"`
-	Content    string
-	CreatedAt  int64 `gorm:"index:idx_from_to_created,priority:3;index:idx_to_from_created,priority:3"`
+	ID          uint `gorm:"primary_key"`
+	FromUserID  uint `gorm:"index:idx_from_to_created,priority:1;index:idx_to_from_created,priority:2;index:idx_from_to_updated,priority:1"`
+	ToUserID    uint `gorm:"index:idx_from_to_created,priority:2;index:idx_to_from_created,priority:1;index:idx_from_to_updated,priority:2"`
+	Content     string
+	CreatedAt   int64 `gorm:"index:idx_from_to_created,priority:3;index:idx_to_from_created,priority:3"`
+	UpdatedAt   int64 `gorm:"autoUpdateTime:milli;default:0;not null;index:idx_from_to_updated,priority:3"`
+	EditedAt    int64 `gorm:"default:0;not null"` // 最近一次编辑的时间（毫秒），0 表示没有编辑过
+	Recalled    bool  `gorm:"default:false;not null"`
+	FromDeleted bool  `gorm:"default:false;not null"` // 发送者删除了这条消息
+	ToDeleted   bool  `gorm:"default:false;not null"` // 接收者删除了这条消息
+}
+
+// AfterCreate hook for the Message model.
+func (message *Message) AfterCreate(tx *gorm.DB) (err error) {
+	// 1. 更新发送者的会话
+	if err = upsertConversation(tx, message.FromUserID, message.ToUserID, message, false); err != nil {
+		return err
+	}
+
+	// 2. 更新接收者的会话，未读数+1
+	return upsertConversation(tx, message.ToUserID, message.FromUserID, message, true)
+}
+
+// DeletedBy 判断 userId 是否删除了这条消息
+func (message *Message) DeletedBy(userId uint) bool {
+	return (message.FromUserID == userId && message.FromDeleted) || (message.ToUserID == userId && message.ToDeleted)
+}
+
+// NotDeletedBy 排除 userId 删除了的消息
+func NotDeletedBy(userId uint) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("NOT (from_user_id = ? AND from_deleted = ?) AND NOT (to_user_id = ? AND to_deleted = ?)",
+			userId, true, userId, true)
+	}
+}
+
+// RecallMessage 撤回消息并清空内容。接收者还没有读这条消息时，接收者会话的未读数-1
+func RecallMessage(db *gorm.DB, message *Message) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(message).Where("recalled = ?", false).
+			Updates(map[string]interface{}{"recalled": true, "content": ""})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 {
+			if err := tx.Model(&Conversation{}).
+				Where("user_id = ? AND peer_id = ? AND read_message_id < ? AND unread_count > 0",
+					message.ToUserID, message.FromUserID, message.ID).
+				UpdateColumn("unread_count", gorm.Expr("unread_count - 1")).Error; err != nil {
+				return err
+			}
+		}
+		return tx.First(message, message.ID).Error
+	})
 }
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// Mute 静音记录，UserID 静音了 MutedUserID。被静音的用户的视频不会出现在视频流中，
+// 评论会被折叠，但关注关系不变，被静音的用户也不会收到通知
+type Mute struct {
+	ID          uint      `gorm:"primaryKey"`
+	UserID      uint      `gorm:"index:idx_user_muted,unique;index:idx_user_mute_created,priority:1;not null"`
+	MutedUserID uint      `gorm:"index:idx_user_muted,unique;not null"`
+	CreatedAt   time.Time `gorm:"index:idx_user_mute_created,priority:2"`
+}
@@ -0,0 +1,119 @@
+package models
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+// 通知类型
+const (
+	NotifyLike    = 1 // 点赞了你的视频，TargetID 为视频ID
+	NotifyComment = 2 // 评论了你的视频，TargetID 为视频ID
+	NotifyReply   = 3 // 回复了你的评论，TargetID 为楼层的一级评论ID
+	NotifyFollow  = 4 // 关注了你，TargetID 为 0
+	NotifyMention = 5 // 在评论或标题中@了你，TargetID 为视频ID
+)
+
+// Notification 通知。同一个用户、同类型、同一个对象的事件在已读之前聚合为一条通知，
+// 例如 "Alice 和其他 12 人赞了你的视频"；已读之后的新事件会生成新的通知
+type Notification struct {
+	ID            uint `gorm:"primaryKey"`
+	UserID        uint `gorm:"index:idx_user_notification;index:idx_user_group;not null"` // 接收通知的用户
+	Type          int  `gorm:"index:idx_user_group;not null"`
+	TargetID      uint `gorm:"index:idx_user_group;default:0;not null"`
+	ActorCount    int  `gorm:"default:0;not null"`
+	LatestActorID uint `gorm:"default:0;not null"`
+	IsRead        bool `gorm:"index:idx_user_group;default:false;not null"`
+	CreatedAt     time.Time
+	UpdatedAt     time.Time `gorm:"index:idx_user_notification;index"`
+}
+
+// NotificationActor 一条聚合通知中触发事件的用户
+type NotificationActor struct {
+	ID             uint      `gorm:"primaryKey"`
+	NotificationID uint      `gorm:"index:idx_notification_actor,unique;not null"`
+	ActorID        uint      `gorm:"index:idx_notification_actor,unique;not null"`
+	CreatedAt      time.Time `gorm:"index"`
+}
+
+// Notify 记录一次事件，在 AfterCreate 中调用，和触发事件的写操作在同一个事务中。
+// 有未读的同组通知时合并进去，否则创建新的通知。自己触发的事件不通知
+func Notify(tx *gorm.DB, userId uint, notifyType int, targetId uint, actorId uint) error {
+	if userId == 0 || userId == actorId {
+		return nil
+	}
+
+	var n Notification
+	err := tx.Where("user_id = ? AND type = ? AND target_id = ? AND is_read = ?",
+		userId, notifyType, targetId, false).Order("id desc").First(&n).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		n = Notification{UserID: userId, Type: notifyType, TargetID: targetId}
+		if err = tx.Create(&n).Error; err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+
+	// 同一个用户重复触发（例如取消点赞后再点赞）只更新时间，不重复计数
+	actor := NotificationActor{NotificationID: n.ID, ActorID: actorId, CreatedAt: time.Now()}
+	result := tx.Clauses(clause.OnConflict{DoUpdates: clause.AssignmentColumns([]string{"created_at"})}).
+		Create(&actor)
+	if result.Error != nil {
+		return result.Error
+	}
+	updates := map[string]interface{}{
+		"latest_actor_id": actorId,
+		"updated_at":      time.Now(),
+	}
+	// MySQL 的 ON DUPLICATE KEY UPDATE 插入新行时 RowsAffected 为 1，更新已有行时为 2
+	if result.RowsAffected == 1 {
+		updates["actor_count"] = gorm.Expr("actor_count + 1")
+	}
+	return tx.Model(&n).Updates(updates).Error
+}
+
+// Retract 撤回一次事件（取消点赞、取消关注），在 AfterDelete 中调用。
+// 只从未读的通知中移除，没有其他用户时删除整条通知；已读的通知保持不变
+func Retract(tx *gorm.DB, userId uint, notifyType int, targetId uint, actorId uint) error {
+	var n Notification
+	err := tx.Where("user_id = ? AND type = ? AND target_id = ? AND is_read = ?",
+		userId, notifyType, targetId, false).Order("id desc").First(&n).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	result := tx.Where("notification_id = ? AND actor_id = ?", n.ID, actorId).Delete(&NotificationActor{})
+	if result.Error != nil || result.RowsAffected == 0 {
+		return result.Error
+	}
+	if n.ActorCount <= 1 {
+		return tx.Delete(&n).Error
+	}
+
+	// 最新的用户被撤回时，改为剩下的用户中最新的一个
+	var latest NotificationActor
+	if err := tx.Where("notification_id = ?", n.ID).Order("created_at desc").First(&latest).Error; err != nil {
+		return err
+	}
+	// 使用 UpdateColumns 不修改 updated_at，撤回不应该让通知排到列表前面
+	return tx.Model(&n).UpdateColumns(map[string]interface{}{
+		"actor_count":     gorm.Expr("CASE WHEN actor_count > 0 THEN actor_count - 1 ELSE 0 END"),
+		"latest_actor_id": latest.ActorID,
+	}).Error
+}
+
+// DeleteNotifications 删除通知以及通知中的用户
+func DeleteNotifications(tx *gorm.DB, ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	if err := tx.Where("notification_id IN ?", ids).Delete(&NotificationActor{}).Error; err != nil {
+		return err
+	}
+	return tx.Delete(&Notification{}, ids).Error
+}
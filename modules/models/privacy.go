@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// 列表的可见范围
+const (
+	PrivacyPublic  = 0 // 所有人可见
+	PrivacyFriends = 1 // 互相关注的好友可见
+	PrivacyOnlyMe  = 2 // 仅自己可见
+)
+
+// PrivacySetting 用户的隐私设置，没有记录时所有列表都是公开的。
+// IsPrivate 为私密账号，关注需要经过同意，视频只对粉丝可见
+type PrivacySetting struct {
+	ID            uint `gorm:"primaryKey"`
+	UserID        uint `gorm:"unique;not null"`
+	FavoriteList  int  `gorm:"default:0;not null"` // 喜欢列表
+	FollowingList int  `gorm:"default:0;not null"` // 关注列表
+	FollowerList  int  `gorm:"default:0;not null"` // 粉丝列表
+	IsPrivate     bool `gorm:"not null"`
+	UpdatedAt     time.Time
+}
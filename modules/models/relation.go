@@ -7,15 +7,20 @@ import (
 
 type Relation struct {
 	ID         uint      `gorm:"primaryKey"`
-	FromUserId uint      `gorm:"index:idx_from_user;index:idx_relationship,unique"`
-	ToUserId   uint      `gorm:"index:idx_to_user;index:idx_relationship,unique"`
-	CreatedAt  time.Time `gorm:"autoCreateTime"`
+	FromUserId uint      `gorm:"index:idx_from_user;index:idx_relationship,unique;index:idx_from_created,priority:1;index:idx_from_mutual"`
+	ToUserId   uint      `gorm:"index:idx_to_user;index:idx_relationship,unique;index:idx_to_created,priority:1"`
+	CreatedAt  time.Time `gorm:"autoCreateTime;index:idx_from_created,priority:2;index:idx_to_created,priority:2"`
+	IsMutual   bool      `gorm:"default:false;not null;index:idx_from_mutual"` // 对方是否也关注了自己，即是否为好友
 	ToUser     User      `gorm:"foreignKey:ToUserId"`
 	FromUser   User      `gorm:"foreignKey:FromUserId"`
 }
 
 // AfterCreate hook for the Relation model.
 func (relation *Relation) AfterCreate(tx *gorm.DB) (err error) {
+	if tx.Statement.DB.RowsAffected == 0 { // 已经关注过
+		return nil
+	}
+
 	// 1. 被关注者的粉丝数+1
 	err = tx.Model(&UserProfile{}).Where("user_id = ?", relation.ToUserId).
 		UpdateColumn("follower_count", gorm.Expr("follower_count + 1")).Error
@@ -26,11 +31,37 @@ func (relation *Relation) AfterCreate(tx *gorm.DB) (err error) {
 	// 2. 关注者的关注数+1
 	err = tx.Model(&UserProfile{}).Where("user_id = ?", relation.FromUserId).
 		UpdateColumn("follow_count", gorm.Expr("follow_count + 1")).Error
-	return err
+	if err != nil {
+		return err
+	}
+
+	// 3. 如果被关注者也关注了关注者，两条关注关系都标记为互相关注，双方的好友数+1
+	result := tx.Model(&Relation{}).
+		Where("from_user_id = ? AND to_user_id = ?", relation.ToUserId, relation.FromUserId).
+		UpdateColumn("is_mutual", true)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		relation.IsMutual = true
+		if err = tx.Model(relation).UpdateColumn("is_mutual", true).Error; err != nil {
+			return err
+		}
+		if err = updateFriendCount(tx, relation, "friend_count + 1"); err != nil {
+			return err
+		}
+	}
+
+	// 4. 通知被关注者
+	return Notify(tx, relation.ToUserId, NotifyFollow, 0, relation.FromUserId)
 }
 
 // AfterDelete hook for the Relation model.
 func (relation *Relation) AfterDelete(tx *gorm.DB) (err error) {
+	if tx.Statement.DB.RowsAffected == 0 { // 没有关注过
+		return nil
+	}
+
 	// 1. 被关注者的粉丝数-1 (确保不会小于0)
 	err = tx.Model(&UserProfile{}).Where("user_id = ?", relation.ToUserId).
 		UpdateColumn("follower_count", gorm.Expr(
@@ -43,5 +74,71 @@ func (relation *Relation) AfterDelete(tx *gorm.DB) (err error) {
 	err = tx.Model(&UserProfile{}).Where("user_id = ?", relation.FromUserId).
 		UpdateColumn("follow_count", gorm.Expr(
 			"CASE WHEN follow_count > 0 THEN follow_count - 1 ELSE 0 END")).Error
-	return err
+	if err != nil {
+		return err
+	}
+
+	// 3. 如果原来是互相关注，对方的关注关系不再是互相关注，双方的好友数-1
+	result := tx.Model(&Relation{}).
+		Where("from_user_id = ? AND to_user_id = ? AND is_mutual = ?", relation.ToUserId, relation.FromUserId, true).
+		UpdateColumn("is_mutual", false)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		err = updateFriendCount(tx, relation, "CASE WHEN friend_count > 0 THEN friend_count - 1 ELSE 0 END")
+		if err != nil {
+			return err
+		}
+	}
+
+	// 4. 从被关注者未读的通知中撤回
+	return Retract(tx, relation.ToUserId, NotifyFollow, 0, relation.FromUserId)
+}
+
+// updateFriendCount 更新关注关系双方的好友数
+func updateFriendCount(tx *gorm.DB, relation *Relation, expr string) error {
+	return tx.Model(&UserProfile{}).
+		Where("user_id IN ?", []uint{relation.FromUserId, relation.ToUserId}).
+		UpdateColumn("friend_count", gorm.Expr(expr)).Error
+}
+
+// IsFriend 查询两个用户是否互相关注
+func IsFriend(db *gorm.DB, userId, otherUserId uint) (bool, error) {
+	var count int64
+	err := db.Model(&Relation{}).
+		Where("from_user_id = ? AND to_user_id = ? AND is_mutual = ?", userId, otherUserId, true).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// FriendSet 用一次查询找出 userIds 中有哪些用户和 userId 互相关注
+func FriendSet(db *gorm.DB, userId uint, userIds []uint) (map[uint]bool, error) {
+	friendSet := make(map[uint]bool)
+	if len(userIds) == 0 {
+		return friendSet, nil
+	}
+	var friendIds []uint
+	if err := db.Model(&Relation{}).
+		Where("from_user_id = ? AND to_user_id IN ? AND is_mutual = ?", userId, userIds, true).
+		Pluck("to_user_id", &friendIds).Error; err != nil {
+		return nil, err
+	}
+	for _, id := range friendIds {
+		friendSet[id] = true
+	}
+	return friendSet, nil
+}
+
+// BackfillMutual 根据已有的关注关系重新计算 is_mutual 和好友数，在 is_mutual 字段刚加入时调用
+func BackfillMutual(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("UPDATE relations r JOIN relations r2 " +
+			"ON r2.from_user_id = r.to_user_id AND r2.to_user_id = r.from_user_id " +
+			"SET r.is_mutual = true").Error; err != nil {
+			return err
+		}
+		return tx.Exec("UPDATE user_profiles p SET friend_count = " +
+			"(SELECT COUNT(*) FROM relations r WHERE r.from_user_id = p.user_id AND r.is_mutual = true)").Error
+	})
 }
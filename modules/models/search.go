@@ -0,0 +1,9 @@
+package models
+
+// SearchHistory 用户的搜索历史，同一个搜索词只保留最近一次搜索的时间
+type SearchHistory struct {
+	ID         uint   `gorm:"primaryKey"`
+	UserID     uint   `gorm:"index:idx_user_query,unique;index:idx_user_searched,priority:1;not null"`
+	Query      string `gorm:"index:idx_user_query,unique;type:varchar(200);not null"`
+	SearchedAt int64  `gorm:"index:idx_user_searched,priority:2;not null"` // 最近一次搜索的时间（毫秒时间戳）
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// 推荐关注的原因
+const (
+	SuggestFriendOfFriend = 1 // 关注的人也关注了他，ViaUserID 为其中一个关注的人
+	SuggestSameFavorite   = 2 // 点赞了相同的视频，ViaCount 为相同视频的数量
+	SuggestPopular        = 3 // 自己的粉丝关注了这个创作者，ViaUserID 为其中一个粉丝
+)
+
+// FollowSuggestion 推荐关注，由后台任务定期计算。Reason 为得分最高的推荐原因，
+// 原因描述在查询时根据 ViaUserID 和 ViaCount 生成，用户改名后不需要重新计算。
+// 用户不感兴趣的推荐标记为 Dismissed，重新计算时保留，不会再次出现
+type FollowSuggestion struct {
+	ID              uint    `gorm:"primaryKey"`
+	UserID          uint    `gorm:"index:idx_user_suggested,unique;index:idx_user_score,priority:1;not null"`
+	SuggestedUserID uint    `gorm:"index:idx_user_suggested,unique;not null"`
+	Reason          int     `gorm:"not null"`
+	ViaUserID       uint    `gorm:"default:0;not null"`
+	ViaCount        int     `gorm:"default:0;not null"`
+	Score           float64 `gorm:"index:idx_user_score,priority:2;not null"`
+	Dismissed       bool    `gorm:"default:false;not null"`
+	UpdatedAt       time.Time
+}
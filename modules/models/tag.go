@@ -0,0 +1,37 @@
+package models
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+// Tag 话题，从视频标题中的 #话题 解析得到
+type Tag struct {
+	ID         uint   `gorm:"primaryKey"`
+	Name       string `gorm:"type:varchar(64);uniqueIndex;not null"`
+	VideoCount int    `gorm:"default:0;not null"` // 使用该话题的视频数，用于联想排序
+	CreatedAt  time.Time
+}
+
+// VideoTag 视频与话题的关联
+type VideoTag struct {
+	ID      uint `gorm:"primaryKey"`
+	VideoID uint `gorm:"index:idx_video_tag,unique;not null"`
+	TagID   uint `gorm:"index:idx_video_tag,unique;index:idx_tag;not null"`
+	Tag     Tag  `gorm:"foreignKey:TagID"`
+}
+
+// AfterCreate hook for the VideoTag model.
+func (vt *VideoTag) AfterCreate(tx *gorm.DB) (err error) {
+	// 话题的视频数 + 1
+	return tx.Model(&Tag{}).Where("id = ?", vt.TagID).
+		UpdateColumn("video_count", gorm.Expr("video_count + 1")).Error
+}
+
+// AfterDelete hook for the VideoTag model.
+func (vt *VideoTag) AfterDelete(tx *gorm.DB) (err error) {
+	// 话题的视频数 - 1
+	return tx.Model(&Tag{}).Where("id = ?", vt.TagID).
+		UpdateColumn("video_count", gorm.Expr(
+			"CASE WHEN video_count > 0 THEN video_count - 1 ELSE 0 END")).Error
+}
@@ -43,9 +43,10 @@ var Signatures = []string{
 // User 表示应用中的用户
 type User struct {
 	gorm.Model
-	Username string `gorm:"unique"`
-	Password string
-	Profile  UserProfile `gorm:"foreignKey:UserID"`
+	Username    string `gorm:"unique"`
+	Password    string
+	IsModerator bool        `gorm:"default:false;not null"` // 审核人员，可以处理审核队列
+	Profile     UserProfile `gorm:"foreignKey:UserID"`
 }
 
 // UserProfile 表示用户的额外信息
@@ -55,11 +56,13 @@ type UserProfile struct {
 	Avatar         string
 	Background     string
 	Signature      string
-	FollowCount    int `gorm:"default:0"` // 关注总数
-	FollowerCount  int `gorm:"default:0"` // 粉丝总数
-	TotalFavorited int `gorm:"default:0"` // 获赞数量
-	WorkCount      int `gorm:"default:0"` // 作品数
-	FavoriteCount  int `gorm:"default:0"` // 喜欢数
+	FollowCount    int  `gorm:"default:0"`     // 关注总数
+	FollowerCount  int  `gorm:"default:0"`     // 粉丝总数
+	TotalFavorited int  `gorm:"default:0"`     // 获赞数量
+	WorkCount      int  `gorm:"default:0"`     // 作品数
+	FavoriteCount  int  `gorm:"default:0"`     // 喜欢数
+	FriendCount    int  `gorm:"default:0"`     // 好友（互相关注）数
+	HistoryPaused  bool `gorm:"default:false"` // 是否暂停记录观看历史
 }
 
 func (u *User) AfterCreate(tx *gorm.DB) (err error) {
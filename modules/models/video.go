@@ -5,6 +5,14 @@ import (
 	"time"
 )
 
+// 视频的评论权限，由发布者设置
+const (
+	CommentPolicyEveryone  = 0 // 所有人可以评论
+	CommentPolicyFollowers = 1 // 只有关注了发布者的用户可以评论
+	CommentPolicyFriends   = 2 // 只有互相关注的用户可以评论
+	CommentPolicyOff       = 3 // 关闭评论
+)
+
 type Video struct {
 	gorm.Model
 	UserID        uint      `gorm:"index:idx_user_created" json:"user_id"`
@@ -15,6 +23,9 @@ type Video struct {
 	FavoriteCount uint      `gorm:"default:0;not null" json:"favorite_count"`
 	CommentCount  uint      `gorm:"default:0;not null" json:"comment_count"`
 	PublishTime   time.Time `gorm:"index:idx_publish_time;index:idx_user_created" json:"published_at"`
+	// 评论区设置，发布者本人始终可以评论
+	CommentPolicy   int  `gorm:"default:0;not null" json:"comment_policy"`
+	PinnedCommentID uint `gorm:"default:0;not null" json:"pinned_comment_id"`
 }
 
 // AfterCreate hook for the Video model.
@@ -37,5 +48,28 @@ func (video *Video) AfterDelete(tx *gorm.DB) (err error) {
 	if err != nil {
 		return err
 	}
-	return nil
+	// 删除标题和评论中的@，被@的用户不再看到这个视频，@的通知在下面和其它通知一起删除
+	if err = tx.Session(&gorm.Session{SkipHooks: true}).
+		Where("video_id = ?", video.ID).Delete(&Mention{}).Error; err != nil {
+		return err
+	}
+	// 从所有收藏夹中移除这个视频
+	if err = tx.Model(&Collection{}).
+		Where("id IN (?)", tx.Model(&CollectionVideo{}).Select("collection_id").Where("video_id = ?", video.ID)).
+		UpdateColumn("video_count", gorm.Expr(
+			"CASE WHEN video_count > 0 THEN video_count - 1 ELSE 0 END")).Error; err != nil {
+		return err
+	}
+	if err = tx.Session(&gorm.Session{SkipHooks: true}).
+		Where("video_id = ?", video.ID).Delete(&CollectionVideo{}).Error; err != nil {
+		return err
+	}
+	// 删除这个视频的通知
+	var notificationIds []uint
+	if err = tx.Model(&Notification{}).
+		Where("type IN ? AND target_id = ?", []int{NotifyLike, NotifyComment, NotifyMention}, video.ID).
+		Pluck("id", &notificationIds).Error; err != nil {
+		return err
+	}
+	return DeleteNotifications(tx, notificationIds)
 }
@@ -0,0 +1,277 @@
+package notification
+
+import (
+	"app/consts"
+	"app/modules/models"
+	"app/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"net/http"
+	"strconv"
+)
+
+// parseType 解析通知类型，空字符串或 0 表示全部类型
+func parseType(typeString string) (int, bool) {
+	if typeString == "" {
+		return 0, true
+	}
+	notifyType, err := strconv.Atoi(typeString)
+	if err != nil || notifyType < 0 || notifyType > models.NotifyMention {
+		return 0, false
+	}
+	return notifyType, true
+}
+
+// unreadCount 按类型统计用户的未读通知数
+func unreadCount(db *gorm.DB, userId uint) (utils.NotificationUnreadCount, error) {
+	var rows []struct {
+		Type  int
+		Count int64
+	}
+	var count utils.NotificationUnreadCount
+	if err := db.Model(&models.Notification{}).Select("type, COUNT(*) AS count").
+		Where("user_id = ? AND is_read = ?", userId, false).
+		Group("type").Scan(&rows).Error; err != nil {
+		return count, err
+	}
+	for _, row := range rows {
+		switch row.Type {
+		case models.NotifyLike:
+			count.Like = row.Count
+		case models.NotifyComment:
+			count.Comment = row.Count
+		case models.NotifyReply:
+			count.Reply = row.Count
+		case models.NotifyFollow:
+			count.Follow = row.Count
+		case models.NotifyMention:
+			count.Mention = row.Count
+		}
+		count.Total += row.Count
+	}
+	return count, nil
+}
+
+// List 分页列出当前用户的通知，按最近一次事件的时间倒序，type 不为空时只返回这一类通知。
+// cursor 为上一页返回的 next_cursor，格式为 "更新时间毫秒_ID"
+func List(c *gin.Context) {
+	// 验证 user_id
+	tokenString := c.DefaultQuery("token", "")
+	userId, err := utils.ValidateToken(tokenString)
+	if err != nil || userId <= 0 {
+		c.JSON(http.StatusBadRequest, utils.NotificationListResponse{
+			StatusCode: 1,
+			StatusMsg:  "Invalid user ID.",
+		})
+		return
+	}
+
+	// 验证通知类型
+	notifyType, ok := parseType(c.DefaultQuery("type", ""))
+	if !ok {
+		c.JSON(http.StatusBadRequest, utils.NotificationListResponse{
+			StatusCode: 1,
+			StatusMsg:  "Invalid type.",
+		})
+		return
+	}
+
+	db := c.MustGet("db").(*gorm.DB)
+	query := db.Where("user_id = ?", userId)
+	if notifyType != 0 {
+		query = query.Where("type = ?", notifyType)
+	}
+	if cursor := c.DefaultQuery("cursor", ""); cursor != "" {
+		cursorTime, cursorId, ok := utils.ParseTimeCursor(cursor)
+		if !ok {
+			c.JSON(http.StatusBadRequest, utils.NotificationListResponse{
+				StatusCode: 1,
+				StatusMsg:  "Invalid cursor.",
+			})
+			return
+		}
+		query = query.Where("updated_at <= ? AND (updated_at < ? OR id < ?)",
+			cursorTime, cursorTime, cursorId)
+	}
+
+	// 多取一条用来判断是否还有下一页
+	var notifications []models.Notification
+	if err := query.Order("updated_at desc").Order("id desc").
+		Limit(consts.MaxNotificationCount + 1).Find(&notifications).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.NotificationListResponse{
+			StatusCode: 1,
+			StatusMsg:  "Failed to fetch notifications.",
+		})
+		return
+	}
+	hasMore := len(notifications) > consts.MaxNotificationCount
+	if hasMore {
+		notifications = notifications[:consts.MaxNotificationCount]
+	}
+
+	// 用窗口函数一次查出每条通知最近的 MaxNotificationActors 个用户
+	var notificationIds []uint
+	for _, n := range notifications {
+		notificationIds = append(notificationIds, n.ID)
+	}
+	var actors []models.NotificationActor
+	if len(notificationIds) > 0 {
+		ranked := db.Model(&models.NotificationActor{}).
+			Select("notification_id, actor_id, created_at, "+
+				"ROW_NUMBER() OVER (PARTITION BY notification_id ORDER BY created_at DESC, id DESC) AS rn").
+			Where("notification_id IN ?", notificationIds)
+		db.Table("(?) AS ranked", ranked).Where("rn <= ?", consts.MaxNotificationActors).
+			Order("created_at desc").Find(&actors)
+	}
+	var actorIds []uint
+	actorsByNotification := make(map[uint][]uint)
+	for _, a := range actors {
+		actorIds = append(actorIds, a.ActorID)
+		actorsByNotification[a.NotificationID] = append(actorsByNotification[a.NotificationID], a.ActorID)
+	}
+
+	// 批量查询用户信息，以及当前用户关注了其中哪些用户
+	userById := make(map[uint]models.User)
+	var followedIdSet = make(map[uint]bool)
+	if len(actorIds) > 0 {
+		var users []models.User
+		db.Preload("Profile").Where("id IN ?", actorIds).Find(&users)
+		for _, u := range users {
+			userById[u.ID] = u
+		}
+		var followedIds []uint
+		db.Table("relations").
+			Where("from_user_id = ? AND to_user_id IN ?", userId, actorIds).
+			Pluck("to_user_id", &followedIds)
+		for _, id := range followedIds {
+			followedIdSet[id] = true
+		}
+	}
+
+	var notificationList []utils.NotificationResItem
+	for _, n := range notifications {
+		item := utils.NotificationResItem{
+			ID:         n.ID,
+			Type:       n.Type,
+			TargetID:   n.TargetID,
+			ActorCount: n.ActorCount,
+			IsRead:     n.IsRead,
+			UpdateDate: n.UpdatedAt,
+		}
+		for _, actorId := range actorsByNotification[n.ID] {
+			if u, ok := userById[actorId]; ok {
+				item.Actors = append(item.Actors, utils.BuildUserResponse(u, followedIdSet[actorId]))
+			}
+		}
+		notificationList = append(notificationList, item)
+	}
+
+	var nextCursor string
+	if hasMore {
+		last := notifications[len(notifications)-1]
+		nextCursor = utils.FormatTimeCursor(last.UpdatedAt, last.ID)
+	}
+
+	count, err := unreadCount(db, userId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.NotificationListResponse{
+			StatusCode: 1,
+			StatusMsg:  "Failed to count unread notifications.",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.NotificationListResponse{
+		StatusCode:       0,
+		StatusMsg:        "Success",
+		UnreadCount:      count,
+		NextCursor:       nextCursor,
+		HasMore:          hasMore,
+		NotificationList: notificationList,
+	})
+}
+
+// Unread 返回当前用户各类型的未读通知数
+func Unread(c *gin.Context) {
+	// 验证 user_id
+	tokenString := c.DefaultQuery("token", "")
+	userId, err := utils.ValidateToken(tokenString)
+	if err != nil || userId <= 0 {
+		c.JSON(http.StatusBadRequest, utils.NotificationUnreadResponse{
+			StatusCode: 1,
+			StatusMsg:  "Invalid user ID.",
+		})
+		return
+	}
+
+	db := c.MustGet("db").(*gorm.DB)
+	count, err := unreadCount(db, userId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.NotificationUnreadResponse{
+			StatusCode: 1,
+			StatusMsg:  "Failed to count unread notifications.",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.NotificationUnreadResponse{
+		StatusCode:  0,
+		StatusMsg:   "Success",
+		UnreadCount: count,
+	})
+}
+
+// Read 标记通知为已读。notification_id 不为空时只标记这一条，
+// 否则标记 type 类型的全部通知，type 也为空时标记全部通知
+func Read(c *gin.Context) {
+	// 验证 user_id
+	tokenString := c.DefaultQuery("token", "")
+	userId, err := utils.ValidateToken(tokenString)
+	if err != nil || userId <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status_code": 1,
+			"status_msg":  "Invalid user ID.",
+		})
+		return
+	}
+
+	db := c.MustGet("db").(*gorm.DB)
+	query := db.Model(&models.Notification{}).Where("user_id = ? AND is_read = ?", userId, false)
+	if notificationId := c.DefaultQuery("notification_id", ""); notificationId != "" {
+		id, err := strconv.Atoi(notificationId)
+		if err != nil || id < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"status_code": 1,
+				"status_msg":  "Invalid notification_id.",
+			})
+			return
+		}
+		query = query.Where("id = ?", id)
+	} else {
+		notifyType, ok := parseType(c.DefaultQuery("type", ""))
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"status_code": 1,
+				"status_msg":  "Invalid type.",
+			})
+			return
+		}
+		if notifyType != 0 {
+			query = query.Where("type = ?", notifyType)
+		}
+	}
+
+	// 标记已读不改变通知的排序
+	if err := query.UpdateColumn("is_read", true).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status_code": 1,
+			"status_msg":  "Failed to mark notifications as read.",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status_code": 0,
+		"status_msg":  "Success",
+	})
+}
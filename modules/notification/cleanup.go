@@ -0,0 +1,48 @@
+package notification
+
+import (
+	"app/consts"
+	"app/modules/models"
+	"gorm.io/gorm"
+	"log"
+	"time"
+)
+
+// Cleanup 删除最近一次事件早于 before 的通知，分批删除避免长时间锁表，返回删除的通知数
+func Cleanup(db *gorm.DB, before time.Time) (int, error) {
+	var total int
+	for {
+		var ids []uint
+		if err := db.Model(&models.Notification{}).Where("updated_at < ?", before).
+			Limit(consts.NotificationCleanupBatch).Pluck("id", &ids).Error; err != nil {
+			return total, err
+		}
+		if len(ids) == 0 {
+			return total, nil
+		}
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			return models.DeleteNotifications(tx, ids)
+		}); err != nil {
+			return total, err
+		}
+		total += len(ids)
+	}
+}
+
+// StartCleaner 启动后台任务，定期删除超过保留期限的通知
+func StartCleaner(db *gorm.DB, retention, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for ; true; <-ticker.C {
+			deleted, err := Cleanup(db, time.Now().Add(-retention))
+			if err != nil {
+				log.Printf("Failed to clean up notifications. Err: %s", err)
+				continue
+			}
+			if deleted > 0 {
+				log.Printf("Cleaned up %d notifications.", deleted)
+			}
+		}
+	}()
+}
@@ -0,0 +1,158 @@
+package notification
+
+import (
+	"app/config"
+	"app/modules/models"
+	"app/utils"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	utils.Setup()
+	postSetup()
+	code := m.Run()
+	utils.Teardown()
+	os.Exit(code)
+}
+
+var ListUrl = "/douyin/notification/list/"
+var ReadUrl = "/douyin/notification/read/"
+var db = utils.GetDb()
+var aliceId, bobId, carolId uint
+var videoId uint
+
+func postSetup() {
+	var ids []uint
+	for _, name := range []string{"alice_n", "bob_n", "carol_n"} {
+		user := models.User{Username: name, Password: name + "_pass"}
+		db.Create(&user)
+		ids = append(ids, user.ID)
+	}
+	aliceId, bobId, carolId = ids[0], ids[1], ids[2]
+
+	video := models.Video{UserID: aliceId, Title: "notification video", PublishTime: time.Now()}
+	db.Create(&video)
+	videoId = video.ID
+
+	config.Router.GET(ListUrl, List)
+	config.Router.POST(ReadUrl, Read)
+}
+
+func listNotifications(t *testing.T, token string, notifyType int) utils.NotificationListResponse {
+	values := url.Values{}
+	values.Add("token", token)
+	values.Add("type", strconv.Itoa(notifyType))
+	req, _ := http.NewRequest("GET", ListUrl+"?"+values.Encode(), nil)
+	response := httptest.NewRecorder()
+	config.Router.ServeHTTP(response, req)
+	assert.Equal(t, http.StatusOK, response.Code)
+
+	var resp utils.NotificationListResponse
+	if err := json.Unmarshal(response.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+// 测试点赞通知的聚合、撤回以及标记已读
+func TestLikeNotifications(t *testing.T) {
+	token, err := utils.GenerateToken(aliceId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 两个用户点赞聚合为一条通知，自己点赞不通知
+	for _, userId := range []uint{bobId, carolId, aliceId} {
+		db.Create(&models.Favorite{UserID: userId, VideoID: videoId})
+	}
+	resp := listNotifications(t, token, models.NotifyLike)
+	assert.Len(t, resp.NotificationList, 1)
+	assert.Equal(t, 2, resp.NotificationList[0].ActorCount)
+	assert.Equal(t, carolId, resp.NotificationList[0].Actors[0].ID)
+	assert.Equal(t, int64(1), resp.UnreadCount.Like)
+
+	// 取消点赞从未读的通知中撤回
+	db.Where("user_id = ? AND video_id = ?", carolId, videoId).
+		Delete(&models.Favorite{UserID: carolId, VideoID: videoId})
+	resp = listNotifications(t, token, models.NotifyLike)
+	assert.Equal(t, 1, resp.NotificationList[0].ActorCount)
+	assert.Equal(t, bobId, resp.NotificationList[0].Actors[0].ID)
+
+	// 标记已读后，新的点赞生成新的通知
+	values := url.Values{}
+	values.Add("token", token)
+	values.Add("type", strconv.Itoa(models.NotifyLike))
+	req, _ := http.NewRequest("POST", ReadUrl+"?"+values.Encode(), nil)
+	response := httptest.NewRecorder()
+	config.Router.ServeHTTP(response, req)
+	assert.Equal(t, http.StatusOK, response.Code)
+
+	db.Create(&models.Favorite{UserID: carolId, VideoID: videoId})
+	resp = listNotifications(t, token, models.NotifyLike)
+	assert.Len(t, resp.NotificationList, 2)
+	assert.False(t, resp.NotificationList[0].IsRead)
+	assert.True(t, resp.NotificationList[1].IsRead)
+	assert.Equal(t, int64(1), resp.UnreadCount.Like)
+}
+
+// 测试撤回不修改通知的更新时间，通知在列表中的顺序不变
+func TestRetractKeepsOrder(t *testing.T) {
+	token, err := utils.GenerateToken(aliceId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	older := models.Video{UserID: aliceId, Title: "retract older", PublishTime: time.Now()}
+	newer := models.Video{UserID: aliceId, Title: "retract newer", PublishTime: time.Now()}
+	db.Create(&older)
+	db.Create(&newer)
+
+	db.Create(&models.Favorite{UserID: bobId, VideoID: older.ID})
+	time.Sleep(10 * time.Millisecond)
+	db.Create(&models.Favorite{UserID: carolId, VideoID: older.ID})
+	time.Sleep(10 * time.Millisecond)
+	db.Create(&models.Favorite{UserID: bobId, VideoID: newer.ID})
+	var before models.Notification
+	db.Where("user_id = ? AND type = ? AND target_id = ?", aliceId, models.NotifyLike, older.ID).First(&before)
+	assert.Equal(t, 2, before.ActorCount)
+
+	time.Sleep(10 * time.Millisecond)
+	db.Where("user_id = ? AND video_id = ?", carolId, older.ID).
+		Delete(&models.Favorite{UserID: carolId, VideoID: older.ID})
+	var after models.Notification
+	db.First(&after, before.ID)
+	assert.Equal(t, 1, after.ActorCount)
+	assert.Equal(t, bobId, after.LatestActorID)
+	assert.True(t, before.UpdatedAt.Equal(after.UpdatedAt))
+
+	var targetIds []uint
+	for _, item := range listNotifications(t, token, models.NotifyLike).NotificationList {
+		if item.TargetID == older.ID || item.TargetID == newer.ID {
+			targetIds = append(targetIds, item.TargetID)
+		}
+	}
+	assert.Equal(t, []uint{newer.ID, older.ID}, targetIds)
+}
+
+// 测试超过保留期限的通知被清理
+func TestCleanup(t *testing.T) {
+	db.Create(&models.Relation{FromUserId: bobId, ToUserId: carolId})
+	var count int64
+	db.Model(&models.Notification{}).Where("user_id = ? AND type = ?", carolId, models.NotifyFollow).Count(&count)
+	assert.Equal(t, int64(1), count)
+
+	deleted, err := Cleanup(db, time.Now().Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Greater(t, deleted, 0)
+	db.Model(&models.Notification{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+	db.Model(&models.NotificationActor{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+}
@@ -0,0 +1,52 @@
+package policy
+
+import (
+	"app/modules/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"net/http"
+)
+
+// IsBlocked 查询两个用户之间是否有任意一方拉黑了另一方
+func IsBlocked(db *gorm.DB, userId, otherUserId uint) (bool, error) {
+	var count int64
+	err := db.Model(&models.Block{}).
+		Where("(user_id = ? AND blocked_user_id = ?) OR (user_id = ? AND blocked_user_id = ?)",
+			userId, otherUserId, otherUserId, userId).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// CheckInteraction 在关注、私信等接口中检查 userId 能否和 targetUserId 互动。
+// 任意一方拉黑了另一方时返回 403 和 msg，不告诉对方是否被拉黑
+func CheckInteraction(c *gin.Context, db *gorm.DB, userId, targetUserId uint, msg string) bool {
+	blocked, err := IsBlocked(db, userId, targetUserId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status_code": 1,
+			"status_msg":  "Failed to check block status.",
+		})
+		return false
+	}
+	if blocked {
+		c.JSON(http.StatusForbidden, gin.H{
+			"status_code": 1,
+			"status_msg":  msg,
+		})
+		return false
+	}
+	return true
+}
+
+// NotBlocked 作为 gorm 的 Scope 使用，过滤掉 column 对应的用户和 viewerId 之间有拉黑关系的记录。
+// column 需要带上表名（例如 videos.user_id），避免和子查询中的字段混淆。未登录时不做过滤
+func NotBlocked(column string, viewerId uint) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if viewerId == 0 {
+			return db
+		}
+		return db.Where("NOT EXISTS (SELECT 1 FROM blocks b WHERE "+
+			"(b.user_id = ? AND b.blocked_user_id = "+column+") OR "+
+			"(b.user_id = "+column+" AND b.blocked_user_id = ?))", viewerId, viewerId)
+	}
+}
@@ -0,0 +1,34 @@
+package policy
+
+import (
+	"app/modules/models"
+	"gorm.io/gorm"
+)
+
+// NotMuted 作为 gorm 的 Scope 使用，过滤掉 column 对应的用户被 viewerId 静音的记录。
+// column 需要带上表名（例如 videos.user_id）。未登录时不做过滤
+func NotMuted(column string, viewerId uint) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if viewerId == 0 {
+			return db
+		}
+		return db.Where("NOT EXISTS (SELECT 1 FROM mutes m WHERE m.user_id = ? AND m.muted_user_id = "+column+")",
+			viewerId)
+	}
+}
+
+// MutedSet 用一次查询找出 userIds 中有哪些用户被 viewerId 静音了
+func MutedSet(db *gorm.DB, viewerId uint, userIds []uint) map[uint]bool {
+	mutedSet := make(map[uint]bool)
+	if viewerId == 0 || len(userIds) == 0 {
+		return mutedSet
+	}
+	var mutedIds []uint
+	db.Model(&models.Mute{}).
+		Where("user_id = ? AND muted_user_id IN ?", viewerId, userIds).
+		Pluck("muted_user_id", &mutedIds)
+	for _, id := range mutedIds {
+		mutedSet[id] = true
+	}
+	return mutedSet
+}
@@ -0,0 +1,41 @@
+package policy
+
+import (
+	"app/modules/models"
+	"gorm.io/gorm"
+)
+
+// CanViewVideos 判断 viewerId 能否查看 ownerId 发布的视频。私密账号的视频只对本人和粉丝可见，viewerId 为 0 表示未登录
+func CanViewVideos(db *gorm.DB, viewerId, ownerId uint) (bool, error) {
+	if viewerId == ownerId {
+		return true, nil
+	}
+	var private int64
+	if err := db.Model(&models.PrivacySetting{}).
+		Where("user_id = ? AND is_private = ?", ownerId, true).
+		Count(&private).Error; err != nil {
+		return false, err
+	}
+	if private == 0 {
+		return true, nil
+	}
+	if viewerId == 0 {
+		return false, nil
+	}
+	var follows int64
+	err := db.Model(&models.Relation{}).
+		Where("from_user_id = ? AND to_user_id = ?", viewerId, ownerId).
+		Count(&follows).Error
+	return follows > 0, err
+}
+
+// VisibleVideos 作为 gorm 的 Scope 使用，过滤掉 viewerId 不能查看的私密账号的视频。
+// column 为视频发布者 ID 的字段，需要带上表名（例如 videos.user_id）
+func VisibleVideos(column string, viewerId uint) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("(NOT EXISTS (SELECT 1 FROM privacy_settings ps "+
+			"WHERE ps.user_id = "+column+" AND ps.is_private = ?) OR "+column+" = ? OR "+
+			"EXISTS (SELECT 1 FROM relations r WHERE r.from_user_id = ? AND r.to_user_id = "+column+"))",
+			true, viewerId, viewerId)
+	}
+}
@@ -0,0 +1,220 @@
+package privacy
+
+import (
+	"app/consts"
+	"app/modules/models"
+	"app/utils"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"net/http"
+	"strconv"
+)
+
+// 受隐私设置保护的列表
+const (
+	FavoriteList  = "favorite_list"
+	FollowingList = "following_list"
+	FollowerList  = "follower_list"
+)
+
+// GetSetting 查询用户的隐私设置，没有设置过时返回全部公开
+func GetSetting(db *gorm.DB, userId uint) (models.PrivacySetting, error) {
+	var setting models.PrivacySetting
+	err := db.Where("user_id = ?", userId).First(&setting).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.PrivacySetting{UserID: userId}, nil
+	}
+	return setting, err
+}
+
+// CanView 判断 viewerId 能否查看 ownerId 的列表，viewerId 为 0 表示未登录
+func CanView(db *gorm.DB, viewerId, ownerId uint, list string) (bool, error) {
+	if viewerId == ownerId {
+		return true, nil
+	}
+	setting, err := GetSetting(db, ownerId)
+	if err != nil {
+		return false, err
+	}
+	var level int
+	switch list {
+	case FavoriteList:
+		level = setting.FavoriteList
+	case FollowingList:
+		level = setting.FollowingList
+	case FollowerList:
+		level = setting.FollowerList
+	}
+	switch level {
+	case models.PrivacyPublic:
+		return true, nil
+	case models.PrivacyFriends:
+		if viewerId == 0 {
+			return false, nil
+		}
+		return models.IsFriend(db, viewerId, ownerId)
+	default:
+		return false, nil
+	}
+}
+
+// CheckList 在列表接口中检查当前用户能否查看 ownerId 的列表。
+// 不能查看时返回 403 和 StatusCodePrivate，客户端据此显示 "该列表为私密"
+func CheckList(c *gin.Context, db *gorm.DB, ownerId uint, list string) bool {
+	tokenString := c.DefaultQuery("token", "")
+	viewerId, _ := utils.ValidateToken(tokenString)
+	ok, err := CanView(db, viewerId, ownerId, list)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status_code": 1,
+			"status_msg":  "Failed to check privacy settings.",
+		})
+		return false
+	}
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{
+			"status_code": consts.StatusCodePrivate,
+			"status_msg":  "This list is private.",
+		})
+		return false
+	}
+	return true
+}
+
+// Get 查询当前用户的隐私设置
+func Get(c *gin.Context) {
+	tokenString := c.DefaultQuery("token", "")
+	userId, err := utils.ValidateToken(tokenString)
+	if err != nil || userId <= 0 {
+		c.JSON(http.StatusBadRequest, utils.PrivacyResponse{
+			StatusCode: 1,
+			StatusMsg:  "Invalid user ID.",
+		})
+		return
+	}
+
+	db := c.MustGet("db").(*gorm.DB)
+	setting, err := GetSetting(db, userId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.PrivacyResponse{
+			StatusCode: 1,
+			StatusMsg:  "Failed to fetch privacy settings.",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.PrivacyResponse{
+		StatusCode:    0,
+		StatusMsg:     "Success",
+		FavoriteList:  setting.FavoriteList,
+		FollowingList: setting.FollowingList,
+		FollowerList:  setting.FollowerList,
+		IsPrivate:     setting.IsPrivate,
+	})
+}
+
+// approveRequests 私密账号改为公开后同意所有待处理的关注申请。
+// 逐条创建 Relation，由 Relation 的 hook 更新关注数、粉丝数和通知
+func approveRequests(tx *gorm.DB, userId uint) error {
+	var requests []models.FollowRequest
+	if err := tx.Where("to_user_id = ?", userId).Find(&requests).Error; err != nil {
+		return err
+	}
+	for _, request := range requests {
+		if err := tx.Delete(&request).Error; err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&models.Relation{FromUserId: request.FromUserID, ToUserId: userId}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Update 修改当前用户的隐私设置，favorite_list / following_list / follower_list
+// 的取值为 0 公开，1 好友可见，2 仅自己可见，is_private 为 1 时设为私密账号，没有传的设置保持不变。
+// 私密账号改为公开时，待处理的关注申请全部同意
+func Update(c *gin.Context) {
+	tokenString := c.DefaultQuery("token", "")
+	userId, err := utils.ValidateToken(tokenString)
+	if err != nil || userId <= 0 {
+		c.JSON(http.StatusBadRequest, utils.PrivacyResponse{
+			StatusCode: 1,
+			StatusMsg:  "Invalid user ID.",
+		})
+		return
+	}
+
+	db := c.MustGet("db").(*gorm.DB)
+	setting, err := GetSetting(db, userId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.PrivacyResponse{
+			StatusCode: 1,
+			StatusMsg:  "Failed to fetch privacy settings.",
+		})
+		return
+	}
+
+	for list, level := range map[string]*int{
+		FavoriteList:  &setting.FavoriteList,
+		FollowingList: &setting.FollowingList,
+		FollowerList:  &setting.FollowerList,
+	} {
+		value := c.DefaultQuery(list, "")
+		if value == "" {
+			continue
+		}
+		levelInt, err := strconv.Atoi(value)
+		if err != nil || levelInt < models.PrivacyPublic || levelInt > models.PrivacyOnlyMe {
+			c.JSON(http.StatusBadRequest, utils.PrivacyResponse{
+				StatusCode: 1,
+				StatusMsg:  "Invalid " + list + ".",
+			})
+			return
+		}
+		*level = levelInt
+	}
+
+	wasPrivate := setting.IsPrivate
+	if value := c.DefaultQuery("is_private", ""); value != "" {
+		if value != "0" && value != "1" {
+			c.JSON(http.StatusBadRequest, utils.PrivacyResponse{
+				StatusCode: 1,
+				StatusMsg:  "Invalid is_private.",
+			})
+			return
+		}
+		setting.IsPrivate = value == "1"
+	}
+
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"favorite_list", "following_list", "follower_list", "is_private", "updated_at"}),
+		}).Create(&setting).Error; err != nil {
+			return err
+		}
+		if wasPrivate && !setting.IsPrivate {
+			return approveRequests(tx, userId)
+		}
+		return nil
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, utils.PrivacyResponse{
+			StatusCode: 1,
+			StatusMsg:  "Failed to update privacy settings.",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.PrivacyResponse{
+		StatusCode:    0,
+		StatusMsg:     "Success",
+		FavoriteList:  setting.FavoriteList,
+		FollowingList: setting.FollowingList,
+		FollowerList:  setting.FollowerList,
+		IsPrivate:     setting.IsPrivate,
+	})
+}
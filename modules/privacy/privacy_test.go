@@ -0,0 +1,113 @@
+package privacy
+
+import (
+	"app/config"
+	"app/modules/models"
+	"app/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strconv"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	utils.Setup()
+	postSetup()
+	code := m.Run()
+	utils.Teardown()
+	os.Exit(code)
+}
+
+var UpdateUrl = "/douyin/privacy/action/"
+var ListUrl = "/douyin/privacy/test/list/"
+var db = utils.GetDb()
+var ownerId, friendId, strangerId uint
+
+func postSetup() {
+	var ids []uint
+	for _, name := range []string{"owner_p", "friend_p", "stranger_p"} {
+		user := models.User{Username: name, Password: name + "_pass"}
+		db.Create(&user)
+		ids = append(ids, user.ID)
+	}
+	ownerId, friendId, strangerId = ids[0], ids[1], ids[2]
+	db.Create(&models.Relation{FromUserId: ownerId, ToUserId: friendId})
+	db.Create(&models.Relation{FromUserId: friendId, ToUserId: ownerId})
+	db.Create(&models.Relation{FromUserId: strangerId, ToUserId: ownerId})
+
+	config.Router.POST(UpdateUrl, Update)
+	// 模拟受保护的列表接口
+	config.Router.GET(ListUrl, func(c *gin.Context) {
+		if !CheckList(c, c.MustGet("db").(*gorm.DB), ownerId, FollowerList) {
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"status_code": 0})
+	})
+}
+
+func request(method, path string, values url.Values) int {
+	req, _ := http.NewRequest(method, path+"?"+values.Encode(), nil)
+	response := httptest.NewRecorder()
+	config.Router.ServeHTTP(response, req)
+	return response.Code
+}
+
+// 测试不同隐私级别下本人、好友和其他用户能否查看粉丝列表
+func TestCheckList(t *testing.T) {
+	ownerToken, _ := utils.GenerateToken(ownerId)
+	friendToken, _ := utils.GenerateToken(friendId)
+	strangerToken, _ := utils.GenerateToken(strangerId)
+
+	for _, step := range []struct {
+		level    int
+		expected []int
+	}{
+		{models.PrivacyPublic, []int{http.StatusOK, http.StatusOK, http.StatusOK}},
+		{models.PrivacyFriends, []int{http.StatusOK, http.StatusOK, http.StatusForbidden}},
+		{models.PrivacyOnlyMe, []int{http.StatusOK, http.StatusForbidden, http.StatusForbidden}},
+	} {
+		code := request("POST", UpdateUrl, url.Values{
+			"token": {ownerToken}, FollowerList: {strconv.Itoa(step.level)},
+		})
+		assert.Equal(t, http.StatusOK, code)
+		for i, token := range []string{ownerToken, friendToken, strangerToken} {
+			assert.Equal(t, step.expected[i], request("GET", ListUrl, url.Values{"token": {token}}))
+		}
+	}
+
+	// 无效的隐私级别
+	code := request("POST", UpdateUrl, url.Values{"token": {ownerToken}, FollowerList: {"3"}})
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+// 测试私密账号改为公开后，待处理的关注申请自动同意
+func TestApproveRequestsWhenPublic(t *testing.T) {
+	user := models.User{Username: "private_p", Password: "private_p_pass"}
+	db.Create(&user)
+	token, _ := utils.GenerateToken(user.ID)
+	assert.Equal(t, http.StatusOK, request("POST", UpdateUrl, url.Values{"token": {token}, "is_private": {"1"}}))
+	db.Create(&models.FollowRequest{FromUserID: friendId, ToUserID: user.ID})
+	db.Create(&models.FollowRequest{FromUserID: strangerId, ToUserID: user.ID})
+
+	// 修改其他设置时申请保持不变
+	assert.Equal(t, http.StatusOK, request("POST", UpdateUrl, url.Values{"token": {token}, FavoriteList: {"1"}}))
+	var requests int64
+	db.Model(&models.FollowRequest{}).Where("to_user_id = ?", user.ID).Count(&requests)
+	assert.Equal(t, int64(2), requests)
+
+	assert.Equal(t, http.StatusOK, request("POST", UpdateUrl, url.Values{"token": {token}, "is_private": {"0"}}))
+	db.Model(&models.FollowRequest{}).Where("to_user_id = ?", user.ID).Count(&requests)
+	assert.Equal(t, int64(0), requests)
+	var relations int64
+	db.Model(&models.Relation{}).Where("to_user_id = ? AND from_user_id IN ?", user.ID, []uint{friendId, strangerId}).
+		Count(&relations)
+	assert.Equal(t, int64(2), relations)
+	var profile models.UserProfile
+	db.Where("user_id = ?", user.ID).First(&profile)
+	assert.Equal(t, 2, profile.FollowerCount)
+}
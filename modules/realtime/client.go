@@ -0,0 +1,77 @@
+package realtime
+
+import (
+	"app/consts"
+	"github.com/gorilla/websocket"
+	"time"
+)
+
+// Client 用户的一个 WebSocket 连接。Hub 只向 send 队列中放入事件，
+// 由 writePump 写入连接，慢的客户端不会阻塞其它用户的推送
+type Client struct {
+	UserID uint
+	conn   *websocket.Conn
+	send   chan []byte
+}
+
+func NewClient(userId uint, conn *websocket.Conn) *Client {
+	return &Client{
+		UserID: userId,
+		conn:   conn,
+		send:   make(chan []byte, consts.WsSendBuffer),
+	}
+}
+
+// Serve 在 hub 中注册连接并启动读写循环，阻塞直到连接断开
+func (c *Client) Serve(hub Hub) {
+	hub.Register(c)
+	go c.writePump()
+	c.readPump(hub)
+}
+
+// readPump 读取客户端的消息以处理 pong 和关闭帧，客户端发来的其它内容直接丢弃。
+// 超过 WsPongWait 没有收到 pong 时认为连接已经断开
+func (c *Client) readPump(hub Hub) {
+	defer func() {
+		hub.Unregister(c)
+		c.conn.Close()
+	}()
+	c.conn.SetReadLimit(consts.WsMaxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(consts.WsPongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(consts.WsPongWait))
+	})
+	for {
+		if _, _, err := c.conn.ReadMessage(); err != nil {
+			return
+		}
+	}
+}
+
+// writePump 将发送队列中的事件写入连接，并定期发送 ping 作为心跳。
+// 发送队列被 Hub 关闭时发送关闭帧并断开连接
+func (c *Client) writePump() {
+	ticker := time.NewTicker(consts.WsPingInterval)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+	for {
+		select {
+		case payload, ok := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(consts.WsWriteWait))
+			if !ok {
+				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				return
+			}
+			if err := c.conn.WriteMessage(websocket.TextMessage, payload); err != nil {
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(consts.WsWriteWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package realtime
+
+import (
+	"encoding/json"
+	"log"
+	"sync"
+)
+
+// 推送给客户端的事件类型
+const (
+	EventMessage = "message" // 新消息，Data 为 utils.MessageResItem
+	EventRead    = "read"    // 已读回执，Data 为 utils.ReadReceipt
+	EventUpdate  = "update"  // 消息被撤回、编辑或删除，Data 为 utils.MessageResItem
+)
+
+// Event 推送给客户端的事件，序列化为 JSON 后通过 WebSocket 发送
+type Event struct {
+	Type string      `json:"type"`
+	Data interface{} `json:"data"`
+}
+
+// Hub 维护用户的 WebSocket 连接并推送事件，同一个用户可以有多个设备同时在线。
+// LocalHub 只能推送给连接在本实例上的用户。多实例部署时可以实现一个基于消息队列的 Hub：
+// Publish 将事件发布到队列，每个实例订阅队列，收到事件后调用本实例 LocalHub 的 Publish 投递
+type Hub interface {
+	// Register 添加本实例上的一个连接
+	Register(c *Client)
+	// Unregister 移除本实例上的一个连接，并关闭它的发送队列
+	Unregister(c *Client)
+	// Publish 向用户所有在线的连接推送事件
+	Publish(userId uint, event Event) error
+}
+
+// DefaultHub 全局使用的 Hub
+var DefaultHub Hub = NewLocalHub()
+
+// SetHub 替换全局使用的 Hub
+func SetHub(h Hub) {
+	DefaultHub = h
+}
+
+// Publish 通过 DefaultHub 向多个用户推送事件，推送失败只记录日志，不影响请求
+func Publish(event Event, userIds ...uint) {
+	for _, userId := range userIds {
+		if err := DefaultHub.Publish(userId, event); err != nil {
+			log.Printf("Failed to publish %s event to user %d. Err: %s", event.Type, userId, err)
+		}
+	}
+}
+
+// LocalHub 在内存中按用户ID保存连接集合
+type LocalHub struct {
+	mu      sync.RWMutex
+	clients map[uint]map[*Client]struct{}
+}
+
+func NewLocalHub() *LocalHub {
+	return &LocalHub{clients: make(map[uint]map[*Client]struct{})}
+}
+
+func (h *LocalHub) Register(c *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	set, ok := h.clients[c.UserID]
+	if !ok {
+		set = make(map[*Client]struct{})
+		h.clients[c.UserID] = set
+	}
+	set[c] = struct{}{}
+}
+
+func (h *LocalHub) Unregister(c *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	set, ok := h.clients[c.UserID]
+	if !ok {
+		return
+	}
+	if _, ok := set[c]; !ok { // 已经移除过了
+		return
+	}
+	delete(set, c)
+	if len(set) == 0 {
+		delete(h.clients, c.UserID)
+	}
+	close(c.send)
+}
+
+// Publish 向用户的每个连接的发送队列放入事件，不会阻塞。
+// 发送队列已满的连接说明客户端消费太慢，直接断开，客户端重连后通过聊天记录接口补齐消息
+func (h *LocalHub) Publish(userId uint, event Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	var slow []*Client
+	h.mu.RLock()
+	for c := range h.clients[userId] {
+		select {
+		case c.send <- payload:
+		default:
+			slow = append(slow, c)
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, c := range slow {
+		log.Printf("Dropping slow websocket client of user %d.", c.UserID)
+		h.Unregister(c)
+	}
+	return nil
+}
+
+// Online 返回用户在本实例上的连接数
+func (h *LocalHub) Online(userId uint) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.clients[userId])
+}
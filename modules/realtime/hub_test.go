@@ -0,0 +1,51 @@
+package realtime
+
+import (
+	"app/consts"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// 同一个用户的多个连接都能收到事件，其它用户收不到
+func TestPublish(t *testing.T) {
+	hub := NewLocalHub()
+	phone, tablet, other := NewClient(1, nil), NewClient(1, nil), NewClient(2, nil)
+	for _, c := range []*Client{phone, tablet, other} {
+		hub.Register(c)
+	}
+	assert.Equal(t, 2, hub.Online(1))
+
+	assert.NoError(t, hub.Publish(1, Event{Type: EventMessage, Data: "hello"}))
+	for _, c := range []*Client{phone, tablet} {
+		var event Event
+		assert.NoError(t, json.Unmarshal(<-c.send, &event))
+		assert.Equal(t, EventMessage, event.Type)
+		assert.Equal(t, "hello", event.Data)
+	}
+	assert.Len(t, other.send, 0)
+
+	// 重复移除不会重复关闭发送队列
+	hub.Unregister(phone)
+	hub.Unregister(phone)
+	_, ok := <-phone.send
+	assert.False(t, ok)
+	assert.Equal(t, 1, hub.Online(1))
+}
+
+// 发送队列满了的连接会被断开，不影响其它连接
+func TestPublishDropsSlowClient(t *testing.T) {
+	hub := NewLocalHub()
+	slow, fast := NewClient(1, nil), NewClient(1, nil)
+	hub.Register(slow)
+	hub.Register(fast)
+	for i := 0; i < consts.WsSendBuffer; i++ {
+		slow.send <- []byte("{}")
+	}
+
+	assert.NoError(t, hub.Publish(1, Event{Type: EventMessage}))
+	assert.Equal(t, 1, hub.Online(1))
+	assert.Len(t, fast.send, 1)
+	for range slow.send { // 读完剩余的事件后队列已关闭
+	}
+}
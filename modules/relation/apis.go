@@ -1,7 +1,10 @@
 package relation
 
 import (
+	"app/consts"
 	"app/modules/models"
+	"app/modules/policy"
+	"app/modules/privacy"
 	"app/utils"
 	"errors"
 	"github.com/gin-gonic/gin"
@@ -61,6 +64,23 @@ func Action(c *gin.Context) {
 	// 执行关注/取关操作
 	switch actionType {
 	case "1": // 关注
+		// 任意一方拉黑了另一方时不能关注
+		if !policy.CheckInteraction(c, db, fromUserId, toUser.ID, "You can't follow this user.") {
+			return
+		}
+		// 关注私密账号时只创建关注申请，对方同意后才真正关注
+		setting, err := privacy.GetSetting(db, toUser.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"status_code": 1,
+				"status_msg":  "Failed to follow.",
+			})
+			return
+		}
+		if setting.IsPrivate {
+			requestFollow(c, db, fromUserId, toUser.ID)
+			return
+		}
 		relation := models.Relation{
 			FromUserId: fromUserId,
 			ToUserId:   uint(toUserIdInt),
@@ -89,6 +109,16 @@ func Action(c *gin.Context) {
 		tx := db.Where("from_user_id = ? and to_user_id = ?", fromUserId, toUserIdString).
 			Delete(&relationToDelete)
 		if tx.RowsAffected == 0 { // 删除了0条记录，说明这条关注关系不存在
+			// 还没有被同意的关注申请直接撤回
+			canceled := db.Where("from_user_id = ? AND to_user_id = ?", fromUserId, toUser.ID).
+				Delete(&models.FollowRequest{})
+			if canceled.Error == nil && canceled.RowsAffected > 0 {
+				c.JSON(http.StatusOK, gin.H{
+					"status_code": 0,
+					"status_msg":  "Follow request canceled.",
+				})
+				return
+			}
 			c.JSON(http.StatusNotFound, gin.H{
 				"status_code": 1,
 				"status_msg":  "You haven't followed this user.",
@@ -117,206 +147,242 @@ func Action(c *gin.Context) {
 	return
 }
 
-// GetFollowings 查询关注列表
-func GetFollowings(c *gin.Context) {
-	// 获取 user_id 参数
-	userIdString := c.DefaultQuery("user_id", "")
-	// 验证 user_id
-	userIdInt, err := strconv.Atoi(userIdString)
-	if err != nil || userIdInt <= 0 {
-		c.JSON(http.StatusBadRequest, utils.CommentResponse{
+// pageRelations 按关注时间倒序分页查询关注关系，并 Preload 关系中另一方的用户信息。
+// 游标为上一页最后一条关注关系的 "关注时间毫秒_ID"，走 idx_from_created / idx_to_created 索引。
+// 出错时直接返回错误响应，ok 为 false
+func pageRelations(c *gin.Context, query *gorm.DB, preload, failMsg string) (
+	relations []models.Relation, nextCursor string, hasMore bool, ok bool) {
+	// 验证每页数量
+	count, err := strconv.Atoi(c.DefaultQuery("count", strconv.Itoa(consts.DefaultRelationCount)))
+	if err != nil || count < 1 || count > consts.MaxRelationCount {
+		c.JSON(http.StatusBadRequest, utils.UserListResponse{
 			StatusCode: 1,
-			StatusMsg:  "Invalid target user ID.",
+			StatusMsg:  "Invalid count.",
 		})
 		return
 	}
 
-	// 查找用户的关注列表
-	var relationships []models.Relation
-	db := c.MustGet("db").(*gorm.DB)
-	if err := db.Preload("ToUser").Preload("ToUser.Profile").
-		Where("from_user_id = ?", userIdString).
-		Find(&relationships).Error; err != nil { // 如果查询失败
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status_code": 1,
-			"status_msg":  "Failed to fetch followings.",
-		})
-		return
+	if cursor := c.DefaultQuery("cursor", ""); cursor != "" {
+		cursorTime, cursorId, valid := utils.ParseTimeCursor(cursor)
+		if !valid {
+			c.JSON(http.StatusBadRequest, utils.UserListResponse{
+				StatusCode: 1,
+				StatusMsg:  "Invalid cursor.",
+			})
+			return
+		}
+		query = query.Where("relations.created_at <= ? AND (relations.created_at < ? OR relations.id < ?)",
+			cursorTime, cursorTime, cursorId)
 	}
 
-	// 生成 user_list
-	var userList []utils.UserResponse
-	for _, relation := range relationships {
-		userList = append(userList, utils.UserResponse{
-			ID:             relation.ToUser.ID,
-			Name:           relation.ToUser.Username,
-			FollowCount:    relation.ToUser.Profile.FollowCount,
-			FollowerCount:  relation.ToUser.Profile.FollowerCount,
-			IsFollow:       true,
-			Avatar:         relation.ToUser.Profile.Avatar,
-			Background:     relation.ToUser.Profile.Background,
-			Signature:      relation.ToUser.Profile.Signature,
-			TotalFavorited: relation.ToUser.Profile.TotalFavorited,
-			WorkCount:      relation.ToUser.Profile.WorkCount,
-			FavoriteCount:  relation.ToUser.Profile.FavoriteCount,
+	// 多取一条用来判断是否还有下一页，只为当前页 Preload 用户信息
+	if err := query.Preload(preload).Preload(preload + ".Profile").
+		Order("relations.created_at desc").Order("relations.id desc").
+		Limit(count + 1).Find(&relations).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.UserListResponse{
+			StatusCode: 1,
+			StatusMsg:  failMsg,
 		})
+		return
+	}
+	hasMore = len(relations) > count
+	if hasMore {
+		relations = relations[:count]
 	}
+	if len(relations) > 0 {
+		last := relations[len(relations)-1]
+		nextCursor = utils.FormatTimeCursor(last.CreatedAt, last.ID)
+	}
+	return relations, nextCursor, hasMore, true
+}
 
-	c.JSON(http.StatusOK, gin.H{
-		"status_code": 1,
-		"status_msg":  "Success",
-		"user_list":   userList,
-	})
+// userRecord 黑名单、静音列表和关注申请中的一条记录，UserID 为列表中显示的用户
+type userRecord struct {
+	ID        uint
+	UserID    uint
+	CreatedAt time.Time
 }
 
-// GetFollowers 查询粉丝列表
-func GetFollowers(c *gin.Context) {
-	// 获取 user_id 参数
-	userIdString := c.DefaultQuery("user_id", "")
-	// 验证 user_id
-	userIdInt, err := strconv.Atoi(userIdString)
-	if err != nil || userIdInt <= 0 {
-		c.JSON(http.StatusBadRequest, utils.CommentResponse{
+// pageUserRecords 按创建时间倒序分页查询黑名单、静音列表等记录，query 需要指定 Model 和查询条件，
+// userColumn 为列表中显示的用户ID的字段。游标为上一页最后一条记录的 "创建时间毫秒_ID"。
+// 返回按顺序排列的用户ID，出错时直接返回错误响应，ok 为 false
+func pageUserRecords(c *gin.Context, query *gorm.DB, userColumn, failMsg string) (
+	userIds []uint, nextCursor string, hasMore bool, ok bool) {
+	// 验证每页数量
+	count, err := strconv.Atoi(c.DefaultQuery("count", strconv.Itoa(consts.DefaultRelationCount)))
+	if err != nil || count < 1 || count > consts.MaxRelationCount {
+		c.JSON(http.StatusBadRequest, utils.UserListResponse{
 			StatusCode: 1,
-			StatusMsg:  "Invalid target user ID.",
+			StatusMsg:  "Invalid count.",
 		})
 		return
 	}
 
-	// 查找用户的粉丝列表
-	var relationships []models.Relation
-	db := c.MustGet("db").(*gorm.DB)
-	if err := db.Preload("FromUser").Preload("FromUser.Profile").
-		Where("to_user_id = ?", userIdString).
-		Find(&relationships).Error; err != nil { // 如果查询失败
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status_code": 1,
-			"status_msg":  "Failed to fetch followers.",
+	if cursor := c.DefaultQuery("cursor", ""); cursor != "" {
+		cursorTime, cursorId, valid := utils.ParseTimeCursor(cursor)
+		if !valid {
+			c.JSON(http.StatusBadRequest, utils.UserListResponse{
+				StatusCode: 1,
+				StatusMsg:  "Invalid cursor.",
+			})
+			return
+		}
+		query = query.Where("created_at <= ? AND (created_at < ? OR id < ?)",
+			cursorTime, cursorTime, cursorId)
+	}
+
+	// 多取一条用来判断是否还有下一页
+	var records []userRecord
+	if err := query.Select("id, " + userColumn + " AS user_id, created_at").
+		Order("created_at desc").Order("id desc").
+		Limit(count + 1).Scan(&records).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.UserListResponse{
+			StatusCode: 1,
+			StatusMsg:  failMsg,
 		})
 		return
 	}
+	hasMore = len(records) > count
+	if hasMore {
+		records = records[:count]
+	}
+	if len(records) > 0 {
+		last := records[len(records)-1]
+		nextCursor = utils.FormatTimeCursor(last.CreatedAt, last.ID)
+	}
+	for _, r := range records {
+		userIds = append(userIds, r.UserID)
+	}
+	return userIds, nextCursor, hasMore, true
+}
 
-	// 查询当前用户关注了哪些他的粉丝
-	var followerIds []uint // 先将粉丝ID放进一个列表，用来传给数据库查询的 IN 操作
-	for _, relation := range relationships {
-		followerIds = append(followerIds, relation.FromUserId)
+// loadUsers 批量查询用户信息，按 userIds 的顺序返回，不存在的用户会被跳过
+func loadUsers(db *gorm.DB, userIds []uint) []models.User {
+	userById := make(map[uint]models.User)
+	if len(userIds) > 0 {
+		var users []models.User
+		db.Preload("Profile").Where("id IN ?", userIds).Find(&users)
+		for _, u := range users {
+			userById[u.ID] = u
+		}
+	}
+	var users []models.User
+	for _, id := range userIds {
+		if u, ok := userById[id]; ok {
+			users = append(users, u)
+		}
 	}
-	// userFollowedFansIds 将查询结果放入这个列表
-	var userFollowedFansIds []uint
+	return users
+}
+
+// followedSet 用一次查询找出 userIds 中有哪些用户被当前用户关注，未登录时为空
+func followedSet(db *gorm.DB, currentUserId uint, userIds []uint) map[uint]bool {
+	var followedIdSet = make(map[uint]bool)
+	if currentUserId == 0 || len(userIds) == 0 {
+		return followedIdSet
+	}
+	var followedIds []uint
 	db.Table("relations").
-		Where("from_user_id = ? AND to_user_id IN (?)", userIdString, followerIds).
-		Pluck("to_user_id", &userFollowedFansIds)
-	// 将 userFollowedFansIds 放入 userFollowedFansSet 哈希表以便在 O(1) 时间内查询
-	var userFollowedFansSet = make(map[uint]bool)
-	for _, id := range userFollowedFansIds {
-		userFollowedFansSet[id] = true
+		Where("from_user_id = ? AND to_user_id IN ?", currentUserId, userIds).
+		Pluck("to_user_id", &followedIds)
+	for _, id := range followedIds {
+		followedIdSet[id] = true
 	}
+	return followedIdSet
+}
 
-	// 生成 user_list
-	var userList []utils.UserResponse
-	for _, relation := range relationships {
-		_, isFollowed := userFollowedFansSet[relation.FromUserId]
-		userList = append(userList, utils.UserResponse{
-			ID:             relation.FromUser.ID,
-			Name:           relation.FromUser.Username,
-			FollowCount:    relation.FromUser.Profile.FollowCount,
-			FollowerCount:  relation.FromUser.Profile.FollowerCount,
-			IsFollow:       isFollowed,
-			Avatar:         relation.FromUser.Profile.Avatar,
-			Background:     relation.FromUser.Profile.Background,
-			Signature:      relation.FromUser.Profile.Signature,
-			TotalFavorited: relation.FromUser.Profile.TotalFavorited,
-			WorkCount:      relation.FromUser.Profile.WorkCount,
-			FavoriteCount:  relation.FromUser.Profile.FavoriteCount,
-		})
+// buildUserList 生成 user_list，is_follow 表示当前用户是否关注了列表中的用户
+func buildUserList(c *gin.Context, db *gorm.DB, users []models.User) []utils.UserResponse {
+	currentUserId, _ := utils.ValidateToken(c.DefaultQuery("token", ""))
+	var userIds []uint
+	for _, u := range users {
+		userIds = append(userIds, u.ID)
 	}
+	followedIdSet := followedSet(db, currentUserId, userIds)
 
-	c.JSON(http.StatusOK, gin.H{
-		"status_code": 0,
-		"status_msg":  "Success",
-		"user_list":   userList,
-	})
+	var userList []utils.UserResponse
+	for _, u := range users {
+		userList = append(userList, utils.BuildUserResponse(u, followedIdSet[u.ID]))
+	}
+	return userList
 }
 
-func GetFriends(c *gin.Context) {
+// GetFollowings 查询关注列表
+func GetFollowings(c *gin.Context) {
 	// 获取 user_id 参数
 	userIdString := c.DefaultQuery("user_id", "")
 	// 验证 user_id
 	userIdInt, err := strconv.Atoi(userIdString)
 	if err != nil || userIdInt <= 0 {
-		c.JSON(http.StatusBadRequest, utils.CommentResponse{
+		c.JSON(http.StatusBadRequest, utils.UserListResponse{
 			StatusCode: 1,
 			StatusMsg:  "Invalid target user ID.",
 		})
 		return
 	}
 
+	// 验证隐私设置
 	db := c.MustGet("db").(*gorm.DB)
-
-	// 获取 user_id 的关注列表
-	var followingIds []uint
-	if err := db.Table("relations").Select("to_user_id").
-		Where("from_user_id = ?", userIdString).
-		Pluck("to_user_id", &followingIds).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, utils.CommentResponse{
-			StatusCode: 1,
-			StatusMsg:  "Failed to fetch data.",
-		})
+	if !privacy.CheckList(c, db, uint(userIdInt), privacy.FollowingList) {
 		return
 	}
 
-	// 如果用户的关注列表为空，那么他肯定没有好友
-	if len(followingIds) == 0 {
-		c.JSON(http.StatusOK, gin.H{
-			"status_code": 0,
-			"status_msg":  "Success",
-			"user_list":   followingIds,
-		})
+	// 分页查找用户的关注列表
+	relationships, nextCursor, hasMore, ok := pageRelations(c,
+		db.Where("from_user_id = ?", userIdInt), "ToUser", "Failed to fetch followings.")
+	if !ok {
 		return
 	}
+	var users []models.User
+	for _, relation := range relationships {
+		users = append(users, relation.ToUser)
+	}
 
-	// 查询 followingIds 中有哪些人关注了 user_id
-	var friends []models.Relation
-	if tx := db.Preload("ToUser").Preload("ToUser.Profile").
-		Where("from_user_id = ? AND to_user_id IN (?)", userIdString, followingIds).
-		Find(&friends); tx.Error != nil {
-		c.JSON(http.StatusInternalServerError, utils.CommentResponse{
+	c.JSON(http.StatusOK, utils.UserListResponse{
+		StatusCode: 0,
+		StatusMsg:  "Success",
+		NextCursor: nextCursor,
+		HasMore:    hasMore,
+		UserList:   buildUserList(c, db, users),
+	})
+}
+
+// GetFollowers 查询粉丝列表
+func GetFollowers(c *gin.Context) {
+	// 获取 user_id 参数
+	userIdString := c.DefaultQuery("user_id", "")
+	// 验证 user_id
+	userIdInt, err := strconv.Atoi(userIdString)
+	if err != nil || userIdInt <= 0 {
+		c.JSON(http.StatusBadRequest, utils.UserListResponse{
 			StatusCode: 1,
-			StatusMsg:  "Failed to fetch data.",
+			StatusMsg:  "Invalid target user ID.",
 		})
 		return
 	}
 
-	if len(friends) == 0 {
-		c.JSON(http.StatusOK, gin.H{
-			"status_code": 0,
-			"status_msg":  "Success",
-			"user_list":   friends,
-		})
+	// 验证隐私设置
+	db := c.MustGet("db").(*gorm.DB)
+	if !privacy.CheckList(c, db, uint(userIdInt), privacy.FollowerList) {
 		return
 	}
 
-	// 生成 user_list
-	var userList []utils.UserResponse
-	for _, friend := range friends {
-		userList = append(userList, utils.UserResponse{
-			ID:             friend.ToUser.ID,
-			Name:           friend.ToUser.Username,
-			FollowCount:    friend.ToUser.Profile.FollowCount,
-			FollowerCount:  friend.ToUser.Profile.FollowerCount,
-			IsFollow:       true,
-			Avatar:         friend.ToUser.Profile.Avatar,
-			Background:     friend.ToUser.Profile.Background,
-			Signature:      friend.ToUser.Profile.Signature,
-			TotalFavorited: friend.ToUser.Profile.TotalFavorited,
-			WorkCount:      friend.ToUser.Profile.WorkCount,
-			FavoriteCount:  friend.ToUser.Profile.FavoriteCount,
-		})
+	// 分页查找用户的粉丝列表
+	relationships, nextCursor, hasMore, ok := pageRelations(c,
+		db.Where("to_user_id = ?", userIdInt), "FromUser", "Failed to fetch followers.")
+	if !ok {
+		return
+	}
+	var users []models.User
+	for _, relation := range relationships {
+		users = append(users, relation.FromUser)
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"status_code": 0,
-		"status_msg":  "Success",
-		"user_list":   userList,
+	c.JSON(http.StatusOK, utils.UserListResponse{
+		StatusCode: 0,
+		StatusMsg:  "Success",
+		NextCursor: nextCursor,
+		HasMore:    hasMore,
+		UserList:   buildUserList(c, db, users),
 	})
 }
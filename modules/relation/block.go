@@ -0,0 +1,144 @@
+package relation
+
+import (
+	"app/modules/models"
+	"app/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"net/http"
+	"strconv"
+)
+
+// parseTarget 验证 token 和 to_user_id，to_user_id 不能是自己，验证失败时已经返回了错误信息
+func parseTarget(c *gin.Context, db *gorm.DB) (uint, uint, bool) {
+	tokenString := c.DefaultQuery("token", "")
+	userId, err := utils.ValidateToken(tokenString)
+	if err != nil || userId <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status_code": 1,
+			"status_msg":  "Invalid user ID.",
+		})
+		return 0, 0, false
+	}
+
+	toUserIdInt, err := strconv.Atoi(c.DefaultQuery("to_user_id", ""))
+	if err != nil || toUserIdInt <= 0 || uint(toUserIdInt) == userId {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status_code": 1,
+			"status_msg":  "Invalid target user ID.",
+		})
+		return 0, 0, false
+	}
+	var toUser models.User
+	if err := db.First(&toUser, toUserIdInt).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status_code": 1,
+			"status_msg":  "to_user_id not found.",
+		})
+		return 0, 0, false
+	}
+	return userId, toUser.ID, true
+}
+
+// Block 拉黑用户，同时删除双方之间的关注关系。重复拉黑不会报错
+func Block(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userId, toUserId, ok := parseTarget(c, db)
+	if !ok {
+		return
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&models.Block{UserID: userId, BlockedUserID: toUserId}).Error; err != nil {
+			return err
+		}
+		// 删除双方之间还没有处理的关注申请
+		if err := tx.Where("(from_user_id = ? AND to_user_id = ?) OR (from_user_id = ? AND to_user_id = ?)",
+			userId, toUserId, toUserId, userId).Delete(&models.FollowRequest{}).Error; err != nil {
+			return err
+		}
+		// 通过 Relation 的 Hook 更新关注数、粉丝数和好友数
+		for _, relation := range []models.Relation{
+			{FromUserId: userId, ToUserId: toUserId},
+			{FromUserId: toUserId, ToUserId: userId},
+		} {
+			if err := tx.Where("from_user_id = ? AND to_user_id = ?", relation.FromUserId, relation.ToUserId).
+				Delete(&relation).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status_code": 1,
+			"status_msg":  "Failed to block user.",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status_code": 0,
+		"status_msg":  "Success",
+	})
+}
+
+// Unblock 取消拉黑，之前删除的关注关系不会恢复
+func Unblock(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userId, toUserId, ok := parseTarget(c, db)
+	if !ok {
+		return
+	}
+
+	if err := db.Where("user_id = ? AND blocked_user_id = ?", userId, toUserId).
+		Delete(&models.Block{}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status_code": 1,
+			"status_msg":  "Failed to unblock user.",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status_code": 0,
+		"status_msg":  "Success",
+	})
+}
+
+// GetBlocks 查询当前用户的黑名单，按拉黑时间倒序分页
+func GetBlocks(c *gin.Context) {
+	tokenString := c.DefaultQuery("token", "")
+	userId, err := utils.ValidateToken(tokenString)
+	if err != nil || userId <= 0 {
+		c.JSON(http.StatusBadRequest, utils.UserListResponse{
+			StatusCode: 1,
+			StatusMsg:  "Invalid user ID.",
+		})
+		return
+	}
+
+	db := c.MustGet("db").(*gorm.DB)
+	blockedIds, nextCursor, hasMore, ok := pageUserRecords(c,
+		db.Model(&models.Block{}).Where("user_id = ?", userId),
+		"blocked_user_id", "Failed to fetch blocked users.")
+	if !ok {
+		return
+	}
+
+	// 拉黑时已经删除了关注关系，is_follow 都为 false
+	var userList []utils.UserResponse
+	for _, u := range loadUsers(db, blockedIds) {
+		userList = append(userList, utils.BuildUserResponse(u, false))
+	}
+
+	c.JSON(http.StatusOK, utils.UserListResponse{
+		StatusCode: 0,
+		StatusMsg:  "Success",
+		NextCursor: nextCursor,
+		HasMore:    hasMore,
+		UserList:   userList,
+	})
+}
@@ -0,0 +1,238 @@
+package relation
+
+import (
+	"app/consts"
+	"app/modules/models"
+	"app/modules/privacy"
+	"app/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 好友列表中最近一条消息的方向
+const (
+	msgTypeReceived = 0 // 当前用户接收的消息
+	msgTypeSent     = 1 // 当前用户发送的消息
+)
+
+// friendRow 好友列表中的一行，LastActive 为和好友最近一条消息的时间（毫秒），没有聊过天时为 0
+type friendRow struct {
+	ID         uint
+	ToUserId   uint
+	LastActive int64
+}
+
+// lastActiveSql 分别走两个方向的 idx_from_to_created 索引取最近一条消息的时间
+const lastActiveSql = "GREATEST(" +
+	"COALESCE((SELECT MAX(created_at) FROM messages " +
+	"WHERE from_user_id = relations.from_user_id AND to_user_id = relations.to_user_id), 0), " +
+	"COALESCE((SELECT MAX(created_at) FROM messages " +
+	"WHERE from_user_id = relations.to_user_id AND to_user_id = relations.from_user_id), 0))"
+
+// latestMessages 用窗口函数一次查出 userId 和每个好友之间最近的一条消息，key 为好友 ID。
+// 跳过 userId 删除了的消息
+func latestMessages(db *gorm.DB, userId uint, friendIds []uint) map[uint]models.Message {
+	messageByFriend := make(map[uint]models.Message)
+	if len(friendIds) == 0 {
+		return messageByFriend
+	}
+	conversations := db.Where("from_user_id = ? AND to_user_id IN ?", userId, friendIds).
+		Or("from_user_id IN ? AND to_user_id = ?", friendIds, userId)
+	ranked := db.Model(&models.Message{}).
+		Select("*, ROW_NUMBER() OVER (PARTITION BY CASE WHEN from_user_id = ? THEN to_user_id ELSE from_user_id END "+
+			"ORDER BY created_at DESC, id DESC) AS rn", userId).
+		Where(conversations).Scopes(models.NotDeletedBy(userId))
+	var messages []models.Message
+	db.Table("(?) AS ranked", ranked).Where("rn = 1").Find(&messages)
+	for _, m := range messages {
+		if m.FromUserID == userId {
+			messageByFriend[m.ToUserID] = m
+		} else {
+			messageByFriend[m.FromUserID] = m
+		}
+	}
+	return messageByFriend
+}
+
+// GetFriends 查询好友列表，按最近聊天时间倒序排列，没有聊过天的好友排在后面。
+// 查询自己的好友列表时，每个好友带上最近的一条消息和消息方向
+func GetFriends(c *gin.Context) {
+	// 获取 user_id 参数
+	userIdString := c.DefaultQuery("user_id", "")
+	// 验证 user_id
+	userIdInt, err := strconv.Atoi(userIdString)
+	if err != nil || userIdInt <= 0 {
+		c.JSON(http.StatusBadRequest, utils.FriendListResponse{
+			StatusCode: 1,
+			StatusMsg:  "Invalid target user ID.",
+		})
+		return
+	}
+
+	// 验证每页数量
+	count, err := strconv.Atoi(c.DefaultQuery("count", strconv.Itoa(consts.DefaultRelationCount)))
+	if err != nil || count < 1 || count > consts.MaxRelationCount {
+		c.JSON(http.StatusBadRequest, utils.FriendListResponse{
+			StatusCode: 1,
+			StatusMsg:  "Invalid count.",
+		})
+		return
+	}
+
+	// 验证隐私设置，好友列表同时暴露了关注和粉丝关系，两个列表都可见时才能查看
+	db := c.MustGet("db").(*gorm.DB)
+	if !privacy.CheckList(c, db, uint(userIdInt), privacy.FollowingList) ||
+		!privacy.CheckList(c, db, uint(userIdInt), privacy.FollowerList) {
+		return
+	}
+
+	// 好友为互相关注的用户，走 idx_from_mutual 索引
+	friends := db.Table("relations").Select("id, to_user_id, "+lastActiveSql+" AS last_active").
+		Where("from_user_id = ? AND is_mutual = ?", userIdInt, true)
+	query := db.Table("(?) AS friends", friends)
+
+	// 游标为上一页最后一个好友的 "最近聊天时间毫秒_关注关系ID"
+	if cursor := c.DefaultQuery("cursor", ""); cursor != "" {
+		cursorTime, cursorId, ok := utils.ParseTimeCursor(cursor)
+		if !ok {
+			c.JSON(http.StatusBadRequest, utils.FriendListResponse{
+				StatusCode: 1,
+				StatusMsg:  "Invalid cursor.",
+			})
+			return
+		}
+		lastActive := cursorTime.UnixMilli()
+		query = query.Where("last_active <= ? AND (last_active < ? OR id < ?)", lastActive, lastActive, cursorId)
+	}
+
+	// 多取一条用来判断是否还有下一页
+	var rows []friendRow
+	if err := query.Order("last_active desc").Order("id desc").Limit(count + 1).Find(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.FriendListResponse{
+			StatusCode: 1,
+			StatusMsg:  "Failed to fetch data.",
+		})
+		return
+	}
+	hasMore := len(rows) > count
+	if hasMore {
+		rows = rows[:count]
+	}
+	var nextCursor string
+	if len(rows) > 0 {
+		last := rows[len(rows)-1]
+		nextCursor = utils.FormatTimeCursor(time.UnixMilli(last.LastActive), last.ID)
+	}
+
+	// 批量查询好友的用户信息，以及当前用户关注了其中哪些用户
+	var friendIds []uint
+	for _, row := range rows {
+		friendIds = append(friendIds, row.ToUserId)
+	}
+	userById := make(map[uint]models.User)
+	if len(friendIds) > 0 {
+		var users []models.User
+		db.Preload("Profile").Where("id IN ?", friendIds).Find(&users)
+		for _, u := range users {
+			userById[u.ID] = u
+		}
+	}
+	currentUserId, _ := utils.ValidateToken(c.DefaultQuery("token", ""))
+	followedIdSet := followedSet(db, currentUserId, friendIds)
+
+	// 聊天记录只对本人可见
+	messageByFriend := make(map[uint]models.Message)
+	if currentUserId == uint(userIdInt) {
+		messageByFriend = latestMessages(db, currentUserId, friendIds)
+	}
+
+	// 生成 user_list
+	var userList []utils.FriendUserResItem
+	for _, row := range rows {
+		user, ok := userById[row.ToUserId]
+		if !ok {
+			continue
+		}
+		item := utils.FriendUserResItem{
+			UserResponse: utils.BuildUserResponse(user, followedIdSet[user.ID]),
+		}
+		if m, ok := messageByFriend[user.ID]; ok {
+			// 和聊天记录一致，撤回的消息显示撤回提示
+			item.Message = m.Content
+			if m.Recalled {
+				item.Message = consts.RecalledMessageContent
+			}
+			item.MsgType = msgTypeReceived
+			if m.FromUserID == currentUserId {
+				item.MsgType = msgTypeSent
+			}
+		}
+		userList = append(userList, item)
+	}
+
+	c.JSON(http.StatusOK, utils.FriendListResponse{
+		StatusCode: 0,
+		StatusMsg:  "Success",
+		NextCursor: nextCursor,
+		HasMore:    hasMore,
+		UserList:   userList,
+	})
+}
+
+// IsFriend 批量查询 user_ids 中的用户是否和当前用户互相关注，user_ids 用逗号分隔
+func IsFriend(c *gin.Context) {
+	tokenString := c.DefaultQuery("token", "")
+	userId, err := utils.ValidateToken(tokenString)
+	if err != nil || userId <= 0 {
+		c.JSON(http.StatusBadRequest, utils.IsFriendResponse{
+			StatusCode: 1,
+			StatusMsg:  "Invalid user ID.",
+		})
+		return
+	}
+
+	// 验证 user_ids
+	var userIds []uint
+	for _, idString := range strings.Split(c.DefaultQuery("user_ids", ""), ",") {
+		id, err := strconv.Atoi(strings.TrimSpace(idString))
+		if err != nil || id <= 0 {
+			c.JSON(http.StatusBadRequest, utils.IsFriendResponse{
+				StatusCode: 1,
+				StatusMsg:  "Invalid user_ids.",
+			})
+			return
+		}
+		userIds = append(userIds, uint(id))
+	}
+	if len(userIds) > consts.MaxFriendCheckCount {
+		c.JSON(http.StatusBadRequest, utils.IsFriendResponse{
+			StatusCode: 1,
+			StatusMsg:  "Too many user_ids.",
+		})
+		return
+	}
+
+	db := c.MustGet("db").(*gorm.DB)
+	friendSet, err := models.FriendSet(db, userId, userIds)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.IsFriendResponse{
+			StatusCode: 1,
+			StatusMsg:  "Failed to fetch data.",
+		})
+		return
+	}
+	isFriend := make(map[uint]bool)
+	for _, id := range userIds {
+		isFriend[id] = friendSet[id]
+	}
+
+	c.JSON(http.StatusOK, utils.IsFriendResponse{
+		StatusCode: 0,
+		StatusMsg:  "Success",
+		IsFriend:   isFriend,
+	})
+}
@@ -0,0 +1,91 @@
+package relation
+
+import (
+	"app/modules/models"
+	"app/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"net/http"
+)
+
+// Mute 静音用户，不影响关注关系，也不会通知对方。重复静音不会报错
+func Mute(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userId, toUserId, ok := parseTarget(c, db)
+	if !ok {
+		return
+	}
+
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.Mute{UserID: userId, MutedUserID: toUserId}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status_code": 1,
+			"status_msg":  "Failed to mute user.",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status_code": 0,
+		"status_msg":  "Success",
+	})
+}
+
+// Unmute 取消静音
+func Unmute(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userId, toUserId, ok := parseTarget(c, db)
+	if !ok {
+		return
+	}
+
+	if err := db.Where("user_id = ? AND muted_user_id = ?", userId, toUserId).
+		Delete(&models.Mute{}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status_code": 1,
+			"status_msg":  "Failed to unmute user.",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status_code": 0,
+		"status_msg":  "Success",
+	})
+}
+
+// GetMutes 查询当前用户静音的用户，按静音时间倒序分页。静音列表只有本人可以查看
+func GetMutes(c *gin.Context) {
+	tokenString := c.DefaultQuery("token", "")
+	userId, err := utils.ValidateToken(tokenString)
+	if err != nil || userId <= 0 {
+		c.JSON(http.StatusBadRequest, utils.UserListResponse{
+			StatusCode: 1,
+			StatusMsg:  "Invalid user ID.",
+		})
+		return
+	}
+
+	db := c.MustGet("db").(*gorm.DB)
+	mutedIds, nextCursor, hasMore, ok := pageUserRecords(c,
+		db.Model(&models.Mute{}).Where("user_id = ?", userId),
+		"muted_user_id", "Failed to fetch muted users.")
+	if !ok {
+		return
+	}
+
+	followedIdSet := followedSet(db, userId, mutedIds)
+	var userList []utils.UserResponse
+	for _, u := range loadUsers(db, mutedIds) {
+		userList = append(userList, utils.BuildUserResponse(u, followedIdSet[u.ID]))
+	}
+
+	c.JSON(http.StatusOK, utils.UserListResponse{
+		StatusCode: 0,
+		StatusMsg:  "Success",
+		NextCursor: nextCursor,
+		HasMore:    hasMore,
+		UserList:   userList,
+	})
+}
@@ -2,14 +2,18 @@ package relation
 
 import (
 	"app/config"
+	"app/consts"
 	"app/modules/models"
 	"app/utils"
+	"encoding/json"
 	"github.com/stretchr/testify/assert"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"os"
+	"strconv"
 	"testing"
+	"time"
 )
 
 func TestMain(m *testing.M) {
@@ -179,7 +183,7 @@ func TestGetFollowers(t *testing.T) {
 
 // 测试获取好友列表
 func TestGetFriends(t *testing.T) {
-	config.Router.GET(FriendListRul, GetFollowers)
+	config.Router.GET(FriendListRul, GetFriends)
 	// 获取id为1的好友列表，结果返回200
 	token, err := utils.GenerateToken(1)
 	if err != nil {
@@ -208,3 +212,427 @@ func TestGetFriends(t *testing.T) {
 	config.Router.ServeHTTP(response, req)
 	assert.Equal(t, http.StatusBadRequest, response.Code)
 }
+
+// 测试好友列表同时受关注列表和粉丝列表的隐私设置保护，路由在 TestGetFriends 中注册
+func TestFriendsPrivacy(t *testing.T) {
+	owner := models.User{Username: "friends_owner", Password: "friends_owner_pass"}
+	viewer := models.User{Username: "friends_viewer", Password: "friends_viewer_pass"}
+	db.Create(&owner)
+	db.Create(&viewer)
+	setting := models.PrivacySetting{UserID: owner.ID, FollowerList: models.PrivacyOnlyMe}
+	db.Create(&setting)
+
+	getFriends := func(userId uint) int {
+		token, _ := utils.GenerateToken(userId)
+		values := url.Values{}
+		values.Add("token", token)
+		values.Add("user_id", strconv.Itoa(int(owner.ID)))
+		req, _ := http.NewRequest("GET", FriendListRul+"?"+values.Encode(), nil)
+		response := httptest.NewRecorder()
+		config.Router.ServeHTTP(response, req)
+		return response.Code
+	}
+
+	// 只有粉丝列表私密时也不能查看好友列表
+	assert.Equal(t, http.StatusForbidden, getFriends(viewer.ID))
+	assert.Equal(t, http.StatusOK, getFriends(owner.ID))
+
+	db.Model(&setting).Updates(map[string]interface{}{
+		"follower_list": models.PrivacyPublic, "following_list": models.PrivacyOnlyMe,
+	})
+	assert.Equal(t, http.StatusForbidden, getFriends(viewer.ID))
+
+	db.Model(&setting).Update("following_list", models.PrivacyPublic)
+	assert.Equal(t, http.StatusOK, getFriends(viewer.ID))
+}
+
+// 测试粉丝列表的游标分页
+func TestFollowersPagination(t *testing.T) {
+	// 新建 3 个用户关注 michael
+	var michael models.User
+	db.Where("username = ?", "michael").First(&michael)
+	var followerIds []uint
+	for _, name := range []string{"fan_a", "fan_b", "fan_c"} {
+		fan := models.User{Username: name, Password: name + "_pass"}
+		db.Create(&fan)
+		db.Create(&models.Relation{FromUserId: fan.ID, ToUserId: michael.ID})
+		followerIds = append(followerIds, fan.ID)
+	}
+
+	token, err := utils.GenerateToken(michael.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var pageIds []uint
+	cursor := ""
+	for page := 0; page < 2; page++ {
+		values := url.Values{}
+		values.Add("token", token)
+		values.Add("user_id", strconv.Itoa(int(michael.ID)))
+		values.Add("count", "2")
+		values.Add("cursor", cursor)
+		req, _ := http.NewRequest("GET", FollowerListUrl+"?"+values.Encode(), nil)
+		response := httptest.NewRecorder()
+		config.Router.ServeHTTP(response, req)
+		assert.Equal(t, http.StatusOK, response.Code)
+
+		var resp utils.UserListResponse
+		if err := json.Unmarshal(response.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		if page == 0 {
+			assert.True(t, resp.HasMore)
+		}
+		for _, u := range resp.UserList {
+			pageIds = append(pageIds, u.ID)
+		}
+		cursor = resp.NextCursor
+	}
+
+	// 按关注时间倒序返回，新粉丝在前面，不会重复也不会遗漏
+	for _, id := range followerIds {
+		assert.Contains(t, pageIds, id)
+	}
+	assert.Equal(t, followerIds[2], pageIds[0])
+}
+
+// 测试好友列表按最近聊天时间排序，并带上最近一条消息
+func TestFriendsMessagePreview(t *testing.T) {
+	var ids []uint
+	for _, name := range []string{"chat_a", "chat_b", "chat_c"} {
+		user := models.User{Username: name, Password: name + "_pass"}
+		db.Create(&user)
+		ids = append(ids, user.ID)
+	}
+	for _, friendId := range ids[1:] {
+		db.Create(&models.Relation{FromUserId: ids[0], ToUserId: friendId})
+		db.Create(&models.Relation{FromUserId: friendId, ToUserId: ids[0]})
+	}
+	now := time.Now().UnixMilli()
+	db.Create(&models.Message{FromUserID: ids[0], ToUserID: ids[1], Content: "older", CreatedAt: now - 1000})
+	db.Create(&models.Message{FromUserID: ids[2], ToUserID: ids[0], Content: "newer", CreatedAt: now})
+
+	token, err := utils.GenerateToken(ids[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	getFriends := func() utils.FriendListResponse {
+		values := url.Values{}
+		values.Add("token", token)
+		values.Add("user_id", strconv.Itoa(int(ids[0])))
+		req, _ := http.NewRequest("GET", FriendListRul+"?"+values.Encode(), nil)
+		response := httptest.NewRecorder()
+		config.Router.ServeHTTP(response, req)
+		assert.Equal(t, http.StatusOK, response.Code)
+
+		var resp utils.FriendListResponse
+		if err := json.Unmarshal(response.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+	resp := getFriends()
+	assert.Len(t, resp.UserList, 2)
+	assert.Equal(t, ids[2], resp.UserList[0].ID)
+	assert.Equal(t, "newer", resp.UserList[0].Message)
+	assert.Equal(t, 0, resp.UserList[0].MsgType)
+	assert.Equal(t, ids[1], resp.UserList[1].ID)
+	assert.Equal(t, "older", resp.UserList[1].Message)
+	assert.Equal(t, 1, resp.UserList[1].MsgType)
+
+	// 自己删除了的消息不作为预览，撤回的消息显示撤回提示
+	db.Create(&models.Message{FromUserID: ids[1], ToUserID: ids[0], Content: "deleted", CreatedAt: now + 1, ToDeleted: true})
+	db.Create(&models.Message{FromUserID: ids[0], ToUserID: ids[2], CreatedAt: now + 2, Recalled: true})
+	resp = getFriends()
+	assert.Len(t, resp.UserList, 2)
+	assert.Equal(t, consts.RecalledMessageContent, resp.UserList[0].Message)
+	assert.Equal(t, 1, resp.UserList[0].MsgType)
+	assert.Equal(t, "older", resp.UserList[1].Message)
+}
+
+// 测试单向关注和互相关注时的好友关系和好友数
+func TestMutualFollow(t *testing.T) {
+	var IsFriendUrl = "/douyin/relation/is_friend/"
+	config.Router.GET(IsFriendUrl, IsFriend)
+
+	alice := models.User{Username: "mutual_a", Password: "mutual_a_pass"}
+	bob := models.User{Username: "mutual_b", Password: "mutual_b_pass"}
+	db.Create(&alice)
+	db.Create(&bob)
+	aliceToken, _ := utils.GenerateToken(alice.ID)
+	bobToken, _ := utils.GenerateToken(bob.ID)
+
+	follow := func(token string, toUserId uint, actionType string) {
+		values := url.Values{}
+		values.Add("token", token)
+		values.Add("to_user_id", strconv.Itoa(int(toUserId)))
+		values.Add("action_type", actionType)
+		req, _ := http.NewRequest("POST", ActionUrl+"?"+values.Encode(), nil)
+		response := httptest.NewRecorder()
+		config.Router.ServeHTTP(response, req)
+		assert.Equal(t, http.StatusOK, response.Code)
+	}
+	assertFriend := func(expected bool) {
+		values := url.Values{}
+		values.Add("token", aliceToken)
+		values.Add("user_ids", strconv.Itoa(int(bob.ID)))
+		req, _ := http.NewRequest("GET", IsFriendUrl+"?"+values.Encode(), nil)
+		response := httptest.NewRecorder()
+		config.Router.ServeHTTP(response, req)
+		assert.Equal(t, http.StatusOK, response.Code)
+		var resp utils.IsFriendResponse
+		if err := json.Unmarshal(response.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, expected, resp.IsFriend[bob.ID])
+
+		expectedCount := 0
+		if expected {
+			expectedCount = 1
+		}
+		for _, id := range []uint{alice.ID, bob.ID} {
+			var profile models.UserProfile
+			db.Where("user_id = ?", id).First(&profile)
+			assert.Equal(t, expectedCount, profile.FriendCount)
+		}
+	}
+
+	// 单向关注不是好友
+	follow(aliceToken, bob.ID, "1")
+	assertFriend(false)
+	// 互相关注后成为好友
+	follow(bobToken, alice.ID, "1")
+	assertFriend(true)
+	// 任意一方取关后不再是好友
+	follow(aliceToken, bob.ID, "2")
+	assertFriend(false)
+}
+
+// 测试二度关注的推荐和不感兴趣
+func TestSuggestions(t *testing.T) {
+	var SuggestUrl = "/douyin/relation/suggest/"
+	var DismissUrl = "/douyin/relation/suggest/dismiss/"
+	config.Router.GET(SuggestUrl, Suggest)
+	config.Router.POST(DismissUrl, Dismiss)
+
+	var ids []uint
+	for _, name := range []string{"suggest_a", "suggest_b", "suggest_c"} {
+		user := models.User{Username: name, Password: name + "_pass"}
+		db.Create(&user)
+		ids = append(ids, user.ID)
+	}
+	db.Create(&models.Relation{FromUserId: ids[0], ToUserId: ids[1]})
+	db.Create(&models.Relation{FromUserId: ids[1], ToUserId: ids[2]})
+	token, _ := utils.GenerateToken(ids[0])
+
+	suggest := func() utils.SuggestionListResponse {
+		if err := ComputeSuggestions(db, ids[0]); err != nil {
+			t.Fatal(err)
+		}
+		req, _ := http.NewRequest("GET", SuggestUrl+"?token="+token, nil)
+		response := httptest.NewRecorder()
+		config.Router.ServeHTTP(response, req)
+		assert.Equal(t, http.StatusOK, response.Code)
+		var resp utils.SuggestionListResponse
+		if err := json.Unmarshal(response.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	// 关注的 suggest_b 关注了 suggest_c
+	resp := suggest()
+	assert.Len(t, resp.SuggestionList, 1)
+	assert.Equal(t, ids[2], resp.SuggestionList[0].User.ID)
+	assert.Equal(t, "Followed by suggest_b", resp.SuggestionList[0].Reason)
+
+	// suggest_b 的关注列表私密时不显示用户名
+	setting := models.PrivacySetting{UserID: ids[1], FollowingList: models.PrivacyOnlyMe}
+	db.Create(&setting)
+	resp = suggest()
+	assert.Len(t, resp.SuggestionList, 1)
+	assert.Equal(t, "Followed by someone you follow", resp.SuggestionList[0].Reason)
+	db.Delete(&setting)
+
+	// 不感兴趣之后重新计算也不会再推荐
+	values := url.Values{}
+	values.Add("token", token)
+	values.Add("user_id", strconv.Itoa(int(ids[2])))
+	req, _ := http.NewRequest("POST", DismissUrl+"?"+values.Encode(), nil)
+	response := httptest.NewRecorder()
+	config.Router.ServeHTTP(response, req)
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Empty(t, suggest().SuggestionList)
+}
+
+// 测试拉黑后删除双方的关注关系，并且不能再关注
+func TestBlock(t *testing.T) {
+	var BlockUrl = "/douyin/relation/block/"
+	var UnblockUrl = "/douyin/relation/unblock/"
+	var BlockListUrl = "/douyin/relation/block/list/"
+	config.Router.POST(BlockUrl, Block)
+	config.Router.POST(UnblockUrl, Unblock)
+	config.Router.GET(BlockListUrl, GetBlocks)
+
+	blocker := models.User{Username: "blocker", Password: "blocker_pass"}
+	blocked := models.User{Username: "blocked", Password: "blocked_pass"}
+	db.Create(&blocker)
+	db.Create(&blocked)
+	db.Create(&models.Relation{FromUserId: blocker.ID, ToUserId: blocked.ID})
+	db.Create(&models.Relation{FromUserId: blocked.ID, ToUserId: blocker.ID})
+	blockerToken, _ := utils.GenerateToken(blocker.ID)
+	blockedToken, _ := utils.GenerateToken(blocked.ID)
+
+	request := func(method, path, token string, toUserId uint, actionType string) *httptest.ResponseRecorder {
+		values := url.Values{}
+		values.Add("token", token)
+		values.Add("to_user_id", strconv.Itoa(int(toUserId)))
+		values.Add("action_type", actionType)
+		req, _ := http.NewRequest(method, path+"?"+values.Encode(), nil)
+		response := httptest.NewRecorder()
+		config.Router.ServeHTTP(response, req)
+		return response
+	}
+
+	assert.Equal(t, http.StatusOK, request("POST", BlockUrl, blockerToken, blocked.ID, "").Code)
+
+	// 双方的关注关系和计数都被删除
+	var relations int64
+	db.Model(&models.Relation{}).Where("from_user_id IN ? AND to_user_id IN ?",
+		[]uint{blocker.ID, blocked.ID}, []uint{blocker.ID, blocked.ID}).Count(&relations)
+	assert.Equal(t, int64(0), relations)
+	for _, id := range []uint{blocker.ID, blocked.ID} {
+		var profile models.UserProfile
+		db.Where("user_id = ?", id).First(&profile)
+		assert.Equal(t, 0, profile.FollowCount)
+		assert.Equal(t, 0, profile.FollowerCount)
+		assert.Equal(t, 0, profile.FriendCount)
+	}
+
+	// 双方都不能再关注对方
+	assert.Equal(t, http.StatusForbidden, request("POST", ActionUrl, blockedToken, blocker.ID, "1").Code)
+	assert.Equal(t, http.StatusForbidden, request("POST", ActionUrl, blockerToken, blocked.ID, "1").Code)
+
+	// 黑名单中只有被拉黑的用户
+	response := request("GET", BlockListUrl, blockerToken, 0, "")
+	assert.Equal(t, http.StatusOK, response.Code)
+	var resp utils.UserListResponse
+	if err := json.Unmarshal(response.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, resp.UserList, 1)
+	assert.Equal(t, blocked.ID, resp.UserList[0].ID)
+
+	// 取消拉黑后可以重新关注
+	assert.Equal(t, http.StatusOK, request("POST", UnblockUrl, blockerToken, blocked.ID, "").Code)
+	assert.Equal(t, http.StatusOK, request("POST", ActionUrl, blockedToken, blocker.ID, "1").Code)
+}
+
+// 测试关注私密账号需要经过同意，同意后粉丝数才变化
+func TestFollowRequest(t *testing.T) {
+	var RequestListUrl = "/douyin/relation/request/list/"
+	var RequestActionUrl = "/douyin/relation/request/action/"
+	config.Router.GET(RequestListUrl, GetRequests)
+	config.Router.POST(RequestActionUrl, RequestAction)
+
+	owner := models.User{Username: "private_owner", Password: "private_owner_pass"}
+	fan := models.User{Username: "private_fan", Password: "private_fan_pass"}
+	db.Create(&owner)
+	db.Create(&fan)
+	db.Create(&models.PrivacySetting{UserID: owner.ID, IsPrivate: true})
+	ownerToken, _ := utils.GenerateToken(owner.ID)
+	fanToken, _ := utils.GenerateToken(fan.ID)
+
+	assertFollowers := func(expected int) {
+		var profile models.UserProfile
+		db.Where("user_id = ?", owner.ID).First(&profile)
+		assert.Equal(t, expected, profile.FollowerCount)
+		var relations int64
+		db.Model(&models.Relation{}).Where("from_user_id = ? AND to_user_id = ?", fan.ID, owner.ID).Count(&relations)
+		assert.Equal(t, int64(expected), relations)
+	}
+
+	// 关注私密账号只会创建关注申请
+	values := url.Values{}
+	values.Add("token", fanToken)
+	values.Add("to_user_id", strconv.Itoa(int(owner.ID)))
+	values.Add("action_type", "1")
+	req, _ := http.NewRequest("POST", ActionUrl+"?"+values.Encode(), nil)
+	response := httptest.NewRecorder()
+	config.Router.ServeHTTP(response, req)
+	assert.Equal(t, http.StatusOK, response.Code)
+	assertFollowers(0)
+
+	// 私密账号收到关注申请
+	req, _ = http.NewRequest("GET", RequestListUrl+"?token="+ownerToken, nil)
+	response = httptest.NewRecorder()
+	config.Router.ServeHTTP(response, req)
+	assert.Equal(t, http.StatusOK, response.Code)
+	var resp utils.UserListResponse
+	if err := json.Unmarshal(response.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, resp.UserList, 1)
+	assert.Equal(t, fan.ID, resp.UserList[0].ID)
+
+	// 同意之后才真正关注，重复处理时申请已经不存在
+	values = url.Values{}
+	values.Add("token", ownerToken)
+	values.Add("from_user_id", strconv.Itoa(int(fan.ID)))
+	values.Add("action_type", "1")
+	for _, expected := range []int{http.StatusOK, http.StatusNotFound} {
+		req, _ = http.NewRequest("POST", RequestActionUrl+"?"+values.Encode(), nil)
+		response = httptest.NewRecorder()
+		config.Router.ServeHTTP(response, req)
+		assert.Equal(t, expected, response.Code)
+		assertFollowers(1)
+	}
+}
+
+// 测试静音不影响关注关系，静音列表中只有被静音的用户
+func TestMute(t *testing.T) {
+	var MuteUrl = "/douyin/relation/mute/"
+	var MuteListUrl = "/douyin/relation/mute/list/"
+	config.Router.POST(MuteUrl, Mute)
+	config.Router.GET(MuteListUrl, GetMutes)
+
+	muter := models.User{Username: "muter", Password: "muter_pass"}
+	muted := models.User{Username: "muted", Password: "muted_pass"}
+	db.Create(&muter)
+	db.Create(&muted)
+	db.Create(&models.Relation{FromUserId: muter.ID, ToUserId: muted.ID})
+	muterToken, _ := utils.GenerateToken(muter.ID)
+	mutedToken, _ := utils.GenerateToken(muted.ID)
+
+	// 重复静音不会报错
+	values := url.Values{}
+	values.Add("token", muterToken)
+	values.Add("to_user_id", strconv.Itoa(int(muted.ID)))
+	for i := 0; i < 2; i++ {
+		req, _ := http.NewRequest("POST", MuteUrl+"?"+values.Encode(), nil)
+		response := httptest.NewRecorder()
+		config.Router.ServeHTTP(response, req)
+		assert.Equal(t, http.StatusOK, response.Code)
+	}
+
+	var relations int64
+	db.Model(&models.Relation{}).Where("from_user_id = ? AND to_user_id = ?", muter.ID, muted.ID).Count(&relations)
+	assert.Equal(t, int64(1), relations)
+
+	// 静音列表只包含当前用户静音的用户
+	for token, expected := range map[string]int{muterToken: 1, mutedToken: 0} {
+		req, _ := http.NewRequest("GET", MuteListUrl+"?token="+token, nil)
+		response := httptest.NewRecorder()
+		config.Router.ServeHTTP(response, req)
+		assert.Equal(t, http.StatusOK, response.Code)
+		var resp utils.UserListResponse
+		if err := json.Unmarshal(response.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		assert.Len(t, resp.UserList, expected)
+		if expected == 1 {
+			assert.Equal(t, muted.ID, resp.UserList[0].ID)
+			assert.True(t, resp.UserList[0].IsFollow)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package relation
+
+import (
+	"app/modules/models"
+	"app/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"net/http"
+	"strconv"
+)
+
+// requestFollow 向私密账号发送关注申请，重复申请不会报错
+func requestFollow(c *gin.Context, db *gorm.DB, fromUserId, toUserId uint) {
+	var follows int64
+	if err := db.Model(&models.Relation{}).
+		Where("from_user_id = ? AND to_user_id = ?", fromUserId, toUserId).
+		Count(&follows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status_code": 1,
+			"status_msg":  "Failed to follow.",
+		})
+		return
+	}
+	if follows > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status_code": 1,
+			"status_msg":  "You've already followed this user.",
+		})
+		return
+	}
+
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.FollowRequest{FromUserID: fromUserId, ToUserID: toUserId}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status_code": 1,
+			"status_msg":  "Failed to send follow request.",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status_code": 0,
+		"status_msg":  "Follow request sent.",
+	})
+}
+
+// GetRequests 查询当前用户收到的关注申请，按申请时间倒序分页
+func GetRequests(c *gin.Context) {
+	tokenString := c.DefaultQuery("token", "")
+	userId, err := utils.ValidateToken(tokenString)
+	if err != nil || userId <= 0 {
+		c.JSON(http.StatusBadRequest, utils.UserListResponse{
+			StatusCode: 1,
+			StatusMsg:  "Invalid user ID.",
+		})
+		return
+	}
+
+	db := c.MustGet("db").(*gorm.DB)
+	fromUserIds, nextCursor, hasMore, ok := pageUserRecords(c,
+		db.Model(&models.FollowRequest{}).Where("to_user_id = ?", userId),
+		"from_user_id", "Failed to fetch follow requests.")
+	if !ok {
+		return
+	}
+
+	followedIdSet := followedSet(db, userId, fromUserIds)
+	var userList []utils.UserResponse
+	for _, u := range loadUsers(db, fromUserIds) {
+		userList = append(userList, utils.BuildUserResponse(u, followedIdSet[u.ID]))
+	}
+
+	c.JSON(http.StatusOK, utils.UserListResponse{
+		StatusCode: 0,
+		StatusMsg:  "Success",
+		NextCursor: nextCursor,
+		HasMore:    hasMore,
+		UserList:   userList,
+	})
+}
+
+// RequestAction 处理收到的关注申请，action_type 为 1 时同意，为 2 时拒绝。
+// 同意时创建关注关系，关注数和粉丝数由 Relation 的 Hook 更新
+func RequestAction(c *gin.Context) {
+	tokenString := c.DefaultQuery("token", "")
+	userId, err := utils.ValidateToken(tokenString)
+	if err != nil || userId <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status_code": 1,
+			"status_msg":  "Invalid user ID.",
+		})
+		return
+	}
+
+	fromUserId, err := strconv.Atoi(c.DefaultQuery("from_user_id", ""))
+	if err != nil || fromUserId <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status_code": 1,
+			"status_msg":  "Invalid from_user_id.",
+		})
+		return
+	}
+
+	actionType := c.DefaultQuery("action_type", "")
+	if actionType != "1" && actionType != "2" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status_code": 1,
+			"status_msg":  "Invalid action_type.",
+		})
+		return
+	}
+
+	db := c.MustGet("db").(*gorm.DB)
+	found := true
+	err = db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("from_user_id = ? AND to_user_id = ?", fromUserId, userId).
+			Delete(&models.FollowRequest{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			found = false
+			return nil
+		}
+		if actionType == "2" {
+			return nil
+		}
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&models.Relation{FromUserId: uint(fromUserId), ToUserId: userId}).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status_code": 1,
+			"status_msg":  "Failed to handle follow request.",
+		})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status_code": 1,
+			"status_msg":  "Follow request not found.",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status_code": 0,
+		"status_msg":  "Success",
+	})
+}
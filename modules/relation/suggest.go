@@ -0,0 +1,360 @@
+package relation
+
+import (
+	"app/consts"
+	"app/modules/models"
+	"app/modules/policy"
+	"app/modules/privacy"
+	"app/utils"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// candidate 某一种原因下推荐的用户，ViaCount 为共同关注/共同点赞的数量
+type candidate struct {
+	SuggestedUserID uint
+	ViaUserID       uint
+	ViaCount        int
+}
+
+// 每种原因的权重，一个用户同时满足多种原因时得分相加
+var suggestWeights = map[int]float64{
+	models.SuggestFriendOfFriend: 3,
+	models.SuggestSameFavorite:   1,
+	models.SuggestPopular:        2,
+}
+
+// notFollowed 排除用户自己和已经关注的用户
+func notFollowed(db *gorm.DB, column string, userId uint) *gorm.DB {
+	followings := db.Table("relations").Select("to_user_id").Where("from_user_id = ?", userId)
+	return db.Where(column+" <> ? AND "+column+" NOT IN (?)", userId, followings)
+}
+
+// findCandidates 查询三种原因下的候选用户，每种原因取共同数量最多的 MaxStoredSuggestions 个
+func findCandidates(db *gorm.DB, userId uint) (map[int][]candidate, error) {
+	candidates := make(map[int][]candidate)
+
+	// 1. 二度关注：关注的人也关注了他
+	var friendsOfFriends []candidate
+	if err := db.Table("relations r1").
+		Select("r2.to_user_id AS suggested_user_id, MIN(r2.from_user_id) AS via_user_id, COUNT(*) AS via_count").
+		Joins("JOIN relations r2 ON r2.from_user_id = r1.to_user_id").
+		Where("r1.from_user_id = ?", userId).
+		Where(notFollowed(db, "r2.to_user_id", userId)).
+		Group("r2.to_user_id").Order("via_count desc").
+		Limit(consts.MaxStoredSuggestions).Scan(&friendsOfFriends).Error; err != nil {
+		return nil, err
+	}
+	candidates[models.SuggestFriendOfFriend] = friendsOfFriends
+
+	// 2. 点赞了相同视频的用户
+	var sameFavorites []candidate
+	if err := db.Table("favorites f1").
+		Select("f2.user_id AS suggested_user_id, COUNT(*) AS via_count").
+		Joins("JOIN favorites f2 ON f2.video_id = f1.video_id").
+		Where("f1.user_id = ?", userId).
+		Where(notFollowed(db, "f2.user_id", userId)).
+		Group("f2.user_id").Order("via_count desc").
+		Limit(consts.MaxStoredSuggestions).Scan(&sameFavorites).Error; err != nil {
+		return nil, err
+	}
+	// 推荐原因会暴露对方点赞过的视频，对方的喜欢列表对当前用户不可见时不使用这个原因
+	var visibleFavorites []candidate
+	for _, cand := range sameFavorites {
+		ok, err := privacy.CanView(db, userId, cand.SuggestedUserID, privacy.FavoriteList)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			visibleFavorites = append(visibleFavorites, cand)
+		}
+	}
+	candidates[models.SuggestSameFavorite] = visibleFavorites
+
+	// 3. 粉丝们关注的创作者
+	var popular []candidate
+	if err := db.Table("relations r1").
+		Select("r2.to_user_id AS suggested_user_id, MIN(r2.from_user_id) AS via_user_id, COUNT(*) AS via_count").
+		Joins("JOIN relations r2 ON r2.from_user_id = r1.from_user_id").
+		Joins("JOIN user_profiles p ON p.user_id = r2.to_user_id AND p.work_count > 0").
+		Where("r1.to_user_id = ?", userId).
+		Where(notFollowed(db, "r2.to_user_id", userId)).
+		Group("r2.to_user_id").Order("via_count desc").
+		Limit(consts.MaxStoredSuggestions).Scan(&popular).Error; err != nil {
+		return nil, err
+	}
+	candidates[models.SuggestPopular] = popular
+
+	return candidates, nil
+}
+
+// ComputeSuggestions 重新计算 userId 的推荐关注，保存得分最高的 MaxStoredSuggestions 个，
+// 删除不再满足条件的推荐，已经不感兴趣的推荐保持不变
+func ComputeSuggestions(db *gorm.DB, userId uint) error {
+	candidates, err := findCandidates(db, userId)
+	if err != nil {
+		return err
+	}
+
+	// 不感兴趣的用户不参与计算
+	var dismissedIds []uint
+	if err := db.Model(&models.FollowSuggestion{}).Where("user_id = ? AND dismissed = ?", userId, true).
+		Pluck("suggested_user_id", &dismissedIds).Error; err != nil {
+		return err
+	}
+	dismissedSet := make(map[uint]bool)
+	for _, id := range dismissedIds {
+		dismissedSet[id] = true
+	}
+
+	// 合并不同原因的得分，原因取得分最高的一种
+	suggestionByUser := make(map[uint]*models.FollowSuggestion)
+	bestScore := make(map[uint]float64)
+	for reason, list := range candidates {
+		for _, cand := range list {
+			if dismissedSet[cand.SuggestedUserID] {
+				continue
+			}
+			score := suggestWeights[reason] * float64(cand.ViaCount)
+			s, ok := suggestionByUser[cand.SuggestedUserID]
+			if !ok {
+				s = &models.FollowSuggestion{UserID: userId, SuggestedUserID: cand.SuggestedUserID}
+				suggestionByUser[cand.SuggestedUserID] = s
+			}
+			s.Score += score
+			if score > bestScore[cand.SuggestedUserID] {
+				bestScore[cand.SuggestedUserID] = score
+				s.Reason, s.ViaUserID, s.ViaCount = reason, cand.ViaUserID, cand.ViaCount
+			}
+		}
+	}
+	var suggestions []models.FollowSuggestion
+	for _, s := range suggestionByUser {
+		suggestions = append(suggestions, *s)
+	}
+	// 按得分从高到低排序，得分相同时按用户 ID 排序保证结果稳定
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].Score != suggestions[j].Score {
+			return suggestions[i].Score > suggestions[j].Score
+		}
+		return suggestions[i].SuggestedUserID < suggestions[j].SuggestedUserID
+	})
+	if len(suggestions) > consts.MaxStoredSuggestions {
+		suggestions = suggestions[:consts.MaxStoredSuggestions]
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		stale := tx.Where("user_id = ? AND dismissed = ?", userId, false)
+		if len(suggestions) > 0 {
+			var suggestedIds []uint
+			for _, s := range suggestions {
+				suggestedIds = append(suggestedIds, s.SuggestedUserID)
+			}
+			stale = stale.Where("suggested_user_id NOT IN ?", suggestedIds)
+		}
+		if err := stale.Delete(&models.FollowSuggestion{}).Error; err != nil {
+			return err
+		}
+		if len(suggestions) == 0 {
+			return nil
+		}
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "suggested_user_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"reason", "via_user_id", "via_count", "score", "updated_at"}),
+		}).Create(&suggestions).Error
+	})
+}
+
+// RefreshSuggestions 为所有用户重新计算推荐关注，单个用户失败只记录日志
+func RefreshSuggestions(db *gorm.DB) error {
+	var users []models.User
+	return db.Select("id").FindInBatches(&users, 500, func(tx *gorm.DB, batch int) error {
+		for _, u := range users {
+			if err := ComputeSuggestions(db, u.ID); err != nil {
+				log.Printf("Failed to compute follow suggestions for user %d. Err: %s", u.ID, err)
+			}
+		}
+		return nil
+	}).Error
+}
+
+// StartSuggester 启动后台任务，定期重新计算推荐关注
+func StartSuggester(db *gorm.DB, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for ; true; <-ticker.C {
+			if err := RefreshSuggestions(db); err != nil {
+				log.Printf("Failed to refresh follow suggestions. Err: %s", err)
+			}
+		}
+	}()
+}
+
+// showVia 判断推荐原因中能否显示 ViaUserID 的用户名。"由 X 关注" 同时暴露了 X 的关注列表
+// 和被推荐用户的粉丝列表，两个列表都对当前用户可见时才显示
+func showVia(db *gorm.DB, userId uint, s models.FollowSuggestion) bool {
+	if s.ViaUserID == 0 {
+		return false
+	}
+	ok, err := privacy.CanView(db, userId, s.ViaUserID, privacy.FollowingList)
+	if err != nil || !ok {
+		return false
+	}
+	ok, err = privacy.CanView(db, userId, s.SuggestedUserID, privacy.FollowerList)
+	return err == nil && ok
+}
+
+// formatReason 生成推荐原因的描述，viaName 为 ViaUserID 对应的用户名，不能显示时为空
+func formatReason(s models.FollowSuggestion, viaName string) string {
+	switch s.Reason {
+	case models.SuggestFriendOfFriend:
+		if viaName == "" {
+			if s.ViaCount <= 1 {
+				return "Followed by someone you follow"
+			}
+			return fmt.Sprintf("Followed by %d people you follow", s.ViaCount)
+		}
+		if s.ViaCount <= 1 {
+			return fmt.Sprintf("Followed by %s", viaName)
+		}
+		return fmt.Sprintf("Followed by %s and %d others", viaName, s.ViaCount-1)
+	case models.SuggestSameFavorite:
+		if s.ViaCount <= 1 {
+			return "Liked the same video as you"
+		}
+		return fmt.Sprintf("Liked %d of the same videos as you", s.ViaCount)
+	case models.SuggestPopular:
+		if viaName == "" {
+			return "Popular with your followers"
+		}
+		if s.ViaCount <= 1 {
+			return fmt.Sprintf("Popular with your followers, followed by %s", viaName)
+		}
+		return fmt.Sprintf("Popular with your followers, followed by %s and %d others", viaName, s.ViaCount-1)
+	}
+	return ""
+}
+
+// Suggest 查询推荐关注，排除不感兴趣的用户、计算之后已经关注的用户和有拉黑关系的用户
+func Suggest(c *gin.Context) {
+	tokenString := c.DefaultQuery("token", "")
+	userId, err := utils.ValidateToken(tokenString)
+	if err != nil || userId <= 0 {
+		c.JSON(http.StatusBadRequest, utils.SuggestionListResponse{
+			StatusCode: 1,
+			StatusMsg:  "Invalid user ID.",
+		})
+		return
+	}
+
+	db := c.MustGet("db").(*gorm.DB)
+	var suggestions []models.FollowSuggestion
+	if err := db.Where("user_id = ? AND dismissed = ?", userId, false).
+		Where(notFollowed(db, "suggested_user_id", userId)).
+		Scopes(policy.NotBlocked("follow_suggestions.suggested_user_id", userId)).
+		Order("score desc").Order("suggested_user_id").
+		Limit(consts.MaxSuggestionCount).Find(&suggestions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.SuggestionListResponse{
+			StatusCode: 1,
+			StatusMsg:  "Failed to fetch suggestions.",
+		})
+		return
+	}
+
+	// 批量查询推荐的用户和推荐原因中的用户
+	var userIds []uint
+	for _, s := range suggestions {
+		userIds = append(userIds, s.SuggestedUserID)
+		if s.ViaUserID != 0 {
+			userIds = append(userIds, s.ViaUserID)
+		}
+	}
+	userById := make(map[uint]models.User)
+	if len(userIds) > 0 {
+		var users []models.User
+		db.Preload("Profile").Where("id IN ?", userIds).Find(&users)
+		for _, u := range users {
+			userById[u.ID] = u
+		}
+	}
+
+	var suggestionList []utils.SuggestionResItem
+	for _, s := range suggestions {
+		user, ok := userById[s.SuggestedUserID]
+		if !ok {
+			continue
+		}
+		// 计算之后对方可能修改了隐私设置，查询时重新检查
+		if s.Reason == models.SuggestSameFavorite {
+			if visible, err := privacy.CanView(db, userId, s.SuggestedUserID, privacy.FavoriteList); err != nil || !visible {
+				continue
+			}
+		}
+		var viaName string
+		if showVia(db, userId, s) {
+			viaName = userById[s.ViaUserID].Username
+		}
+		suggestionList = append(suggestionList, utils.SuggestionResItem{
+			User:   utils.BuildUserResponse(user, false),
+			Reason: formatReason(s, viaName),
+		})
+	}
+
+	c.JSON(http.StatusOK, utils.SuggestionListResponse{
+		StatusCode:     0,
+		StatusMsg:      "Success",
+		SuggestionList: suggestionList,
+	})
+}
+
+// Dismiss 对推荐的用户不感兴趣，之后不会再推荐这个用户
+func Dismiss(c *gin.Context) {
+	tokenString := c.DefaultQuery("token", "")
+	userId, err := utils.ValidateToken(tokenString)
+	if err != nil || userId <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status_code": 1,
+			"status_msg":  "Invalid user ID.",
+		})
+		return
+	}
+
+	suggestedUserId, err := strconv.Atoi(c.DefaultQuery("user_id", ""))
+	if err != nil || suggestedUserId <= 0 || uint(suggestedUserId) == userId {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status_code": 1,
+			"status_msg":  "Invalid target user ID.",
+		})
+		return
+	}
+
+	// 还没有被推荐过的用户也记录下来，之后计算时不会推荐
+	db := c.MustGet("db").(*gorm.DB)
+	if err := db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "suggested_user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"dismissed", "updated_at"}),
+	}).Create(&models.FollowSuggestion{
+		UserID:          userId,
+		SuggestedUserID: uint(suggestedUserId),
+		Dismissed:       true,
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status_code": 1,
+			"status_msg":  "Failed to dismiss suggestion.",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status_code": 0,
+		"status_msg":  "Success",
+	})
+}
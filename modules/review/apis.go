@@ -0,0 +1,199 @@
+package review
+
+import (
+	"app/consts"
+	"app/modules/comment"
+	"app/modules/filter"
+	"app/modules/message"
+	"app/modules/models"
+	"app/modules/user"
+	"app/modules/video"
+	"app/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// publishers 各个来源的内容审核通过后的发布方式
+var publishers = map[string]func(db *gorm.DB, review models.ContentReview) error{
+	filter.SourceComment:   comment.PublishReviewed,
+	filter.SourceMessage:   message.PublishReviewed,
+	filter.SourceTitle:     video.PublishReviewed,
+	filter.SourceSignature: user.PublishReviewed,
+}
+
+// checkModerator 验证 token 并确认当前用户是审核人员，验证失败时已经返回了错误信息
+func checkModerator(c *gin.Context, db *gorm.DB) (uint, bool) {
+	tokenString := c.DefaultQuery("token", "")
+	userId, err := utils.ValidateToken(tokenString)
+	if err != nil || userId <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status_code": 1,
+			"status_msg":  "Invalid user ID.",
+		})
+		return 0, false
+	}
+	var moderator models.User
+	if err := db.Select("id", "is_moderator").First(&moderator, userId).Error; err != nil || !moderator.IsModerator {
+		c.JSON(http.StatusForbidden, gin.H{
+			"status_code": 1,
+			"status_msg":  "Only moderators can review content.",
+		})
+		return 0, false
+	}
+	return userId, true
+}
+
+// List 按提交时间顺序分页列出待审核的内容，cursor 为上一页返回的 next_cursor
+func List(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	if _, ok := checkModerator(c, db); !ok {
+		return
+	}
+
+	// 验证 cursor，0 表示第一页
+	cursor, err := strconv.Atoi(c.DefaultQuery("cursor", "0"))
+	if err != nil || cursor < 0 {
+		c.JSON(http.StatusBadRequest, utils.ContentReviewListResponse{
+			StatusCode: 1,
+			StatusMsg:  "Invalid cursor.",
+		})
+		return
+	}
+	// 验证每页数量
+	count, err := strconv.Atoi(c.DefaultQuery("count", strconv.Itoa(consts.DefaultReviewCount)))
+	if err != nil || count < 1 || count > consts.MaxReviewCount {
+		c.JSON(http.StatusBadRequest, utils.ContentReviewListResponse{
+			StatusCode: 1,
+			StatusMsg:  "Invalid count.",
+		})
+		return
+	}
+
+	// 多取一条用来判断是否还有下一页
+	var reviews []models.ContentReview
+	if err := db.Where("status = ? AND id > ?", models.ReviewPending, cursor).
+		Order("id").Limit(count + 1).Find(&reviews).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ContentReviewListResponse{
+			StatusCode: 1,
+			StatusMsg:  "Failed to fetch reviews.",
+		})
+		return
+	}
+	hasMore := len(reviews) > count
+	if hasMore {
+		reviews = reviews[:count]
+	}
+
+	reviewList := make([]utils.ContentReviewResItem, 0, len(reviews))
+	var nextCursor uint
+	for _, r := range reviews {
+		reviewList = append(reviewList, utils.ContentReviewResItem{
+			ID:         r.ID,
+			UserID:     r.UserID,
+			Source:     r.Source,
+			TargetID:   r.TargetID,
+			ParentID:   r.ParentID,
+			Content:    r.Content,
+			CreateDate: r.CreatedAt,
+		})
+		nextCursor = r.ID
+	}
+
+	c.JSON(http.StatusOK, utils.ContentReviewListResponse{
+		StatusCode: 0,
+		StatusMsg:  "Success",
+		NextCursor: nextCursor,
+		HasMore:    hasMore,
+		ReviewList: reviewList,
+	})
+}
+
+// Action 处理一条待审核的内容，action_type 为 1 时通过并发布，为 2 时拒绝
+func Action(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	moderatorId, ok := checkModerator(c, db)
+	if !ok {
+		return
+	}
+
+	reviewId, err := strconv.Atoi(c.DefaultQuery("review_id", "0"))
+	if err != nil || reviewId < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status_code": 1,
+			"status_msg":  "Invalid review_id.",
+		})
+		return
+	}
+	status := models.ReviewApproved
+	switch c.DefaultQuery("action_type", "") {
+	case "1":
+	case "2":
+		status = models.ReviewRejected
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status_code": 1,
+			"status_msg":  "Invalid action type.",
+		})
+		return
+	}
+
+	var review models.ContentReview
+	if err := db.First(&review, reviewId).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status_code": 1,
+			"status_msg":  "Review not found.",
+		})
+		return
+	}
+
+	// 先把状态从待审核改为处理结果，避免同一条内容被重复发布
+	now := time.Now()
+	result := db.Model(&review).Where("status = ?", models.ReviewPending).Updates(map[string]interface{}{
+		"status":      status,
+		"reviewer_id": moderatorId,
+		"reviewed_at": now,
+	})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status_code": 1,
+			"status_msg":  "Failed to review content.",
+		})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"status_code": 1,
+			"status_msg":  "Content has already been reviewed.",
+		})
+		return
+	}
+
+	// 发布失败时（例如视频或被回复的评论已经被删除）恢复为待审核，审核人员可以重试或拒绝
+	if status == models.ReviewApproved {
+		publish, ok := publishers[review.Source]
+		if !ok {
+			log.Printf("No publisher for review source %s.", review.Source)
+		}
+		if !ok || publish(db, review) != nil {
+			db.Model(&review).Updates(map[string]interface{}{
+				"status":      models.ReviewPending,
+				"reviewer_id": 0,
+				"reviewed_at": nil,
+			})
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"status_code": 1,
+				"status_msg":  "Failed to publish content.",
+			})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status_code": 0,
+		"status_msg":  "Success",
+	})
+}
@@ -0,0 +1,185 @@
+package review
+
+import (
+	"app/config"
+	"app/modules/comment"
+	"app/modules/filter"
+	"app/modules/message"
+	"app/modules/models"
+	"app/modules/user"
+	"app/modules/video"
+	"app/utils"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	utils.Setup()
+	postSetup()
+	code := m.Run()
+	utils.Teardown()
+	os.Exit(code)
+}
+
+var ListUrl = "/douyin/review/list/"
+var ActionUrl = "/douyin/review/action/"
+var CommentUrl = "/douyin/comment/action/"
+var MessageUrl = "/douyin/message/action/"
+var TitleUrl = "/douyin/publish/edit/"
+var SignatureUrl = "/douyin/user/signature/"
+var db = utils.GetDb()
+var moderatorId, aliceId, bobId uint
+
+func postSetup() {
+	var ids []uint
+	for _, name := range []string{"moderator_r", "alice_r", "bob_r"} {
+		u := models.User{Username: name, Password: name + "_pass"}
+		db.Create(&u)
+		ids = append(ids, u.ID)
+	}
+	moderatorId, aliceId, bobId = ids[0], ids[1], ids[2]
+	db.Model(&models.User{}).Where("id = ?", moderatorId).Update("is_moderator", true)
+
+	// 加载只有一个 review 敏感词的词库
+	file, err := os.CreateTemp("", "sensitive_words")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	file.WriteString("加微信,spam,review\n")
+	file.Close()
+	if err := filter.Load(file.Name()); err != nil {
+		log.Fatal(err)
+	}
+
+	config.Router.GET(ListUrl, List)
+	config.Router.POST(ActionUrl, Action)
+	config.Router.POST(CommentUrl, comment.Action)
+	config.Router.POST(MessageUrl, message.Send)
+	config.Router.POST(TitleUrl, video.Edit)
+	config.Router.POST(SignatureUrl, user.UpdateSignature)
+}
+
+func request(method, path string, userId uint, values url.Values) *httptest.ResponseRecorder {
+	token, _ := utils.GenerateToken(userId)
+	values.Set("token", token)
+	req, _ := http.NewRequest(method, path+"?"+values.Encode(), nil)
+	response := httptest.NewRecorder()
+	config.Router.ServeHTTP(response, req)
+	return response
+}
+
+func listReviews(t *testing.T) []utils.ContentReviewResItem {
+	response := request("GET", ListUrl, moderatorId, url.Values{})
+	assert.Equal(t, http.StatusOK, response.Code)
+	var resp utils.ContentReviewListResponse
+	if err := json.Unmarshal(response.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp.ReviewList
+}
+
+func handle(reviewId uint, actionType string) *httptest.ResponseRecorder {
+	return request("POST", ActionUrl, moderatorId, url.Values{
+		"review_id": {strconv.Itoa(int(reviewId))}, "action_type": {actionType},
+	})
+}
+
+// 测试命中 review 敏感词的评论、私信、标题和签名在审核通过之前不会发布
+func TestHeldUntilApproved(t *testing.T) {
+	videoRecord := models.Video{UserID: aliceId, Title: "original title", PublishTime: time.Now()}
+	db.Create(&videoRecord)
+	db.Model(&models.UserProfile{}).Where("user_id = ?", aliceId).Update("signature", "original signature")
+	videoId := strconv.Itoa(int(videoRecord.ID))
+
+	response := request("POST", CommentUrl, bobId, url.Values{
+		"video_id": {videoId}, "action_type": {"1"}, "comment_text": {"加微信聊"},
+	})
+	assert.Equal(t, http.StatusAccepted, response.Code)
+	response = request("POST", MessageUrl, bobId, url.Values{
+		"to_user_id": {strconv.Itoa(int(aliceId))}, "action_type": {"1"}, "content": {"加微信"},
+	})
+	assert.Equal(t, http.StatusAccepted, response.Code)
+	response = request("POST", TitleUrl, aliceId, url.Values{"video_id": {videoId}, "title": {"加微信看"}})
+	assert.Equal(t, http.StatusAccepted, response.Code)
+	response = request("POST", SignatureUrl, aliceId, url.Values{"signature": {"加微信"}})
+	assert.Equal(t, http.StatusAccepted, response.Code)
+
+	// 审核之前都不可见
+	var comments, messages int64
+	db.Model(&models.Comment{}).Where("video_id = ?", videoRecord.ID).Count(&comments)
+	db.Model(&models.Message{}).Where("from_user_id = ?", bobId).Count(&messages)
+	assert.Equal(t, int64(0), comments)
+	assert.Equal(t, int64(0), messages)
+	db.First(&videoRecord, videoRecord.ID)
+	assert.Equal(t, "original title", videoRecord.Title)
+	assert.Equal(t, uint(0), videoRecord.CommentCount)
+	var profile models.UserProfile
+	db.Where("user_id = ?", aliceId).First(&profile)
+	assert.Equal(t, "original signature", profile.Signature)
+
+	// 只有审核人员可以查看审核队列
+	response = request("GET", ListUrl, aliceId, url.Values{})
+	assert.Equal(t, http.StatusForbidden, response.Code)
+	reviews := listReviews(t)
+	assert.Len(t, reviews, 4)
+	reviewIds := make(map[string]uint)
+	for _, r := range reviews {
+		reviewIds[r.Source] = r.ID
+	}
+
+	// 拒绝后仍然不可见，也不能再次处理
+	assert.Equal(t, http.StatusOK, handle(reviewIds[filter.SourceSignature], "2").Code)
+	assert.Equal(t, http.StatusConflict, handle(reviewIds[filter.SourceSignature], "1").Code)
+	db.Where("user_id = ?", aliceId).First(&profile)
+	assert.Equal(t, "original signature", profile.Signature)
+
+	// 通过后发布
+	for _, source := range []string{filter.SourceComment, filter.SourceMessage, filter.SourceTitle} {
+		assert.Equal(t, http.StatusOK, handle(reviewIds[source], "1").Code, source)
+	}
+	var published models.Comment
+	assert.NoError(t, db.Where("video_id = ?", videoRecord.ID).First(&published).Error)
+	assert.Equal(t, "加微信聊", published.Content)
+	db.Model(&models.Message{}).Where("from_user_id = ? AND content = ?", bobId, "加微信").Count(&messages)
+	assert.Equal(t, int64(1), messages)
+	db.First(&videoRecord, videoRecord.ID)
+	assert.Equal(t, "加微信看", videoRecord.Title)
+	assert.Equal(t, uint(1), videoRecord.CommentCount)
+	assert.Empty(t, listReviews(t))
+}
+
+// 测试被回复的评论在审核期间被删除时，审核通过也不会发布，内容保留在审核队列中
+func TestPublishFailureKeepsPending(t *testing.T) {
+	videoRecord := models.Video{UserID: aliceId, Title: "reply video", PublishTime: time.Now()}
+	db.Create(&videoRecord)
+	parent := models.Comment{UserID: aliceId, VideoID: videoRecord.ID, Content: "parent", CreatedAt: time.Now()}
+	db.Create(&parent)
+
+	response := request("POST", CommentUrl, bobId, url.Values{
+		"video_id": {strconv.Itoa(int(videoRecord.ID))}, "action_type": {"1"},
+		"comment_text": {"加微信"}, "parent_id": {strconv.Itoa(int(parent.ID))},
+	})
+	assert.Equal(t, http.StatusAccepted, response.Code)
+	db.Delete(&parent)
+
+	reviews := listReviews(t)
+	assert.Len(t, reviews, 1)
+	assert.Equal(t, parent.ID, reviews[0].ParentID)
+	assert.Equal(t, http.StatusUnprocessableEntity, handle(reviews[0].ID, "1").Code)
+	var replies int64
+	db.Model(&models.Comment{}).Where("parent_id = ?", parent.ID).Count(&replies)
+	assert.Equal(t, int64(0), replies)
+
+	// 恢复为待审核之后可以拒绝
+	assert.Equal(t, http.StatusOK, handle(reviews[0].ID, "2").Code)
+	assert.Empty(t, listReviews(t))
+}
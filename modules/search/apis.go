@@ -0,0 +1,244 @@
+package search
+
+import (
+	"app/consts"
+	"app/modules/models"
+	"app/modules/policy"
+	"app/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// rank 将文本相关度与热度（获赞数、粉丝数、话题视频数）混合，
+// 热度取对数，避免热门内容完全压过相关度
+func rank(hits []Hit, popularity map[uint]int64) []Hit {
+	ranked := make([]Hit, 0, len(hits))
+	for _, h := range hits {
+		pop, ok := popularity[h.ID]
+		if !ok { // 数据库中已经不存在的文档
+			continue
+		}
+		ranked = append(ranked, Hit{
+			ID:    h.ID,
+			Score: h.Score * (1 + consts.SearchPopularityWeight*math.Log1p(float64(pop))),
+		})
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].Score > ranked[j].Score
+	})
+	return ranked
+}
+
+// page 取出从 offset 开始的一页结果的ID，返回下一页的 offset 和是否还有下一页
+func page(hits []Hit, offset int) (ids []uint, nextOffset int, hasMore bool) {
+	for i := offset; i < len(hits) && i < offset+consts.MaxSearchResults; i++ {
+		ids = append(ids, hits[i].ID)
+	}
+	return ids, offset + len(ids), len(hits) > offset+consts.MaxSearchResults
+}
+
+// Search 搜索接口，type 为 video / user / tag，按相关度和热度排序，
+// 用 offset 翻页，下一页的 offset 为返回的 next_offset
+func Search(c *gin.Context) {
+	query := strings.TrimSpace(c.DefaultQuery("q", ""))
+	if len(query) == 0 || utf8.RuneCountInString(query) > consts.MaxSearchQueryLength {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status_code": 1,
+			"status_msg":  "Query length must be between 1 and 50.",
+		})
+		return
+	}
+
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status_code": 1,
+			"status_msg":  "Invalid offset.",
+		})
+		return
+	}
+
+	searchType := c.DefaultQuery("type", TypeVideo)
+	if searchType != TypeVideo && searchType != TypeUser && searchType != TypeTag {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status_code": 1,
+			"status_msg":  "Invalid type.",
+		})
+		return
+	}
+
+	hits, err := DefaultIndex.Search(searchType, query, consts.MaxSearchCandidates)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status_code": 1,
+			"status_msg":  "Failed to search.",
+		})
+		return
+	}
+
+	db := c.MustGet("db").(*gorm.DB)
+	tokenString := c.DefaultQuery("token", "")
+	currentUserId, _ := utils.ValidateToken(tokenString)
+
+	// 只在翻第一页时记录，避免翻页重复计数
+	if offset == 0 {
+		recordHistory(db, currentUserId, query)
+	}
+
+	var candidateIds []uint
+	for _, h := range hits {
+		candidateIds = append(candidateIds, h.ID)
+	}
+
+	switch searchType {
+	case TypeVideo:
+		searchVideos(c, db, hits, candidateIds, offset, currentUserId)
+	case TypeUser:
+		searchUsers(c, db, hits, candidateIds, offset, currentUserId)
+	case TypeTag:
+		searchTags(c, db, hits, candidateIds, offset)
+	}
+}
+
+func searchVideos(c *gin.Context, db *gorm.DB, hits []Hit, candidateIds []uint, offset int, currentUserId uint) {
+	// 查询候选视频的获赞数作为热度，有拉黑关系的发布者的视频和没有关注的私密账号的视频不参与排序
+	var counts []models.Video
+	if len(candidateIds) > 0 {
+		if err := db.Select("id", "favorite_count").Where("id IN ?", candidateIds).
+			Scopes(policy.NotBlocked("videos.user_id", currentUserId),
+				policy.VisibleVideos("videos.user_id", currentUserId)).
+			Find(&counts).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, utils.SearchResponse{
+				StatusCode: 1,
+				StatusMsg:  "Failed to fetch videos.",
+			})
+			return
+		}
+	}
+	popularity := make(map[uint]int64)
+	for _, v := range counts {
+		popularity[v.ID] = int64(v.FavoriteCount)
+	}
+	ids, nextOffset, hasMore := page(rank(hits, popularity), offset)
+
+	// 按排序后的顺序返回视频
+	var videos []models.Video
+	if len(ids) > 0 {
+		db.Preload("User").Preload("User.Profile").Where("id IN ?", ids).Find(&videos)
+	}
+	order := make(map[uint]int)
+	for i, id := range ids {
+		order[id] = i
+	}
+	sort.Slice(videos, func(i, j int) bool {
+		return order[videos[i].ID] < order[videos[j].ID]
+	})
+
+	c.JSON(http.StatusOK, utils.SearchResponse{
+		StatusCode: 0,
+		StatusMsg:  "Success",
+		NextOffset: nextOffset,
+		HasMore:    hasMore,
+		VideoList:  utils.BuildVideoResList(db, videos, currentUserId),
+	})
+}
+
+func searchUsers(c *gin.Context, db *gorm.DB, hits []Hit, candidateIds []uint, offset int, currentUserId uint) {
+	// 查询候选用户的粉丝数作为热度，有拉黑关系的用户不参与排序
+	var profiles []models.UserProfile
+	if len(candidateIds) > 0 {
+		if err := db.Select("user_id", "follower_count").Where("user_id IN ?", candidateIds).
+			Scopes(policy.NotBlocked("user_profiles.user_id", currentUserId)).
+			Find(&profiles).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, utils.SearchResponse{
+				StatusCode: 1,
+				StatusMsg:  "Failed to fetch users.",
+			})
+			return
+		}
+	}
+	popularity := make(map[uint]int64)
+	for _, p := range profiles {
+		popularity[p.UserID] = int64(p.FollowerCount)
+	}
+	ids, nextOffset, hasMore := page(rank(hits, popularity), offset)
+
+	var users []models.User
+	if len(ids) > 0 {
+		db.Preload("Profile").Where("id IN ?", ids).Find(&users)
+	}
+	order := make(map[uint]int)
+	for i, id := range ids {
+		order[id] = i
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return order[users[i].ID] < order[users[j].ID]
+	})
+
+	// 查询当前用户关注了哪些搜索结果中的用户
+	var followedIds []uint
+	db.Table("relations").
+		Where("from_user_id = ? AND to_user_id IN ?", currentUserId, ids).
+		Pluck("to_user_id", &followedIds)
+	var followedIdSet = make(map[uint]bool)
+	for _, id := range followedIds {
+		followedIdSet[id] = true
+	}
+
+	var userList []utils.UserResponse
+	for _, u := range users {
+		userList = append(userList, utils.BuildUserResponse(u, followedIdSet[u.ID]))
+	}
+
+	c.JSON(http.StatusOK, utils.SearchResponse{
+		StatusCode: 0,
+		StatusMsg:  "Success",
+		NextOffset: nextOffset,
+		HasMore:    hasMore,
+		UserList:   userList,
+	})
+}
+
+func searchTags(c *gin.Context, db *gorm.DB, hits []Hit, candidateIds []uint, offset int) {
+	// 话题的视频数作为热度
+	var tags []models.Tag
+	if len(candidateIds) > 0 {
+		if err := db.Where("id IN ? AND video_count > 0", candidateIds).Find(&tags).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, utils.SearchResponse{
+				StatusCode: 1,
+				StatusMsg:  "Failed to fetch tags.",
+			})
+			return
+		}
+	}
+	popularity := make(map[uint]int64)
+	tagById := make(map[uint]models.Tag)
+	for _, t := range tags {
+		popularity[t.ID] = int64(t.VideoCount)
+		tagById[t.ID] = t
+	}
+	ids, nextOffset, hasMore := page(rank(hits, popularity), offset)
+
+	var tagList []utils.TagResItem
+	for _, id := range ids {
+		tagList = append(tagList, utils.TagResItem{
+			ID:         id,
+			Name:       tagById[id].Name,
+			VideoCount: int64(tagById[id].VideoCount),
+		})
+	}
+
+	c.JSON(http.StatusOK, utils.SearchResponse{
+		StatusCode: 0,
+		StatusMsg:  "Success",
+		NextOffset: nextOffset,
+		HasMore:    hasMore,
+		TagList:    tagList,
+	})
+}
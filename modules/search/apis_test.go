@@ -0,0 +1,36 @@
+package search
+
+import (
+	"app/consts"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// 测试按 offset 翻页时的 next_offset 和 has_more
+func TestPage(t *testing.T) {
+	var hits []Hit
+	for i := 1; i <= consts.MaxSearchResults+5; i++ {
+		hits = append(hits, Hit{ID: uint(i)})
+	}
+
+	ids, nextOffset, hasMore := page(hits, 0)
+	assert.Len(t, ids, consts.MaxSearchResults)
+	assert.Equal(t, consts.MaxSearchResults, nextOffset)
+	assert.True(t, hasMore)
+
+	ids, nextOffset, hasMore = page(hits, nextOffset)
+	assert.Len(t, ids, 5)
+	assert.Equal(t, uint(consts.MaxSearchResults+1), ids[0])
+	assert.Equal(t, len(hits), nextOffset)
+	assert.False(t, hasMore)
+
+	// 正好一页时没有下一页
+	_, _, hasMore = page(hits[:consts.MaxSearchResults], 0)
+	assert.False(t, hasMore)
+
+	// offset 超出结果数
+	ids, nextOffset, hasMore = page(hits, len(hits)+10)
+	assert.Empty(t, ids)
+	assert.Equal(t, len(hits)+10, nextOffset)
+	assert.False(t, hasMore)
+}
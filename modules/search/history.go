@@ -0,0 +1,193 @@
+package search
+
+import (
+	"app/consts"
+	"app/modules/filter"
+	"app/modules/models"
+	"app/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// recordHistory 记录一次搜索：计入热门搜索统计，已登录用户同时写入搜索历史。
+// 含有敏感词的搜索词不会出现在公开的热门搜索和联想词中，被拒绝的搜索词也不写入搜索历史
+func recordHistory(db *gorm.DB, userId uint, query string) {
+	filtered, verdict := filter.Apply(db, userId, filter.SourceSearch, query)
+	if verdict == filter.Reject {
+		return
+	}
+	now := time.Now()
+	if filtered == query {
+		DefaultHot.Record(query, now)
+	}
+	if userId == 0 {
+		return
+	}
+	history := models.SearchHistory{
+		UserID:     userId,
+		Query:      NormalizeQuery(filtered),
+		SearchedAt: now.UnixMilli(),
+	}
+	if err := db.Clauses(clause.OnConflict{
+		DoUpdates: clause.AssignmentColumns([]string{"searched_at"}),
+	}).Create(&history).Error; err != nil {
+		log.Printf("Failed to save search history. Err: %s", err)
+		return
+	}
+	if err := trimHistory(db, userId); err != nil {
+		log.Printf("Failed to trim search history. Err: %s", err)
+	}
+}
+
+// trimHistory 每个用户只保留最近的 MaxSearchHistoryCount 条搜索历史，走 idx_user_searched 索引
+func trimHistory(db *gorm.DB, userId uint) error {
+	var oldest []models.SearchHistory
+	if err := db.Where("user_id = ?", userId).Order("searched_at desc").Order("id desc").
+		Offset(consts.MaxSearchHistoryCount - 1).Limit(1).Find(&oldest).Error; err != nil {
+		return err
+	}
+	if len(oldest) == 0 {
+		return nil
+	}
+	last := oldest[0]
+	return db.Where("user_id = ? AND (searched_at < ? OR (searched_at = ? AND id < ?))",
+		userId, last.SearchedAt, last.SearchedAt, last.ID).Delete(&models.SearchHistory{}).Error
+}
+
+// Suggest 搜索联想接口，返回以 prefix 开头的热门搜索词、用户名和视频标题
+func Suggest(c *gin.Context) {
+	prefix := strings.TrimSpace(c.DefaultQuery("prefix", ""))
+	if len(prefix) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status_code": 1,
+			"status_msg":  "Prefix is required.",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status_code": 0,
+		"status_msg":  "Success",
+		"suggestions": Suggestions(prefix),
+	})
+}
+
+// GetHot 热门搜索接口，返回最近一段时间内搜索次数最多的搜索词
+func GetHot(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status_code": 0,
+		"status_msg":  "Success",
+		"hot_list":    DefaultHot.Top(consts.MaxHotSearches, time.Now()),
+	})
+}
+
+// GetHistory 返回当前用户最近的搜索历史
+func GetHistory(c *gin.Context) {
+	tokenString := c.DefaultQuery("token", "")
+	userId, err := utils.ValidateToken(tokenString)
+	if err != nil || userId <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status_code": 1,
+			"status_msg":  "Invalid user ID.",
+		})
+		return
+	}
+
+	var histories []models.SearchHistory
+	db := c.MustGet("db").(*gorm.DB)
+	if err := db.Where("user_id = ?", userId).Order("searched_at desc").Order("id desc").
+		Limit(consts.MaxSearchHistoryCount).Find(&histories).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status_code": 1,
+			"status_msg":  "Failed to fetch search history.",
+		})
+		return
+	}
+
+	var queries []string
+	for _, h := range histories {
+		queries = append(queries, h.Query)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status_code":  0,
+		"status_msg":   "Success",
+		"history_list": queries,
+	})
+}
+
+// DeleteHistory 删除一条搜索历史
+func DeleteHistory(c *gin.Context) {
+	tokenString := c.DefaultQuery("token", "")
+	userId, err := utils.ValidateToken(tokenString)
+	if err != nil || userId <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status_code": 1,
+			"status_msg":  "Invalid user ID.",
+		})
+		return
+	}
+
+	query := NormalizeQuery(c.DefaultQuery("q", ""))
+	if len(query) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status_code": 1,
+			"status_msg":  "Query is required.",
+		})
+		return
+	}
+
+	db := c.MustGet("db").(*gorm.DB)
+	tx := db.Where("user_id = ? AND query = ?", userId, query).Delete(&models.SearchHistory{})
+	if tx.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status_code": 1,
+			"status_msg":  "Failed to delete search history.",
+		})
+		return
+	}
+	if tx.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status_code": 1,
+			"status_msg":  "Search history not found.",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status_code": 0,
+		"status_msg":  "Success",
+	})
+}
+
+// ClearHistory 清空当前用户的搜索历史
+func ClearHistory(c *gin.Context) {
+	tokenString := c.DefaultQuery("token", "")
+	userId, err := utils.ValidateToken(tokenString)
+	if err != nil || userId <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status_code": 1,
+			"status_msg":  "Invalid user ID.",
+		})
+		return
+	}
+
+	db := c.MustGet("db").(*gorm.DB)
+	if err := db.Where("user_id = ?", userId).Delete(&models.SearchHistory{}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status_code": 1,
+			"status_msg":  "Failed to clear search history.",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status_code": 0,
+		"status_msg":  "Success",
+	})
+}
@@ -0,0 +1,86 @@
+package search
+
+import (
+	"app/consts"
+	"sort"
+	"sync"
+	"time"
+)
+
+// HotQuery 热门搜索词及其在时间窗口内的搜索次数
+type HotQuery struct {
+	Query string `json:"query"`
+	Count int64  `json:"count"`
+}
+
+type hotBucket struct {
+	start  int64 // 桶的起始时间（bucketSize 的整数倍）
+	counts map[string]int64
+}
+
+// HotTracker 按滑动窗口统计搜索词的频率。窗口被切分为若干个时间桶组成环形数组，
+// 过期的桶在被复用时清空，因此统计结果只包含最近 window 时间内的搜索
+type HotTracker struct {
+	mu         sync.Mutex
+	bucketSize time.Duration
+	buckets    []hotBucket
+}
+
+func NewHotTracker(window, bucketSize time.Duration) *HotTracker {
+	return &HotTracker{
+		bucketSize: bucketSize,
+		buckets:    make([]hotBucket, int(window/bucketSize)),
+	}
+}
+
+// DefaultHot 全局的热门搜索统计
+var DefaultHot = NewHotTracker(consts.HotSearchWindow, consts.HotSearchBucket)
+
+// Record 记录一次搜索
+func (h *HotTracker) Record(query string, now time.Time) {
+	query = NormalizeQuery(query)
+	if len(query) == 0 {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	start := now.UnixNano() / int64(h.bucketSize)
+	b := &h.buckets[start%int64(len(h.buckets))]
+	if b.start != start || b.counts == nil { // 桶已过期，清空后复用
+		b.start = start
+		b.counts = make(map[string]int64)
+	}
+	b.counts[query]++
+}
+
+// Top 返回时间窗口内搜索次数最多的 n 个搜索词
+func (h *HotTracker) Top(n int, now time.Time) []HotQuery {
+	h.mu.Lock()
+	totals := make(map[string]int64)
+	current := now.UnixNano() / int64(h.bucketSize)
+	for _, b := range h.buckets {
+		if b.counts == nil || current-b.start >= int64(len(h.buckets)) || b.start > current {
+			continue
+		}
+		for q, c := range b.counts {
+			totals[q] += c
+		}
+	}
+	h.mu.Unlock()
+
+	hot := make([]HotQuery, 0, len(totals))
+	for q, c := range totals {
+		hot = append(hot, HotQuery{Query: q, Count: c})
+	}
+	sort.Slice(hot, func(i, j int) bool {
+		if hot[i].Count != hot[j].Count {
+			return hot[i].Count > hot[j].Count
+		}
+		return hot[i].Query < hot[j].Query
+	})
+	if len(hot) > n {
+		hot = hot[:n]
+	}
+	return hot
+}
@@ -0,0 +1,170 @@
+package search
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// 索引中的文档类型
+const (
+	TypeVideo = "video"
+	TypeUser  = "user"
+	TypeTag   = "tag"
+)
+
+// Hit 一条搜索结果，Score 为文本相关度
+type Hit struct {
+	ID    uint
+	Score float64
+}
+
+// Index 搜索索引的接口，只负责文本相关度，热度排序由调用方完成。
+// 默认使用进程内的 MemoryIndex，需要多实例部署时可以换成外部的搜索服务
+type Index interface {
+	Put(docType string, id uint, text string) error
+	Delete(docType string, id uint) error
+	Search(docType string, query string, limit int) ([]Hit, error)
+}
+
+// Analyze 分词：连续的字母/数字作为一个词（转为小写），
+// 中日韩文字没有空格分隔，按单字和相邻两字切分
+func Analyze(text string) []string {
+	var tokens []string
+	var word []rune
+	var cjk []rune
+
+	flushWord := func() {
+		if len(word) > 0 {
+			tokens = append(tokens, strings.ToLower(string(word)))
+			word = word[:0]
+		}
+	}
+	flushCJK := func() {
+		for i := range cjk {
+			tokens = append(tokens, string(cjk[i]))
+			if i+1 < len(cjk) {
+				tokens = append(tokens, string(cjk[i:i+2]))
+			}
+		}
+		cjk = cjk[:0]
+	}
+
+	for _, r := range text {
+		switch {
+		case isCJK(r):
+			flushWord()
+			cjk = append(cjk, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			flushCJK()
+			word = append(word, r)
+		default:
+			flushWord()
+			flushCJK()
+		}
+	}
+	flushWord()
+	flushCJK()
+	return tokens
+}
+
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r)
+}
+
+// MemoryIndex 进程内的倒排索引，服务启动时通过 Rebuild 从数据库加载
+type MemoryIndex struct {
+	mu       sync.RWMutex
+	docs     map[string]map[uint][]string       // 文档类型 -> 文档ID -> 分词结果
+	postings map[string]map[string]map[uint]int // 文档类型 -> 词 -> 文档ID -> 词频
+}
+
+func NewMemoryIndex() *MemoryIndex {
+	return &MemoryIndex{
+		docs:     make(map[string]map[uint][]string),
+		postings: make(map[string]map[string]map[uint]int),
+	}
+}
+
+func (idx *MemoryIndex) Put(docType string, id uint, text string) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.remove(docType, id)
+	tokens := Analyze(text)
+	if len(tokens) == 0 {
+		return nil
+	}
+	if idx.docs[docType] == nil {
+		idx.docs[docType] = make(map[uint][]string)
+		idx.postings[docType] = make(map[string]map[uint]int)
+	}
+	idx.docs[docType][id] = tokens
+	for _, token := range tokens {
+		if idx.postings[docType][token] == nil {
+			idx.postings[docType][token] = make(map[uint]int)
+		}
+		idx.postings[docType][token][id]++
+	}
+	return nil
+}
+
+func (idx *MemoryIndex) Delete(docType string, id uint) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.remove(docType, id)
+	return nil
+}
+
+// remove 删除文档，调用前需要持有写锁
+func (idx *MemoryIndex) remove(docType string, id uint) {
+	tokens, ok := idx.docs[docType][id]
+	if !ok {
+		return
+	}
+	for _, token := range tokens {
+		delete(idx.postings[docType][token], id)
+		if len(idx.postings[docType][token]) == 0 {
+			delete(idx.postings[docType], token)
+		}
+	}
+	delete(idx.docs[docType], id)
+}
+
+// Search 用 TF-IDF 计算相关度，词频按文档长度归一化，返回相关度最高的 limit 个文档
+func (idx *MemoryIndex) Search(docType string, query string, limit int) ([]Hit, error) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	total := float64(len(idx.docs[docType]))
+	scores := make(map[uint]float64)
+	for _, token := range Analyze(query) {
+		posting := idx.postings[docType][token]
+		if len(posting) == 0 {
+			continue
+		}
+		idf := math.Log(1 + total/float64(len(posting)))
+		for id, tf := range posting {
+			scores[id] += float64(tf) / math.Sqrt(float64(len(idx.docs[docType][id]))) * idf
+		}
+	}
+
+	hits := make([]Hit, 0, len(scores))
+	for id, score := range scores {
+		hits = append(hits, Hit{ID: id, Score: score})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ID > hits[j].ID
+	})
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits, nil
+}
@@ -0,0 +1,50 @@
+package search
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// 测试分词
+func TestAnalyze(t *testing.T) {
+	// 英文按单词切分并转为小写
+	assert.Equal(t, []string{"hello", "world2"}, Analyze("Hello, World2!"))
+	// 中文按单字和相邻两字切分
+	assert.Equal(t, []string{"美", "美食", "食"}, Analyze("美食"))
+	// 中英文混合
+	assert.Equal(t, []string{"vlog", "旅", "旅行", "行"}, Analyze("vlog旅行"))
+}
+
+// 测试内存索引的增删查
+func TestMemoryIndex(t *testing.T) {
+	idx := NewMemoryIndex()
+	_ = idx.Put(TypeVideo, 1, "家常菜做法 #美食")
+	_ = idx.Put(TypeVideo, 2, "周末去旅行")
+	_ = idx.Put(TypeVideo, 3, "美食探店")
+	_ = idx.Put(TypeUser, 1, "foodie_jordan")
+
+	// 不同类型的文档互不影响
+	hits, _ := idx.Search(TypeVideo, "美食", 10)
+	assert.Equal(t, 2, len(hits))
+	hits, _ = idx.Search(TypeUser, "美食", 10)
+	assert.Empty(t, hits)
+
+	// 更短的文档相关度更高
+	hits, _ = idx.Search(TypeVideo, "美食", 10)
+	assert.Equal(t, uint(3), hits[0].ID)
+
+	// 更新文档后旧的内容不再被搜索到
+	_ = idx.Put(TypeVideo, 3, "周末探店")
+	hits, _ = idx.Search(TypeVideo, "美食", 10)
+	assert.Equal(t, 1, len(hits))
+	assert.Equal(t, uint(1), hits[0].ID)
+
+	// 删除文档
+	_ = idx.Delete(TypeVideo, 2)
+	hits, _ = idx.Search(TypeVideo, "旅行", 10)
+	assert.Empty(t, hits)
+
+	// limit 限制返回数量
+	hits, _ = idx.Search(TypeVideo, "周末 家常菜", 1)
+	assert.Equal(t, 1, len(hits))
+}
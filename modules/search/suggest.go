@@ -0,0 +1,177 @@
+package search
+
+import (
+	"app/consts"
+	"app/modules/filter"
+	"app/modules/models"
+	"gorm.io/gorm"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// NormalizeQuery 统一搜索词的格式：去掉首尾空白，合并连续空白，英文转为小写
+func NormalizeQuery(query string) string {
+	return strings.ToLower(strings.Join(strings.Fields(query), " "))
+}
+
+type suggestion struct {
+	Text   string
+	Weight int64
+}
+
+type trieNode struct {
+	children map[rune]*trieNode
+	top      []suggestion // 以该节点为前缀、权重最高的若干个候选词
+}
+
+// Trie 前缀树，每个节点预先保存权重最高的 k 个候选词，查询时只需要走到前缀对应的节点
+type Trie struct {
+	root *trieNode
+	k    int
+}
+
+// BuildTrie 用候选词及其权重构建前缀树，候选词按小写匹配
+func BuildTrie(entries map[string]int64, k int) *Trie {
+	t := &Trie{root: &trieNode{children: make(map[rune]*trieNode)}, k: k}
+	for text, weight := range entries {
+		s := suggestion{Text: text, Weight: weight}
+		node := t.root
+		depth := 0
+		for _, r := range strings.ToLower(text) {
+			if depth >= consts.MaxSuggestPrefixLength {
+				break
+			}
+			child, ok := node.children[r]
+			if !ok {
+				child = &trieNode{children: make(map[rune]*trieNode)}
+				node.children[r] = child
+			}
+			node = child
+			node.top = insertTop(node.top, s, k)
+			depth++
+		}
+	}
+	return t
+}
+
+// insertTop 将候选词插入有序的 top 列表，保留权重最高的 k 个
+func insertTop(top []suggestion, s suggestion, k int) []suggestion {
+	i := sort.Search(len(top), func(i int) bool {
+		if top[i].Weight != s.Weight {
+			return top[i].Weight < s.Weight
+		}
+		return top[i].Text > s.Text
+	})
+	if i >= k {
+		return top
+	}
+	top = append(top, suggestion{})
+	copy(top[i+1:], top[i:])
+	top[i] = s
+	if len(top) > k {
+		top = top[:k]
+	}
+	return top
+}
+
+// Suggest 返回以 prefix 开头的候选词，按权重从高到低排列
+func (t *Trie) Suggest(prefix string) []string {
+	node := t.root
+	for _, r := range strings.ToLower(prefix) {
+		child, ok := node.children[r]
+		if !ok {
+			return nil
+		}
+		node = child
+	}
+	var texts []string
+	for _, s := range node.top {
+		texts = append(texts, s.Text)
+	}
+	return texts
+}
+
+var (
+	suggestMu   sync.RWMutex
+	suggestTrie = BuildTrie(nil, consts.MaxSuggestions)
+)
+
+// RefreshSuggestions 用热门搜索词、用户名和视频标题重新构建联想词前缀树。
+// 热门搜索词的权重为搜索次数，用户名为粉丝数，视频标题为获赞数
+func RefreshSuggestions(db *gorm.DB) error {
+	entries := make(map[string]int64)
+	add := func(text string, weight int64) {
+		text = strings.TrimSpace(text)
+		if len(text) == 0 || utf8.RuneCountInString(text) > consts.MaxSearchQueryLength {
+			return
+		}
+		// 词库更新后，之前记录的热门搜索词和已有的用户名、标题也可能含有敏感词
+		if len(filter.Check(text).Matches) > 0 {
+			return
+		}
+		entries[text] += weight + 1
+	}
+
+	for _, hot := range DefaultHot.Top(consts.MaxSuggestSourceCount, time.Now()) {
+		add(hot.Query, hot.Count)
+	}
+
+	var users []struct {
+		Username      string
+		FollowerCount int64
+	}
+	if err := db.Table("users").
+		Select("users.username, user_profiles.follower_count").
+		Joins("JOIN user_profiles ON user_profiles.user_id = users.id").
+		Where("users.deleted_at IS NULL").
+		Order("user_profiles.follower_count desc").
+		Limit(consts.MaxSuggestSourceCount).Scan(&users).Error; err != nil {
+		return err
+	}
+	for _, u := range users {
+		add(u.Username, u.FollowerCount)
+	}
+
+	var videos []models.Video
+	if err := db.Select("title", "favorite_count").
+		Order("favorite_count desc").
+		Limit(consts.MaxSuggestSourceCount).Find(&videos).Error; err != nil {
+		return err
+	}
+	for _, v := range videos {
+		add(v.Title, int64(v.FavoriteCount))
+	}
+
+	trie := BuildTrie(entries, consts.MaxSuggestions)
+	suggestMu.Lock()
+	suggestTrie = trie
+	suggestMu.Unlock()
+	return nil
+}
+
+// StartSuggestRefresher 启动后台任务，定期刷新联想词
+func StartSuggestRefresher(db *gorm.DB, interval time.Duration) {
+	if err := RefreshSuggestions(db); err != nil {
+		log.Printf("Failed to refresh search suggestions. Err: %s", err)
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := RefreshSuggestions(db); err != nil {
+				log.Printf("Failed to refresh search suggestions. Err: %s", err)
+			}
+		}
+	}()
+}
+
+// Suggestions 从当前的前缀树中查询联想词
+func Suggestions(prefix string) []string {
+	suggestMu.RLock()
+	defer suggestMu.RUnlock()
+	return suggestTrie.Suggest(prefix)
+}
@@ -0,0 +1,50 @@
+package search
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// 测试联想词前缀树
+func TestTrie(t *testing.T) {
+	trie := BuildTrie(map[string]int64{
+		"Jordan":     10,
+		"jordan_fan": 30,
+		"jojo":       20,
+		"美食探店":       5,
+		"美食教程":       8,
+	}, 2)
+
+	// 按权重排序，只保留前 k 个，大小写不敏感
+	assert.Equal(t, []string{"jordan_fan", "jojo"}, trie.Suggest("J"))
+	assert.Equal(t, []string{"jordan_fan", "Jordan"}, trie.Suggest("jor"))
+	// 中文前缀
+	assert.Equal(t, []string{"美食教程", "美食探店"}, trie.Suggest("美食"))
+	// 没有匹配的前缀
+	assert.Empty(t, trie.Suggest("xyz"))
+}
+
+// 测试热门搜索的滑动窗口
+func TestHotTracker(t *testing.T) {
+	h := NewHotTracker(3*time.Hour, time.Hour)
+	now := time.Date(2023, 9, 1, 12, 0, 0, 0, time.UTC)
+
+	h.Record("Golang", now.Add(-2*time.Hour))
+	h.Record("golang ", now.Add(-2*time.Hour))
+	h.Record("旅行", now.Add(-time.Hour))
+	h.Record("旅行", now)
+	h.Record("旅行", now)
+
+	// 搜索词统一格式后合并计数
+	top := h.Top(10, now)
+	assert.Equal(t, []HotQuery{{Query: "旅行", Count: 3}, {Query: "golang", Count: 2}}, top)
+
+	// 超出时间窗口的搜索不再计入
+	top = h.Top(10, now.Add(time.Hour))
+	assert.Equal(t, []HotQuery{{Query: "旅行", Count: 3}}, top)
+
+	// 限制返回数量
+	top = h.Top(1, now)
+	assert.Equal(t, 1, len(top))
+}
@@ -0,0 +1,105 @@
+package search
+
+import (
+	"app/modules/models"
+	"gorm.io/gorm"
+	"log"
+)
+
+// DefaultIndex 全局使用的搜索索引
+var DefaultIndex Index = NewMemoryIndex()
+
+// SetIndex 替换全局使用的搜索索引
+func SetIndex(idx Index) {
+	DefaultIndex = idx
+}
+
+// Rebuild 服务启动时从数据库加载全部视频、用户和话题到索引中
+func Rebuild(db *gorm.DB) error {
+	var videos []models.Video
+	if err := db.Select("id", "title").FindInBatches(&videos, 500, func(tx *gorm.DB, batch int) error {
+		for _, v := range videos {
+			if err := DefaultIndex.Put(TypeVideo, v.ID, v.Title); err != nil {
+				return err
+			}
+		}
+		return nil
+	}).Error; err != nil {
+		return err
+	}
+
+	var users []models.User
+	if err := db.Select("id", "username").FindInBatches(&users, 500, func(tx *gorm.DB, batch int) error {
+		for _, u := range users {
+			if err := DefaultIndex.Put(TypeUser, u.ID, u.Username); err != nil {
+				return err
+			}
+		}
+		return nil
+	}).Error; err != nil {
+		return err
+	}
+
+	var tags []models.Tag
+	return db.Where("video_count > 0").FindInBatches(&tags, 500, func(tx *gorm.DB, batch int) error {
+		for _, t := range tags {
+			if err := DefaultIndex.Put(TypeTag, t.ID, t.Name); err != nil {
+				return err
+			}
+		}
+		return nil
+	}).Error
+}
+
+// SyncVideo 视频发布/编辑后更新索引，tagIds 为这次变化涉及的话题。
+// 需要在数据库事务提交之后调用，索引失败只记录日志，不影响请求
+func SyncVideo(db *gorm.DB, videoId uint, tagIds []uint) {
+	var video models.Video
+	if err := db.First(&video, videoId).Error; err != nil {
+		log.Printf("Failed to load video %d for search index. Err: %s", videoId, err)
+		return
+	}
+	if err := DefaultIndex.Put(TypeVideo, video.ID, video.Title); err != nil {
+		log.Printf("Failed to index video %d. Err: %s", videoId, err)
+	}
+	SyncTags(db, tagIds)
+}
+
+// RemoveVideo 视频删除后从索引中移除
+func RemoveVideo(db *gorm.DB, videoId uint, tagIds []uint) {
+	if err := DefaultIndex.Delete(TypeVideo, videoId); err != nil {
+		log.Printf("Failed to remove video %d from search index. Err: %s", videoId, err)
+	}
+	SyncTags(db, tagIds)
+}
+
+// SyncUser 用户注册/改名后更新索引
+func SyncUser(db *gorm.DB, userId uint) {
+	var user models.User
+	if err := db.First(&user, userId).Error; err != nil {
+		log.Printf("Failed to load user %d for search index. Err: %s", userId, err)
+		return
+	}
+	if err := DefaultIndex.Put(TypeUser, user.ID, user.Username); err != nil {
+		log.Printf("Failed to index user %d. Err: %s", userId, err)
+	}
+}
+
+// SyncTags 更新话题的索引，已经没有视频使用的话题从索引中移除
+func SyncTags(db *gorm.DB, tagIds []uint) {
+	if len(tagIds) == 0 {
+		return
+	}
+	var tags []models.Tag
+	if err := db.Where("id IN ?", tagIds).Find(&tags).Error; err != nil {
+		log.Printf("Failed to load tags for search index. Err: %s", err)
+		return
+	}
+	for _, t := range tags {
+		if t.VideoCount > 0 {
+			_ = DefaultIndex.Put(TypeTag, t.ID, t.Name)
+		} else {
+			_ = DefaultIndex.Delete(TypeTag, t.ID)
+		}
+	}
+}
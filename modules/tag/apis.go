@@ -0,0 +1,206 @@
+package tag
+
+import (
+	"app/consts"
+	"app/modules/models"
+	"app/utils"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SyncTags 根据视频标题同步视频的话题：新增标题中出现的话题，删除标题中已经没有的话题。
+// 需要在创建/编辑视频的同一个事务中调用，返回新增和删除的话题ID
+func SyncTags(tx *gorm.DB, videoId uint, title string) ([]uint, error) {
+	names := utils.ParseHashtags(title, consts.MaxTagsPerVideo, consts.MaxTagLength)
+	wanted := make(map[string]bool)
+	for _, name := range names {
+		wanted[name] = true
+	}
+
+	// 查询视频当前的话题
+	var current []models.VideoTag
+	if err := tx.Preload("Tag").Where("video_id = ?", videoId).Find(&current).Error; err != nil {
+		return nil, err
+	}
+	var changed []uint
+	existing := make(map[string]bool)
+	for _, vt := range current {
+		if wanted[vt.Tag.Name] {
+			existing[vt.Tag.Name] = true
+			continue
+		}
+		// 标题中已经没有这个话题了，逐条删除以触发 AfterDelete 更新话题的视频数
+		vt := vt
+		if err := tx.Delete(&vt).Error; err != nil {
+			return nil, err
+		}
+		changed = append(changed, vt.TagID)
+	}
+
+	for _, name := range names {
+		if existing[name] {
+			continue
+		}
+		// 话题不存在时创建，已存在则直接使用
+		tag := models.Tag{Name: name}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&tag).Error; err != nil {
+			return nil, err
+		}
+		if err := tx.Where("name = ?", name).First(&tag).Error; err != nil {
+			return nil, err
+		}
+		if err := tx.Create(&models.VideoTag{VideoID: videoId, TagID: tag.ID}).Error; err != nil {
+			return nil, err
+		}
+		changed = append(changed, tag.ID)
+	}
+	return changed, nil
+}
+
+// GetTag 话题页，返回话题的信息（视频数、总获赞数）以及早于 latest_time 发布的视频
+func GetTag(c *gin.Context) {
+	name := strings.ToLower(strings.TrimPrefix(c.DefaultQuery("name", ""), "#"))
+	if len(name) == 0 {
+		c.JSON(http.StatusBadRequest, utils.TagResponse{
+			StatusCode: 1,
+			StatusMsg:  "Tag name is required.",
+		})
+		return
+	}
+
+	latestTimeString := c.DefaultQuery("latest_time", "")
+	if latestTimeString == "" {
+		// 将当前时间转换为毫秒单位的Unix时间戳
+		latestTimeString = fmt.Sprintf("%d", time.Now().UnixMilli())
+	}
+	unixTimeMs, err := strconv.ParseInt(latestTimeString, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.TagResponse{
+			StatusCode: 1,
+			StatusMsg:  "Invalid latest_time format. Expected Unix timestamp in milliseconds.",
+		})
+		return
+	}
+	latestTime := time.UnixMilli(unixTimeMs)
+
+	db := c.MustGet("db").(*gorm.DB)
+
+	// 查询话题
+	var tag models.Tag
+	if err := db.Where("name = ?", name).First(&tag).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, utils.TagResponse{
+				StatusCode: 1,
+				StatusMsg:  "Tag not found.",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, utils.TagResponse{
+			StatusCode: 1,
+			StatusMsg:  "Failed to fetch tag.",
+		})
+		return
+	}
+
+	// 统计话题下未删除的视频数和总获赞数
+	var stats struct {
+		VideoCount     int64
+		TotalFavorited int64
+	}
+	if err := db.Table("video_tags").
+		Select("COUNT(*) AS video_count, COALESCE(SUM(videos.favorite_count), 0) AS total_favorited").
+		Joins("JOIN videos ON videos.id = video_tags.video_id AND videos.deleted_at IS NULL").
+		Where("video_tags.tag_id = ?", tag.ID).
+		Scan(&stats).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.TagResponse{
+			StatusCode: 1,
+			StatusMsg:  "Failed to fetch tag.",
+		})
+		return
+	}
+
+	// 查询话题下的视频
+	var videos []models.Video
+	if err := db.Preload("User").Preload("User.Profile").
+		Joins("JOIN video_tags ON video_tags.video_id = videos.id").
+		Where("video_tags.tag_id = ? AND videos.publish_time < ?", tag.ID, latestTime).
+		Order("videos.publish_time desc").
+		Limit(consts.MaxVideos).Find(&videos).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.TagResponse{
+			StatusCode: 1,
+			StatusMsg:  "Failed to fetch videos.",
+		})
+		return
+	}
+
+	tokenString := c.DefaultQuery("token", "")
+	currentUserId, _ := utils.ValidateToken(tokenString)
+
+	// 计算nextTime
+	var nextTime int64
+	if len(videos) > 0 {
+		nextTime = videos[len(videos)-1].PublishTime.UnixMilli()
+	}
+
+	c.JSON(http.StatusOK, utils.TagResponse{
+		StatusCode: 0,
+		StatusMsg:  "Success",
+		Tag: &utils.TagResItem{
+			ID:             tag.ID,
+			Name:           tag.Name,
+			VideoCount:     stats.VideoCount,
+			TotalFavorited: stats.TotalFavorited,
+		},
+		NextTime:  nextTime,
+		VideoList: utils.BuildVideoResList(db, videos, currentUserId),
+	})
+}
+
+// Suggest 话题联想，返回以 prefix 开头的话题，按使用次数排序
+func Suggest(c *gin.Context) {
+	prefix := strings.ToLower(strings.TrimPrefix(c.DefaultQuery("prefix", ""), "#"))
+	if len(prefix) == 0 {
+		c.JSON(http.StatusBadRequest, utils.TagSuggestResponse{
+			StatusCode: 1,
+			StatusMsg:  "Prefix is required.",
+		})
+		return
+	}
+
+	// 转义 LIKE 中的通配符
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(prefix)
+
+	var tags []models.Tag
+	db := c.MustGet("db").(*gorm.DB)
+	if err := db.Where("name LIKE ? AND video_count > 0", escaped+"%").
+		Order("video_count desc").Order("id").
+		Limit(consts.MaxTagSuggestions).Find(&tags).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.TagSuggestResponse{
+			StatusCode: 1,
+			StatusMsg:  "Failed to fetch tags.",
+		})
+		return
+	}
+
+	var tagList []utils.TagResItem
+	for _, t := range tags {
+		tagList = append(tagList, utils.TagResItem{
+			ID:         t.ID,
+			Name:       t.Name,
+			VideoCount: int64(t.VideoCount),
+		})
+	}
+
+	c.JSON(http.StatusOK, utils.TagSuggestResponse{
+		StatusCode: 0,
+		StatusMsg:  "Success",
+		TagList:    tagList,
+	})
+}
@@ -1,7 +1,10 @@
 package user
 
 import (
+	"app/consts"
+	"app/modules/filter"
 	"app/modules/models"
+	"app/modules/search"
 	"app/utils"
 	"errors"
 	"fmt"
@@ -9,8 +12,16 @@ import (
 	"gorm.io/gorm"
 	"net/http"
 	"strconv"
+	"strings"
+	"unicode/utf8"
 )
 
+// allowedUsername 检测用户名中的敏感词，用户名公开可见，命中任何敏感词都不允许使用
+func allowedUsername(db *gorm.DB, userId uint, username string) bool {
+	filtered, verdict := filter.Apply(db, userId, filter.SourceUsername, username)
+	return verdict != filter.Reject && filtered == username
+}
+
 // Register 处理用户注册的API请求
 func Register(c *gin.Context) {
 	var user models.User
@@ -28,8 +39,18 @@ func Register(c *gin.Context) {
 		return
 	}
 
-	// 使用GORM将用户数据存储到数据库中
 	db := c.MustGet("db").(*gorm.DB)
+	// 用户名是登录凭证，含有敏感词时直接拒绝而不是打码
+	if !allowedUsername(db, 0, user.Username) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status_code": 1,
+			"status_msg":  "Username contains sensitive words.",
+			"user_id":     nil,
+		})
+		return
+	}
+
+	// 使用GORM将用户数据存储到数据库中
 	if err := db.Create(&user).Error; err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"status_code": 1,
@@ -40,6 +61,8 @@ func Register(c *gin.Context) {
 		return
 	}
 
+	search.SyncUser(db, user.ID)
+
 	// 生成新Token
 	newToken, err := utils.GenerateToken(user.ID)
 	if err != nil {
@@ -116,6 +139,7 @@ func GetUser(c *gin.Context) {
 		"total_favorited":  user.Profile.TotalFavorited,
 		"work_count":       user.Profile.WorkCount,
 		"favorite_count":   user.Profile.FavoriteCount,
+		"friend_count":     user.Profile.FriendCount,
 	}
 
 	fmt.Println(http.StatusOK, userResponse)
@@ -186,3 +210,132 @@ func Login(c *gin.Context) {
 	})
 	fmt.Println(http.StatusOK, "Logged in successfully.")
 }
+
+// Rename 修改当前登录用户的用户名
+func Rename(c *gin.Context) {
+	tokenString := c.DefaultQuery("token", "")
+	userId, err := utils.ValidateToken(tokenString)
+	if err != nil || userId <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status_code": 1,
+			"status_msg":  "Invalid user ID.",
+		})
+		return
+	}
+
+	username := c.Query("username")
+	if len(username) < 6 || len(username) > 25 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status_code": 1,
+			"status_msg":  "Username should be non-empty and between 6 - 25 characters.",
+		})
+		fmt.Println(http.StatusBadRequest, "Username should be non-empty and between 6 - 25 characters.")
+		return
+	}
+
+	db := c.MustGet("db").(*gorm.DB)
+	if !allowedUsername(db, userId, username) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status_code": 1,
+			"status_msg":  "Username contains sensitive words.",
+		})
+		return
+	}
+	// 验证用户名是否已被其他用户占用
+	var count int64
+	db.Model(&models.User{}).Where("username = ? AND id <> ?", username, userId).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status_code": 1,
+			"status_msg":  "Username is already taken.",
+		})
+		return
+	}
+
+	if err := db.Model(&models.User{}).Where("id = ?", userId).Update("username", username).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status_code": 1,
+			"status_msg":  "Failed to rename.",
+		})
+		fmt.Println(http.StatusInternalServerError, "Failed to rename.")
+		return
+	}
+	search.SyncUser(db, userId)
+
+	c.JSON(http.StatusOK, gin.H{
+		"status_code": 0,
+		"status_msg":  "Success",
+	})
+}
+
+// updateSignature 修改用户的个人简介
+func updateSignature(db *gorm.DB, userId uint, signature string) error {
+	return db.Model(&models.UserProfile{}).Where("user_id = ?", userId).
+		Update("signature", signature).Error
+}
+
+// PublishReviewed 应用审核通过的个人简介
+func PublishReviewed(db *gorm.DB, review models.ContentReview) error {
+	return updateSignature(db, review.UserID, review.Content)
+}
+
+// UpdateSignature 修改个人简介
+func UpdateSignature(c *gin.Context) {
+	tokenString := c.DefaultQuery("token", "")
+	userId, err := utils.ValidateToken(tokenString)
+	if err != nil || userId <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status_code": 1,
+			"status_msg":  "Invalid user ID.",
+		})
+		return
+	}
+
+	signature := strings.TrimSpace(c.Query("signature"))
+	if utf8.RuneCountInString(signature) > consts.MaxSignatureLength {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status_code": 1,
+			"status_msg":  "Signature is too long.",
+		})
+		return
+	}
+
+	db := c.MustGet("db").(*gorm.DB)
+	// 敏感词过滤
+	signature, verdict := filter.Apply(db, userId, filter.SourceSignature, signature)
+	if verdict == filter.Reject {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status_code": 1,
+			"status_msg":  "Signature contains sensitive words.",
+		})
+		return
+	}
+	// 需要人工审核的签名先加入审核队列，审核通过之前继续使用原来的签名
+	if verdict == filter.Hold {
+		if err := filter.Enqueue(db, userId, filter.SourceSignature, 0, 0, signature); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"status_code": 1,
+				"status_msg":  "Failed to update signature.",
+			})
+			return
+		}
+		c.JSON(http.StatusAccepted, gin.H{
+			"status_code": 0,
+			"status_msg":  "Signature is pending review.",
+		})
+		return
+	}
+
+	if err := updateSignature(db, userId, signature); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status_code": 1,
+			"status_msg":  "Failed to update signature.",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status_code": 0,
+		"status_msg":  "Success",
+	})
+}
@@ -3,7 +3,12 @@ package video
 import (
 	"app/config"
 	"app/consts"
+	"app/modules/filter"
+	"app/modules/mention"
 	"app/modules/models"
+	"app/modules/policy"
+	"app/modules/search"
+	"app/modules/tag"
 	"app/utils"
 	"bytes"
 	"fmt"
@@ -19,6 +24,7 @@ import (
 	"os"
 	"strconv"
 	"time"
+	"unicode/utf8"
 )
 
 // GetFeed 视频流接口，返回早于latest_time发布的MaxVideos个视频
@@ -42,10 +48,17 @@ func GetFeed(c *gin.Context) {
 	// 将毫秒单位的Unix时间戳转换为time.Time对象
 	latestTime := time.Unix(0, unixTimeMs*1e6)
 
-	// 找出所有发布时间早于latestTime的视频
+	// 检查当前登录状态
+	tokenString := c.DefaultQuery("token", "")
+	userId, _ := utils.ValidateToken(tokenString)
+
+	// 找出所有发布时间早于latestTime的视频，并过滤掉用户负反馈过的视频、有拉黑关系的发布者的视频、
+	// 没有关注的私密账号的视频和用户静音了的发布者的视频
 	var videos []models.Video
 	db := c.MustGet("db").(*gorm.DB)
 	err = db.Preload("User").Preload("User.Profile").
+		Scopes(FeedbackFilter(userId), policy.NotBlocked("videos.user_id", userId),
+			policy.VisibleVideos("videos.user_id", userId), policy.NotMuted("videos.user_id", userId)).
 		Where("publish_time < ?", latestTime).Order("publish_time desc").
 		Limit(consts.MaxVideos).Find(&videos).Error
 	if err != nil {
@@ -56,74 +69,7 @@ func GetFeed(c *gin.Context) {
 		return
 	}
 
-	// 检查当前登录状态
-	tokenString := c.DefaultQuery("token", "")
-	userId, _ := utils.ValidateToken(tokenString)
-	isLoggedIn := userId > 0
-
-	// 如果当前已登录，我们需要：1. 知道返回的MaxVideos个视频中哪些被用户已经点赞过
-	// 2. 知道其中哪些视频发布者是当前登录用户关注的
-	var likedVideoIdSet = make(map[uint]bool)
-	var followedVideoCreatorIdSet = make(map[uint]bool)
-	if isLoggedIn == true {
-		// 生成视频 ID 列表和视频发布者 ID 列表
-		var videoIds []uint
-		var creatorIds []uint
-		for _, video := range videos {
-			videoIds = append(videoIds, video.ID)
-			creatorIds = append(creatorIds, video.UserID)
-		}
-		// 查询 favorites 表，看看哪些视频被用户点赞过
-		var likedVideoIds []uint
-		db.Table("favorites").
-			Where("user_id = ? AND video_id IN ?", userId, videoIds).
-			Pluck("video_id", &likedVideoIds)
-
-		// 查询 relations 表，看看当前用户关注了哪些视频发布者
-		var followedCreatorIds []uint
-		db.Table("relations").
-			Where("from_user_id = ? AND to_user_id IN ?", userId, creatorIds).
-			Pluck("to_user_id", &followedCreatorIds)
-
-		// 将视频 ID 放入哈希表
-		for _, id := range likedVideoIds {
-			likedVideoIdSet[id] = true
-		}
-
-		// 将被关注的视频发布者 ID 放入哈希表
-		for _, id := range followedCreatorIds {
-			followedVideoCreatorIdSet[id] = true
-		}
-	}
-
-	var videoResList []utils.VideoResItem
-	for _, v := range videos {
-		// 将查询的数据填充到返回的结构体中
-		_, isLiked := likedVideoIdSet[v.ID]
-		_, isFollowed := followedVideoCreatorIdSet[v.UserID]
-		videoResList = append(videoResList, utils.VideoResItem{
-			ID:            v.ID,
-			PlayUrl:       v.PlayUrl,
-			CoverUrl:      v.CoverUrl,
-			FavoriteCount: v.FavoriteCount,
-			CommentCount:  v.CommentCount,
-			Title:         v.Title,
-			IsFavorite:    isLiked,
-			Author: utils.UserResponse{
-				ID:             v.User.ID,
-				Name:           v.User.Username,
-				IsFollow:       isFollowed,
-				Avatar:         v.User.Profile.Avatar,
-				Background:     v.User.Profile.Background,
-				Signature:      v.User.Profile.Signature,
-				FollowCount:    v.User.Profile.FollowCount,
-				FollowerCount:  v.User.Profile.FollowerCount,
-				TotalFavorited: v.User.Profile.TotalFavorited,
-				WorkCount:      v.User.Profile.WorkCount,
-				FavoriteCount:  v.User.Profile.FavoriteCount,
-			},
-		})
-	}
+	videoResList := utils.BuildVideoResList(db, videos, userId)
 
 	// 计算nextTime
 	var nextTime int64
@@ -154,10 +100,35 @@ func GetUserVideos(c *gin.Context) {
 
 	db := c.MustGet("db").(*gorm.DB)
 
+	// 私密账号的投稿列表只对粉丝可见
+	tokenString := c.DefaultQuery("token", "")
+	currentUserId, _ := utils.ValidateToken(tokenString)
+	userIdInt, _ := strconv.Atoi(userId)
+	// 有拉黑关系时双方都不能查看对方的投稿列表
+	if currentUserId > 0 && !policy.CheckInteraction(c, db, currentUserId, uint(userIdInt),
+		"You can't view this user's videos.") {
+		return
+	}
+	visible, err := policy.CanViewVideos(db, currentUserId, uint(userIdInt))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.VideoResponse{
+			StatusCode: 1,
+			StatusMsg:  "Error fetching videos.",
+		})
+		return
+	}
+	if !visible {
+		c.JSON(http.StatusForbidden, utils.VideoResponse{
+			StatusCode: consts.StatusCodePrivate,
+			StatusMsg:  "This account is private.",
+		})
+		return
+	}
+
 	// 获取用户的投稿列表
 	var videos []models.Video
 
-	err := db.Preload("User").Preload("User.Profile").
+	err = db.Preload("User").Preload("User.Profile").
 		Where("user_id = ?", userId).Order("publish_time desc").
 		Find(&videos).Error
 	if err != nil {
@@ -168,57 +139,7 @@ func GetUserVideos(c *gin.Context) {
 		return
 	}
 
-	// 获取投稿视频的ID列表
-	var videoIds []uint
-	for _, v := range videos {
-		videoIds = append(videoIds, v.ID)
-	}
-
-	// 在这些视频ID中，查询哪些被当前用户点赞过
-	tokenString := c.DefaultQuery("token", "")
-	currentUserId, _ := utils.ValidateToken(tokenString)
-	var likedVideoIds []uint
-	db.Table("favorites").
-		Where("user_id = ? AND video_id IN (?)", currentUserId, videoIds).
-		Pluck("video_id", &likedVideoIds)
-
-	// 将这些ID放进哈希表，以便在O(1)时间内查询某个视频是否被自己点赞过
-	var likedVideoIdSet = make(map[uint]bool)
-	for _, id := range likedVideoIds {
-		likedVideoIdSet[id] = true
-	}
-
-	// 查询当前登录用户是否关注了列表的发布者
-	var relation models.Relation
-	result := db.Where("from_user_id = ? AND to_user_id = ?", currentUserId, userId).First(&relation)
-	isFollowed := result.RowsAffected > 0
-
-	var videoResList []utils.VideoResItem
-	for _, v := range videos {
-		_, isLiked := likedVideoIdSet[v.ID]
-		videoResList = append(videoResList, utils.VideoResItem{
-			ID:            v.ID,
-			PlayUrl:       v.PlayUrl,
-			CoverUrl:      v.CoverUrl,
-			FavoriteCount: v.FavoriteCount,
-			CommentCount:  v.CommentCount,
-			Title:         v.Title,
-			IsFavorite:    isLiked,
-			Author: utils.UserResponse{
-				ID:             v.User.ID,
-				Name:           v.User.Username,
-				IsFollow:       isFollowed,
-				Avatar:         v.User.Profile.Avatar,
-				Background:     v.User.Profile.Background,
-				Signature:      v.User.Profile.Signature,
-				FollowCount:    v.User.Profile.FollowCount,
-				FollowerCount:  v.User.Profile.FollowerCount,
-				TotalFavorited: v.User.Profile.TotalFavorited,
-				WorkCount:      v.User.Profile.WorkCount,
-				FavoriteCount:  v.User.Profile.FavoriteCount,
-			},
-		})
-	}
+	videoResList := utils.BuildVideoResList(db, videos, currentUserId)
 
 	c.JSON(http.StatusOK, utils.VideoResponse{
 		StatusCode: 0,
@@ -227,17 +148,54 @@ func GetUserVideos(c *gin.Context) {
 	})
 }
 
+// checkTitle 验证视频标题的长度，按字符而不是字节计算，验证失败时已经返回了错误信息
+func checkTitle(c *gin.Context, title string) bool {
+	if len(title) == 0 || utf8.RuneCountInString(title) > consts.MaxVideoTitleLength {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status_code": 1,
+			"status_msg":  fmt.Sprintf("Title is required and must be between 1 - %d characters.", consts.MaxVideoTitleLength),
+		})
+		return false
+	}
+	return true
+}
+
+// createVideo 创建视频记录，同时解析标题中的话题和@提及，二者在同一个事务中完成，之后同步搜索索引。
+// held 为 true 时标题需要人工审核，视频先以空标题发布，标题加入审核队列
+func createVideo(db *gorm.DB, video *models.Video, held bool) error {
+	title := video.Title
+	if held {
+		video.Title = ""
+	}
+	var tagIds []uint
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(video).Error; err != nil {
+			return err
+		}
+		if held {
+			return filter.Enqueue(tx, video.UserID, filter.SourceTitle, video.ID, 0, title)
+		}
+		var err error
+		tagIds, err = tag.SyncTags(tx, video.ID, video.Title)
+		if err != nil {
+			return err
+		}
+		return mention.Sync(tx, models.MentionTitle, video.ID, video.ID, video.UserID, video.Title)
+	})
+	if err != nil {
+		return err
+	}
+	search.SyncVideo(db, video.ID, tagIds)
+	return nil
+}
+
 // TODO: 将所有 ffmpeg 相关操作改为异步/消息队列来完成
 
 func Publish(c *gin.Context) {
 	// TODO: 将所有 ffmpeg 相关操作改为异步/消息队列来完成
 	// 验证视频标题
 	title := c.DefaultPostForm("title", "")
-	if len(title) == 0 || len(title) > 255 {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status_code": 1,
-			"status_msg":  "Title is required and must be between 0 - 255 characters.",
-		})
+	if !checkTitle(c, title) {
 		return
 	}
 
@@ -299,6 +257,16 @@ func Publish(c *gin.Context) {
 	tokenString := c.DefaultPostForm("token", "")
 	userId, _ := utils.ValidateToken(tokenString)
 
+	// 标题敏感词过滤，在上传文件之前完成
+	title, verdict := filter.Apply(c.MustGet("db").(*gorm.DB), userId, filter.SourceTitle, title)
+	if verdict == filter.Reject {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status_code": 1,
+			"status_msg":  "Title contains sensitive words.",
+		})
+		return
+	}
+
 	// 生成文件名
 	now := time.Now()
 	nowUnix := now.UnixMilli()
@@ -350,60 +318,56 @@ func Publish(c *gin.Context) {
 		coverKey,
 	)
 
-	// 更新 videos 表
-	videoRecord := models.Video{
-		UserID:      userId,
-		Title:       title,
-		PlayUrl:     videoUrl,
-		CoverUrl:    coverUrl,
-		PublishTime: now,
-	}
-	db := c.MustGet("db").(*gorm.DB)
-	tx := db.Create(&videoRecord)
-	if tx.Error != nil {
+	// 将视频和封面上传到S3
+	err = utils.UploadFileToS3(tempInputVideoPath, videoKey)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"status_code": 1,
-			"status_msg":  "Failed to create video record",
+			"status_msg":  err.Error(),
 		})
-		log.Printf("Failed to create db record. Err: %s", err)
 		return
 	}
 
-	// 将视频和封面上传到S3
-	err = utils.UploadFileToS3(tempInputVideoPath, videoKey)
+	err = utils.UploadFileToS3(tempCoverPath, coverKey)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"status_code": 1,
 			"status_msg":  err.Error(),
 		})
-		tx.Rollback()
 		return
 	}
 
-	err = utils.UploadFileToS3(tempCoverPath, coverKey)
-	if err != nil {
+	// 更新 videos 表
+	videoRecord := models.Video{
+		UserID:      userId,
+		Title:       title,
+		PlayUrl:     videoUrl,
+		CoverUrl:    coverUrl,
+		PublishTime: now,
+	}
+	if err := createVideo(c.MustGet("db").(*gorm.DB), &videoRecord, verdict == filter.Hold); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"status_code": 1,
-			"status_msg":  err.Error(),
+			"status_msg":  "Failed to create video record",
 		})
-		tx.Rollback()
+		log.Printf("Failed to create db record. Err: %s", err)
 		return
 	}
 
+	statusMsg := "Success"
+	if verdict == filter.Hold {
+		statusMsg = "Success. Title is pending review."
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"status_code": 0,
-		"status_msg":  "Success",
+		"status_msg":  statusMsg,
 	})
 }
 
 func PublishToMinIO(c *gin.Context) {
 	// 验证视频标题
 	title := c.DefaultPostForm("title", "")
-	if len(title) == 0 || len(title) > 255 {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status_code": 1,
-			"status_msg":  "Title is required and must be between 0 - 255 characters.",
-		})
+	if !checkTitle(c, title) {
 		return
 	}
 
@@ -465,6 +429,16 @@ func PublishToMinIO(c *gin.Context) {
 	tokenString := c.DefaultPostForm("token", "")
 	userId, _ := utils.ValidateToken(tokenString)
 
+	// 标题敏感词过滤，在上传文件之前完成
+	title, verdict := filter.Apply(c.MustGet("db").(*gorm.DB), userId, filter.SourceTitle, title)
+	if verdict == filter.Reject {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status_code": 1,
+			"status_msg":  "Title contains sensitive words.",
+		})
+		return
+	}
+
 	// 生成文件名
 	now := time.Now()
 	nowUnix := now.UnixMilli()
@@ -531,9 +505,7 @@ func PublishToMinIO(c *gin.Context) {
 		CoverUrl:    coverUrl,
 		PublishTime: now,
 	}
-	db := c.MustGet("db").(*gorm.DB)
-	tx := db.Create(&videoRecord)
-	if tx.Error != nil {
+	if err := createVideo(c.MustGet("db").(*gorm.DB), &videoRecord, verdict == filter.Hold); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"status_code": 1,
 			"status_msg":  "Failed to create video record",
@@ -542,6 +514,197 @@ func PublishToMinIO(c *gin.Context) {
 		return
 	}
 
+	statusMsg := "Success"
+	if verdict == filter.Hold {
+		statusMsg = "Success. Title is pending review."
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status_code": 0,
+		"status_msg":  statusMsg,
+	})
+}
+
+// updateTitle 修改视频标题，同时重新解析话题和@提及，之后同步搜索索引
+func updateTitle(db *gorm.DB, video models.Video, title string) error {
+	var tagIds []uint
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&video).Update("title", title).Error; err != nil {
+			return err
+		}
+		var err error
+		tagIds, err = tag.SyncTags(tx, video.ID, title)
+		if err != nil {
+			return err
+		}
+		return mention.Sync(tx, models.MentionTitle, video.ID, video.ID, video.UserID, title)
+	})
+	if err != nil {
+		return err
+	}
+	search.SyncVideo(db, video.ID, tagIds)
+	return nil
+}
+
+// PublishReviewed 应用审核通过的标题，视频在审核期间被删除时不再修改
+func PublishReviewed(db *gorm.DB, review models.ContentReview) error {
+	var video models.Video
+	if err := db.Where("user_id = ?", review.UserID).First(&video, review.TargetID).Error; err != nil {
+		return err
+	}
+	return updateTitle(db, video, review.Content)
+}
+
+// Edit 修改视频标题，并重新同步视频的话题
+func Edit(c *gin.Context) {
+	// 验证 user_id
+	tokenString := c.DefaultQuery("token", "")
+	userId, err := utils.ValidateToken(tokenString)
+	if err != nil || userId <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status_code": 1,
+			"status_msg":  "Invalid user ID.",
+		})
+		return
+	}
+
+	// 验证 video_id
+	videoIdInt, err := strconv.Atoi(c.DefaultQuery("video_id", "0"))
+	if err != nil || videoIdInt < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status_code": 1,
+			"status_msg":  "Invalid video_id.",
+		})
+		return
+	}
+
+	// 验证视频标题
+	title := c.DefaultQuery("title", "")
+	if !checkTitle(c, title) {
+		return
+	}
+
+	db := c.MustGet("db").(*gorm.DB)
+
+	// 验证视频是否存在
+	var video models.Video
+	if err := db.First(&video, videoIdInt).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status_code": 1,
+			"status_msg":  "Video not found.",
+		})
+		return
+	}
+	// 只有发布者可以修改视频
+	if video.UserID != userId {
+		c.JSON(http.StatusForbidden, gin.H{
+			"status_code": 1,
+			"status_msg":  "You do not have permission to edit this video.",
+		})
+		return
+	}
+
+	// 标题敏感词过滤
+	title, verdict := filter.Apply(db, userId, filter.SourceTitle, title)
+	if verdict == filter.Reject {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status_code": 1,
+			"status_msg":  "Title contains sensitive words.",
+		})
+		return
+	}
+	// 需要人工审核的标题先加入审核队列，审核通过之前继续使用原来的标题
+	if verdict == filter.Hold {
+		if err := filter.Enqueue(db, userId, filter.SourceTitle, video.ID, 0, title); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"status_code": 1,
+				"status_msg":  "Failed to edit video.",
+			})
+			return
+		}
+		c.JSON(http.StatusAccepted, gin.H{
+			"status_code": 0,
+			"status_msg":  "Title is pending review.",
+		})
+		return
+	}
+
+	if err := updateTitle(db, video, title); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status_code": 1,
+			"status_msg":  "Failed to edit video.",
+		})
+		log.Printf("Failed to edit video. Err: %s", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status_code": 0,
+		"status_msg":  "Success",
+	})
+}
+
+// Delete 删除视频，同时移除视频的话题
+func Delete(c *gin.Context) {
+	// 验证 user_id
+	tokenString := c.DefaultQuery("token", "")
+	userId, err := utils.ValidateToken(tokenString)
+	if err != nil || userId <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status_code": 1,
+			"status_msg":  "Invalid user ID.",
+		})
+		return
+	}
+
+	// 验证 video_id
+	videoIdInt, err := strconv.Atoi(c.DefaultQuery("video_id", "0"))
+	if err != nil || videoIdInt < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status_code": 1,
+			"status_msg":  "Invalid video_id.",
+		})
+		return
+	}
+
+	db := c.MustGet("db").(*gorm.DB)
+
+	// 验证视频是否存在
+	var video models.Video
+	if err := db.First(&video, videoIdInt).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status_code": 1,
+			"status_msg":  "Video not found.",
+		})
+		return
+	}
+	// 只有发布者可以删除视频
+	if video.UserID != userId {
+		c.JSON(http.StatusForbidden, gin.H{
+			"status_code": 1,
+			"status_msg":  "You do not have permission to delete this video.",
+		})
+		return
+	}
+
+	// 删除视频会触发 AfterDelete 更新发布者的作品数，空标题会移除视频的全部话题
+	var tagIds []uint
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&video).Error; err != nil {
+			return err
+		}
+		tagIds, err = tag.SyncTags(tx, video.ID, "")
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status_code": 1,
+			"status_msg":  "Failed to delete video.",
+		})
+		log.Printf("Failed to delete video. Err: %s", err)
+		return
+	}
+	search.RemoveVideo(db, video.ID, tagIds)
+
 	c.JSON(http.StatusOK, gin.H{
 		"status_code": 0,
 		"status_msg":  "Success",
@@ -0,0 +1,146 @@
+package video
+
+import (
+	"app/consts"
+	"app/modules/models"
+	"app/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// FeedbackFilter 视频流的负反馈过滤条件，作为 gorm 的 Scope 使用。
+// 过滤在 SQL 中完成，保证每页仍然能取满 MaxVideos 个视频。未登录时不做过滤
+func FeedbackFilter(userId uint) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if userId == 0 {
+			return db
+		}
+		return db.Where(`NOT EXISTS (SELECT 1 FROM feed_feedbacks f WHERE f.user_id = ? AND (
+			(f.reason = ? AND f.video_id = videos.id) OR
+			(f.reason = ? AND f.creator_id = videos.user_id) OR
+			(f.reason = ? AND INSTR(videos.title, f.keyword) > 0)))`,
+			userId, models.FeedbackVideo, models.FeedbackCreator, models.FeedbackKeyword)
+	}
+}
+
+// Feedback 视频流负反馈接口。reason 为 1 时需要 video_id（不感兴趣），
+// 为 2 时需要 to_user_id（不看该作者），为 3 时需要 keyword（屏蔽关键词）。
+// action_type 为 1 时添加反馈，为 2 时撤销反馈
+func Feedback(c *gin.Context) {
+	// 验证 user_id
+	tokenString := c.DefaultQuery("token", "")
+	userId, err := utils.ValidateToken(tokenString)
+	if err != nil || userId <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status_code": 1,
+			"status_msg":  "Invalid user ID.",
+		})
+		return
+	}
+
+	db := c.MustGet("db").(*gorm.DB)
+	feedback := models.FeedFeedback{UserID: userId}
+
+	// 根据 reason 验证对应的参数
+	switch c.DefaultQuery("reason", "") {
+	case "1": // 对这个视频不感兴趣
+		videoIdInt, err := strconv.Atoi(c.DefaultQuery("video_id", "0"))
+		if err != nil || videoIdInt < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"status_code": 1,
+				"status_msg":  "Invalid video_id.",
+			})
+			return
+		}
+		var video models.Video
+		if err := db.First(&video, videoIdInt).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"status_code": 1,
+				"status_msg":  "Video not found.",
+			})
+			return
+		}
+		feedback.Reason = models.FeedbackVideo
+		feedback.VideoID = video.ID
+	case "2": // 不看这个作者
+		toUserIdInt, err := strconv.Atoi(c.DefaultQuery("to_user_id", "0"))
+		if err != nil || toUserIdInt < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"status_code": 1,
+				"status_msg":  "Invalid to_user_id.",
+			})
+			return
+		}
+		if uint(toUserIdInt) == userId {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"status_code": 1,
+				"status_msg":  "You can't hide yourself.",
+			})
+			return
+		}
+		var toUser models.User
+		if err := db.First(&toUser, toUserIdInt).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"status_code": 1,
+				"status_msg":  "to_user_id not found.",
+			})
+			return
+		}
+		feedback.Reason = models.FeedbackCreator
+		feedback.CreatorID = toUser.ID
+	case "3": // 屏蔽标题关键词
+		keyword := strings.TrimSpace(c.DefaultQuery("keyword", ""))
+		if len(keyword) == 0 || utf8.RuneCountInString(keyword) > consts.MaxFeedbackKeywordLength {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"status_code": 1,
+				"status_msg":  "Keyword length must be between 1 and 20.",
+			})
+			return
+		}
+		feedback.Reason = models.FeedbackKeyword
+		feedback.Keyword = keyword
+	default: // 错误的 reason
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status_code": 1,
+			"status_msg":  "Invalid reason.",
+		})
+		return
+	}
+
+	switch c.DefaultQuery("action_type", "") {
+	case "1": // 添加反馈，重复添加不报错
+		if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&feedback).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"status_code": 1,
+				"status_msg":  "Failed to save feedback.",
+			})
+			return
+		}
+	case "2": // 撤销反馈
+		if err := db.Where("user_id = ? AND reason = ? AND video_id = ? AND creator_id = ? AND keyword = ?",
+			feedback.UserID, feedback.Reason, feedback.VideoID, feedback.CreatorID, feedback.Keyword).
+			Delete(&models.FeedFeedback{}).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"status_code": 1,
+				"status_msg":  "Failed to cancel feedback.",
+			})
+			return
+		}
+	default: // 错误的 action_type
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status_code": 1,
+			"status_msg":  "Invalid action_type.",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status_code": 0,
+		"status_msg":  "Success",
+	})
+}
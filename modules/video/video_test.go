@@ -0,0 +1,175 @@
+package video
+
+import (
+	"app/config"
+	"app/consts"
+	"app/modules/models"
+	"app/utils"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	utils.Setup()
+	postSetup()
+	code := m.Run()
+	utils.Teardown()
+	os.Exit(code)
+}
+
+var FeedUrl = "/douyin/feed/"
+var FeedbackUrl = "/douyin/feed/feedback/"
+var PublishListUrl = "/douyin/publish/list/"
+var EditUrl = "/douyin/publish/edit/"
+var db = utils.GetDb()
+var viewerId, creatorId, otherCreatorId uint
+var boringVideo, otherVideo, spoilerVideo, plainVideo models.Video
+
+func postSetup() {
+	var ids []uint
+	for _, name := range []string{"viewer_f", "creator_f", "other_creator_f"} {
+		user := models.User{Username: name, Password: name + "_pass"}
+		db.Create(&user)
+		ids = append(ids, user.ID)
+	}
+	viewerId, creatorId, otherCreatorId = ids[0], ids[1], ids[2]
+
+	publishTime := time.Now().Add(-time.Hour)
+	boringVideo = models.Video{UserID: creatorId, Title: "boring video", PublishTime: publishTime}
+	spoilerVideo = models.Video{UserID: creatorId, Title: "finale spoiler", PublishTime: publishTime.Add(time.Minute)}
+	plainVideo = models.Video{UserID: creatorId, Title: "plain video", PublishTime: publishTime.Add(2 * time.Minute)}
+	otherVideo = models.Video{UserID: otherCreatorId, Title: "other video", PublishTime: publishTime.Add(3 * time.Minute)}
+	for _, v := range []*models.Video{&boringVideo, &spoilerVideo, &plainVideo, &otherVideo} {
+		db.Create(v)
+	}
+
+	config.Router.GET(FeedUrl, GetFeed)
+	config.Router.POST(FeedbackUrl, Feedback)
+	config.Router.GET(PublishListUrl, GetUserVideos)
+	config.Router.POST(EditUrl, Edit)
+}
+
+// feedVideoIds 返回用户视频流中的视频ID
+func feedVideoIds(t *testing.T, userId uint) map[uint]bool {
+	token, _ := utils.GenerateToken(userId)
+	req, _ := http.NewRequest("GET", FeedUrl+"?"+url.Values{"token": {token}}.Encode(), nil)
+	response := httptest.NewRecorder()
+	config.Router.ServeHTTP(response, req)
+	assert.Equal(t, http.StatusOK, response.Code)
+
+	var resp utils.VideoResponse
+	if err := json.Unmarshal(response.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	ids := make(map[uint]bool)
+	for _, v := range resp.VideoList {
+		ids[v.ID] = true
+	}
+	return ids
+}
+
+// feedback 提交负反馈，values 为 reason 对应的参数
+func feedback(t *testing.T, userId uint, actionType string, values url.Values) {
+	token, _ := utils.GenerateToken(userId)
+	values.Set("token", token)
+	values.Set("action_type", actionType)
+	req, _ := http.NewRequest("POST", FeedbackUrl+"?"+values.Encode(), nil)
+	response := httptest.NewRecorder()
+	config.Router.ServeHTTP(response, req)
+	assert.Equal(t, http.StatusOK, response.Code)
+}
+
+// 测试不感兴趣的视频不再出现在视频流中，撤销后恢复
+func TestFeedbackVideo(t *testing.T) {
+	values := url.Values{"reason": {"1"}, "video_id": {strconv.Itoa(int(boringVideo.ID))}}
+	feedback(t, viewerId, "1", values)
+	assert.False(t, feedVideoIds(t, viewerId)[boringVideo.ID])
+	assert.True(t, feedVideoIds(t, viewerId)[plainVideo.ID])
+	assert.True(t, feedVideoIds(t, otherCreatorId)[boringVideo.ID])
+
+	feedback(t, viewerId, "2", values)
+	assert.True(t, feedVideoIds(t, viewerId)[boringVideo.ID])
+}
+
+// 测试不看该作者后视频流中不再出现该作者的任何视频
+func TestFeedbackCreator(t *testing.T) {
+	values := url.Values{"reason": {"2"}, "to_user_id": {strconv.Itoa(int(creatorId))}}
+	feedback(t, viewerId, "1", values)
+	ids := feedVideoIds(t, viewerId)
+	for _, v := range []models.Video{boringVideo, spoilerVideo, plainVideo} {
+		assert.False(t, ids[v.ID])
+	}
+	assert.True(t, ids[otherVideo.ID])
+
+	feedback(t, viewerId, "2", values)
+	assert.True(t, feedVideoIds(t, viewerId)[plainVideo.ID])
+}
+
+// 测试屏蔽关键词后标题含有关键词的视频不再出现在视频流中
+func TestFeedbackKeyword(t *testing.T) {
+	values := url.Values{"reason": {"3"}, "keyword": {"spoiler"}}
+	feedback(t, viewerId, "1", values)
+	ids := feedVideoIds(t, viewerId)
+	assert.False(t, ids[spoilerVideo.ID])
+	assert.True(t, ids[plainVideo.ID])
+
+	feedback(t, viewerId, "2", values)
+	assert.True(t, feedVideoIds(t, viewerId)[spoilerVideo.ID])
+}
+
+// 测试有拉黑关系时双方都不能查看对方的投稿列表
+func TestUserVideosBlocked(t *testing.T) {
+	var ids []uint
+	for _, name := range []string{"blocker_f", "blocked_f"} {
+		user := models.User{Username: name, Password: name + "_pass"}
+		db.Create(&user)
+		ids = append(ids, user.ID)
+	}
+	block := models.Block{UserID: ids[0], BlockedUserID: ids[1]}
+	db.Create(&block)
+
+	userVideos := func(viewerId, ownerId uint) int {
+		token, _ := utils.GenerateToken(viewerId)
+		values := url.Values{"token": {token}, "user_id": {strconv.Itoa(int(ownerId))}}
+		req, _ := http.NewRequest("GET", PublishListUrl+"?"+values.Encode(), nil)
+		response := httptest.NewRecorder()
+		config.Router.ServeHTTP(response, req)
+		return response.Code
+	}
+	assert.Equal(t, http.StatusForbidden, userVideos(ids[0], ids[1]))
+	assert.Equal(t, http.StatusForbidden, userVideos(ids[1], ids[0]))
+	assert.Equal(t, http.StatusOK, userVideos(ids[0], ids[0]))
+	assert.Equal(t, http.StatusOK, userVideos(viewerId, ids[0]))
+
+	db.Delete(&block)
+	assert.Equal(t, http.StatusOK, userVideos(ids[1], ids[0]))
+}
+
+// 测试标题长度按字符计算，中文标题不会因为字节数超过限制被拒绝
+func TestEditTitleLength(t *testing.T) {
+	video := models.Video{UserID: creatorId, Title: "edit video", PublishTime: time.Now()}
+	db.Create(&video)
+	edit := func(title string) int {
+		token, _ := utils.GenerateToken(creatorId)
+		values := url.Values{"token": {token}, "video_id": {strconv.Itoa(int(video.ID))}, "title": {title}}
+		req, _ := http.NewRequest("POST", EditUrl+"?"+values.Encode(), nil)
+		response := httptest.NewRecorder()
+		config.Router.ServeHTTP(response, req)
+		return response.Code
+	}
+
+	title := strings.Repeat("视", consts.MaxVideoTitleLength)
+	assert.Equal(t, http.StatusOK, edit(title))
+	db.First(&video, video.ID)
+	assert.Equal(t, title, video.Title)
+	assert.Equal(t, http.StatusBadRequest, edit(title+"频"))
+	assert.Equal(t, http.StatusBadRequest, edit(""))
+}
@@ -0,0 +1,30 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FormatTimeCursor 生成 "时间毫秒_ID" 格式的游标，用于按时间倒序、时间相同时按 ID 倒序的列表
+func FormatTimeCursor(t time.Time, id uint) string {
+	return fmt.Sprintf("%d_%d", t.UnixMilli(), id)
+}
+
+// ParseTimeCursor 解析 "时间毫秒_ID" 格式的游标
+func ParseTimeCursor(cursor string) (time.Time, uint, bool) {
+	parts := strings.SplitN(cursor, "_", 2)
+	if len(parts) != 2 {
+		return time.Time{}, 0, false
+	}
+	ms, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || ms < 0 {
+		return time.Time{}, 0, false
+	}
+	id, err := strconv.ParseUint(parts[1], 10, 64)
+	if err != nil {
+		return time.Time{}, 0, false
+	}
+	return time.UnixMilli(ms), uint(id), true
+}
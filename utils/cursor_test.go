@@ -0,0 +1,22 @@
+package utils
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// 测试 "时间毫秒_ID" 游标的生成和解析
+func TestTimeCursor(t *testing.T) {
+	cursorTime, cursorId, ok := ParseTimeCursor("1693918697123_42")
+	assert.True(t, ok)
+	assert.Equal(t, time.UnixMilli(1693918697123), cursorTime)
+	assert.Equal(t, uint(42), cursorId)
+	assert.Equal(t, "1693918697123_42", FormatTimeCursor(cursorTime, cursorId))
+
+	// 格式错误的游标
+	for _, cursor := range []string{"", "1693918697123", "abc_1", "1_abc", "-1_1", "1_-1"} {
+		_, _, ok = ParseTimeCursor(cursor)
+		assert.False(t, ok, cursor)
+	}
+}
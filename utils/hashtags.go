@@ -0,0 +1,29 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// hashtagPattern 匹配 #话题，话题名由字母（包括中日韩文字）、数字、下划线组成
+var hashtagPattern = regexp.MustCompile(`#([\p{L}\p{M}\p{N}_]+)`)
+
+// ParseHashtags 从标题中解析出去重后的话题名（英文统一转为小写），
+// 最多返回 maxCount 个，超过 maxLength 个字符的话题会被忽略
+func ParseHashtags(title string, maxCount, maxLength int) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, match := range hashtagPattern.FindAllStringSubmatch(title, -1) {
+		name := strings.ToLower(match[1])
+		if seen[name] || utf8.RuneCountInString(name) > maxLength {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+		if len(names) >= maxCount {
+			break
+		}
+	}
+	return names
+}
@@ -0,0 +1,29 @@
+package utils
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// 测试从标题中解析话题
+func TestParseHashtags(t *testing.T) {
+	// 英文话题统一为小写并去重，中文话题可以正常解析
+	names := ParseHashtags("今天做饭 #Cooking #美食 #cooking #家常菜_2023", 10, 64)
+	assert.Equal(t, []string{"cooking", "美食", "家常菜_2023"}, names)
+
+	// 标点符号会截断话题
+	names = ParseHashtags("#旅行，#summer!", 10, 64)
+	assert.Equal(t, []string{"旅行", "summer"}, names)
+
+	// 单独的 # 不是话题
+	names = ParseHashtags("# 没有话题 #", 10, 64)
+	assert.Empty(t, names)
+
+	// 超过数量上限的话题被忽略
+	names = ParseHashtags("#a #b #c", 2, 64)
+	assert.Equal(t, []string{"a", "b"}, names)
+
+	// 超过长度上限的话题被忽略
+	names = ParseHashtags("#abcdef #abc", 10, 5)
+	assert.Equal(t, []string{"abc"}, names)
+}
@@ -0,0 +1,70 @@
+package utils
+
+import (
+	"app/modules/models"
+	"gorm.io/gorm"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// mentionPattern 匹配 @用户名，用户名由字母（包括中日韩文字）、数字、下划线、连字符组成，
+// 中间可以有点号，结尾的点号视为标点
+var mentionPattern = regexp.MustCompile(`@([\p{L}\p{M}\p{N}_\-]+(?:\.[\p{L}\p{M}\p{N}_\-]+)*)`)
+
+// ParsedMention 从文本中解析出的 @用户名，Start 和 End 为字符（rune）下标
+type ParsedMention struct {
+	Username string
+	Start    int
+	End      int
+}
+
+// ParseMentions 从文本中解析出去重后的 @用户名（大小写不敏感，保留第一次出现的位置），
+// 最多返回 maxCount 个。紧跟在字母或数字后面的 @（例如邮箱地址）不算@
+func ParseMentions(text string, maxCount int) []ParsedMention {
+	var mentions []ParsedMention
+	seen := make(map[string]bool)
+	for _, loc := range mentionPattern.FindAllStringSubmatchIndex(text, -1) {
+		if loc[0] > 0 {
+			prev, _ := utf8.DecodeLastRuneInString(text[:loc[0]])
+			if unicode.IsLetter(prev) || unicode.IsDigit(prev) {
+				continue
+			}
+		}
+		username := text[loc[2]:loc[3]]
+		key := strings.ToLower(username)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		start := utf8.RuneCountInString(text[:loc[0]])
+		mentions = append(mentions, ParsedMention{
+			Username: username,
+			Start:    start,
+			End:      start + utf8.RuneCountInString(text[loc[0]:loc[1]]),
+		})
+		if len(mentions) >= maxCount {
+			break
+		}
+	}
+	return mentions
+}
+
+// MentionSpans 批量查询评论或视频标题中的@，按评论ID/视频ID分组
+func MentionSpans(db *gorm.DB, source int, targetIds []uint) map[uint][]MentionSpan {
+	spans := make(map[uint][]MentionSpan)
+	if len(targetIds) == 0 {
+		return spans
+	}
+	var mentions []models.Mention
+	db.Where("source = ? AND target_id IN ?", source, targetIds).Order("start").Find(&mentions)
+	for _, m := range mentions {
+		spans[m.TargetID] = append(spans[m.TargetID], MentionSpan{
+			UserID: m.UserID,
+			Start:  m.Start,
+			End:    m.End,
+		})
+	}
+	return spans
+}
@@ -0,0 +1,28 @@
+package utils
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// 测试从文本中解析@
+func TestParseMentions(t *testing.T) {
+	// 位置按字符计算，同一个用户大小写不敏感，只保留第一次出现
+	mentions := ParseMentions("你好 @alice_01 和 @小明同学，还有 @Alice_01", 10)
+	assert.Equal(t, []ParsedMention{
+		{Username: "alice_01", Start: 3, End: 12},
+		{Username: "小明同学", Start: 15, End: 20},
+	}, mentions)
+
+	// 结尾的点号是标点，中间的点号属于用户名
+	mentions = ParseMentions("cc @bob.smith.", 10)
+	assert.Equal(t, []ParsedMention{{Username: "bob.smith", Start: 3, End: 13}}, mentions)
+
+	// 邮箱地址和单独的 @ 不是@
+	assert.Empty(t, ParseMentions("mail me: me@example.com @ ", 10))
+
+	// 超过数量上限的@被忽略
+	mentions = ParseMentions("@a @b @c", 2)
+	assert.Len(t, mentions, 2)
+	assert.Equal(t, "b", mentions[1].Username)
+}
@@ -10,14 +10,113 @@ type VideoResponse struct {
 }
 
 type VideoResItem struct {
-	ID            uint         `json:"id"`
-	Author        UserResponse `json:"author"`
-	PlayUrl       string       `json:"play_url"`
-	CoverUrl      string       `json:"cover_url"`
-	FavoriteCount uint         `json:"favorite_count"`
-	CommentCount  uint         `json:"comment_count"`
-	IsFavorite    bool         `json:"is_favorite"`
-	Title         string       `json:"title"`
+	ID            uint          `json:"id"`
+	Author        UserResponse  `json:"author"`
+	PlayUrl       string        `json:"play_url"`
+	CoverUrl      string        `json:"cover_url"`
+	FavoriteCount uint          `json:"favorite_count"`
+	CommentCount  uint          `json:"comment_count"`
+	IsFavorite    bool          `json:"is_favorite"`
+	Title         string        `json:"title"`
+	CommentPolicy int           `json:"comment_policy"`
+	TitleMentions []MentionSpan `json:"title_mentions"`
+}
+
+type FavoriteListResponse struct {
+	StatusCode int            `json:"status_code"`
+	StatusMsg  string         `json:"status_msg"`
+	NextCursor string         `json:"next_cursor"`
+	HasMore    bool           `json:"has_more"`
+	VideoList  []VideoResItem `json:"video_list"`
+}
+
+type HistoryResponse struct {
+	StatusCode int              `json:"status_code"`
+	StatusMsg  string           `json:"status_msg"`
+	NextCursor string           `json:"next_cursor"`
+	HasMore    bool             `json:"has_more"`
+	VideoList  []HistoryResItem `json:"video_list"`
+}
+
+type HistoryResItem struct {
+	VideoResItem
+	ResumePositionMs int64 `json:"resume_position_ms"`
+	WatchedAt        int64 `json:"watched_at"`
+}
+
+type TagResponse struct {
+	StatusCode int            `json:"status_code"`
+	StatusMsg  string         `json:"status_msg"`
+	Tag        *TagResItem    `json:"tag,omitempty"`
+	NextTime   int64          `json:"next_time"`
+	VideoList  []VideoResItem `json:"video_list"`
+}
+
+type TagSuggestResponse struct {
+	StatusCode int          `json:"status_code"`
+	StatusMsg  string       `json:"status_msg"`
+	TagList    []TagResItem `json:"tag_list"`
+}
+
+// SearchResponse 搜索结果，根据搜索类型只返回 video_list / user_list / tag_list 中的一个
+type SearchResponse struct {
+	StatusCode int            `json:"status_code"`
+	StatusMsg  string         `json:"status_msg"`
+	NextOffset int            `json:"next_offset"`
+	HasMore    bool           `json:"has_more"`
+	VideoList  []VideoResItem `json:"video_list,omitempty"`
+	UserList   []UserResponse `json:"user_list,omitempty"`
+	TagList    []TagResItem   `json:"tag_list,omitempty"`
+}
+
+type TagResItem struct {
+	ID             uint   `json:"id"`
+	Name           string `json:"name"`
+	VideoCount     int64  `json:"video_count"`
+	TotalFavorited int64  `json:"total_favorited"`
+}
+
+type UserListResponse struct {
+	StatusCode int            `json:"status_code"`
+	StatusMsg  string         `json:"status_msg"`
+	NextCursor string         `json:"next_cursor"`
+	HasMore    bool           `json:"has_more"`
+	UserList   []UserResponse `json:"user_list"`
+}
+
+type FriendListResponse struct {
+	StatusCode int                 `json:"status_code"`
+	StatusMsg  string              `json:"status_msg"`
+	NextCursor string              `json:"next_cursor"`
+	HasMore    bool                `json:"has_more"`
+	UserList   []FriendUserResItem `json:"user_list"`
+}
+
+// FriendUserResItem 好友列表中的用户，Message 为和该好友最近的一条消息，
+// MsgType 为 0 表示当前用户接收的消息，为 1 表示当前用户发送的消息
+type FriendUserResItem struct {
+	UserResponse
+	Message string `json:"message"`
+	MsgType int    `json:"msgType"`
+}
+
+// IsFriendResponse IsFriend 的 key 为用户 ID，value 为该用户是否和当前用户互相关注
+type IsFriendResponse struct {
+	StatusCode int           `json:"status_code"`
+	StatusMsg  string        `json:"status_msg"`
+	IsFriend   map[uint]bool `json:"is_friend"`
+}
+
+type SuggestionListResponse struct {
+	StatusCode     int                 `json:"status_code"`
+	StatusMsg      string              `json:"status_msg"`
+	SuggestionList []SuggestionResItem `json:"suggestion_list"`
+}
+
+// SuggestionResItem 推荐关注的用户，Reason 为推荐原因，例如 "Followed by Alice and 3 others"
+type SuggestionResItem struct {
+	User   UserResponse `json:"user"`
+	Reason string       `json:"reason"`
 }
 
 type UserResponse struct {
@@ -34,17 +133,142 @@ type UserResponse struct {
 	FavoriteCount  int    `json:"favorite_count"`
 }
 
+type CollectionResItem struct {
+	ID         uint   `json:"id"`
+	UserID     uint   `json:"user_id"`
+	Name       string `json:"name"`
+	IsPublic   bool   `json:"is_public"`
+	VideoCount int    `json:"video_count"`
+	CoverUrl   string `json:"cover_url"`
+}
+
+type CollectionResponse struct {
+	StatusCode int                `json:"status_code"`
+	StatusMsg  string             `json:"status_msg"`
+	Collection *CollectionResItem `json:"collection,omitempty"`
+}
+
+type CollectionListResponse struct {
+	StatusCode     int                 `json:"status_code"`
+	StatusMsg      string              `json:"status_msg"`
+	CollectionList []CollectionResItem `json:"collection_list"`
+	NextCursor     string              `json:"next_cursor"`
+	HasMore        bool                `json:"has_more"`
+}
+
+type CollectionVideosResponse struct {
+	StatusCode int                `json:"status_code"`
+	StatusMsg  string             `json:"status_msg"`
+	Collection *CollectionResItem `json:"collection,omitempty"`
+	NextCursor int                `json:"next_cursor"`
+	HasMore    bool               `json:"has_more"`
+	VideoList  []VideoResItem     `json:"video_list"`
+}
+
+type PrivacyResponse struct {
+	StatusCode    int    `json:"status_code"`
+	StatusMsg     string `json:"status_msg"`
+	FavoriteList  int    `json:"favorite_list"`
+	FollowingList int    `json:"following_list"`
+	FollowerList  int    `json:"follower_list"`
+	IsPrivate     bool   `json:"is_private"`
+}
+
 type CommentListResponse struct {
 	StatusCode  int               `json:"status_code"`
 	StatusMsg   string            `json:"status_msg"`
 	CommentList *[]CommentResItem `json:"comment_list"`
+	NextCursor  string            `json:"next_cursor"`
+	HasMore     bool              `json:"has_more"`
 }
 
 type CommentResItem struct {
-	ID         uint         `json:"id"`
-	User       UserResponse `json:"user"`
-	Content    string       `json:"content"`
-	CreateDate time.Time    `json:"create_date"`
+	ID            uint             `json:"id"`
+	User          UserResponse     `json:"user"`
+	Content       string           `json:"content"`
+	CreateDate    time.Time        `json:"create_date"`
+	ParentID      uint             `json:"parent_id"`
+	RootID        uint             `json:"root_id"`
+	ReplyToUserID uint             `json:"reply_to_user_id"`
+	ReplyCount    int              `json:"reply_count"`
+	LikeCount     int              `json:"like_count"`
+	IsLiked       bool             `json:"is_liked"`
+	IsDeleted     bool             `json:"is_deleted"`
+	IsPinned      bool             `json:"is_pinned"`
+	IsCollapsed   bool             `json:"is_collapsed"` // 评论者被当前用户静音了，客户端折叠显示
+	Mentions      []MentionSpan    `json:"mentions"`
+	Replies       []CommentResItem `json:"replies,omitempty"`
+}
+
+// MentionSpan 文本中的@，Start 和 End 为字符（rune）下标
+type MentionSpan struct {
+	UserID uint `json:"user_id"`
+	Start  int  `json:"start"`
+	End    int  `json:"end"`
+}
+
+type MentionListResponse struct {
+	StatusCode  int              `json:"status_code"`
+	StatusMsg   string           `json:"status_msg"`
+	UnreadCount int64            `json:"unread_count"`
+	NextCursor  uint             `json:"next_cursor"`
+	HasMore     bool             `json:"has_more"`
+	MentionList []MentionResItem `json:"mention_list"`
+}
+
+type MentionResItem struct {
+	ID         uint          `json:"id"`
+	FromUser   UserResponse  `json:"from_user"`
+	Source     int           `json:"source"`
+	VideoID    uint          `json:"video_id"`
+	CommentID  uint          `json:"comment_id"`
+	Content    string        `json:"content"`
+	Mentions   []MentionSpan `json:"mentions"`
+	IsRead     bool          `json:"is_read"`
+	CreateDate time.Time     `json:"create_date"`
+}
+
+type NotificationListResponse struct {
+	StatusCode       int                     `json:"status_code"`
+	StatusMsg        string                  `json:"status_msg"`
+	UnreadCount      NotificationUnreadCount `json:"unread_count"`
+	NextCursor       string                  `json:"next_cursor"`
+	HasMore          bool                    `json:"has_more"`
+	NotificationList []NotificationResItem   `json:"notification_list"`
+}
+
+// NotificationResItem 聚合后的通知，Actors 为最近触发事件的几个用户，ActorCount 为总人数
+type NotificationResItem struct {
+	ID         uint           `json:"id"`
+	Type       int            `json:"type"`
+	TargetID   uint           `json:"target_id"`
+	ActorCount int            `json:"actor_count"`
+	Actors     []UserResponse `json:"actors"`
+	IsRead     bool           `json:"is_read"`
+	UpdateDate time.Time      `json:"update_date"`
+}
+
+type NotificationUnreadCount struct {
+	Total   int64 `json:"total"`
+	Like    int64 `json:"like"`
+	Comment int64 `json:"comment"`
+	Reply   int64 `json:"reply"`
+	Follow  int64 `json:"follow"`
+	Mention int64 `json:"mention"`
+}
+
+type NotificationUnreadResponse struct {
+	StatusCode  int                     `json:"status_code"`
+	StatusMsg   string                  `json:"status_msg"`
+	UnreadCount NotificationUnreadCount `json:"unread_count"`
+}
+
+type CommentRepliesResponse struct {
+	StatusCode int              `json:"status_code"`
+	StatusMsg  string           `json:"status_msg"`
+	NextCursor string           `json:"next_cursor"`
+	HasMore    bool             `json:"has_more"`
+	ReplyList  []CommentResItem `json:"reply_list"`
 }
 
 type CommentResponse struct {
@@ -57,6 +281,7 @@ type MessageHistoryResponse struct {
 	StatusCode  int              `json:"status_code"`
 	StatusMsg   string           `json:"status_msg"`
 	MessageList []MessageResItem `json:"message_list"`
+	SyncTime    string           `json:"sync_time"` // 下次同步变化时传入的 sync_time，格式为 "更新时间毫秒_ID"
 }
 
 type MessageResItem struct {
@@ -65,4 +290,63 @@ type MessageResItem struct {
 	FromUserId uint   `json:"from_user_id"`
 	Content    string `json:"content"`
 	CreateTime int64  `json:"create_time"`
+	IsRead     bool   `json:"is_read"` // 接收者是否已读
+	IsRecalled bool   `json:"is_recalled"`
+	IsEdited   bool   `json:"is_edited"`
+	IsDeleted  bool   `json:"is_deleted"` // 当前用户删除了这条消息，只在同步变化时返回
+	UpdateTime int64  `json:"update_time"`
+}
+
+type MessageResponse struct {
+	StatusCode int            `json:"status_code"`
+	StatusMsg  string         `json:"status_msg"`
+	Message    MessageResItem `json:"message"`
+}
+
+type ConversationListResponse struct {
+	StatusCode       int                   `json:"status_code"`
+	StatusMsg        string                `json:"status_msg"`
+	NextCursor       string                `json:"next_cursor"`
+	HasMore          bool                  `json:"has_more"`
+	ConversationList []ConversationResItem `json:"conversation_list"`
+}
+
+type ConversationResItem struct {
+	User           UserResponse   `json:"user"`
+	LastMessage    MessageResItem `json:"last_message"`
+	LastActiveTime int64          `json:"last_active_time"`
+	UnreadCount    int            `json:"unread_count"`
+}
+
+type ReadResponse struct {
+	StatusCode    int    `json:"status_code"`
+	StatusMsg     string `json:"status_msg"`
+	ReadMessageId uint   `json:"read_message_id"`
+	UnreadCount   int    `json:"unread_count"`
+}
+
+// ReadReceipt 已读回执，UserId 读了和 PeerId 的会话中 ID 不大于 ReadMessageId 的消息
+type ReadReceipt struct {
+	UserId        uint `json:"user_id"`
+	PeerId        uint `json:"peer_id"`
+	ReadMessageId uint `json:"read_message_id"`
+}
+
+type ContentReviewListResponse struct {
+	StatusCode int                    `json:"status_code"`
+	StatusMsg  string                 `json:"status_msg"`
+	NextCursor uint                   `json:"next_cursor"`
+	HasMore    bool                   `json:"has_more"`
+	ReviewList []ContentReviewResItem `json:"review_list"`
+}
+
+// ContentReviewResItem 审核队列中的一条内容，TargetID 和 ParentID 的含义见 models.ContentReview
+type ContentReviewResItem struct {
+	ID         uint      `json:"id"`
+	UserID     uint      `json:"user_id"`
+	Source     string    `json:"source"`
+	TargetID   uint      `json:"target_id"`
+	ParentID   uint      `json:"parent_id"`
+	Content    string    `json:"content"`
+	CreateDate time.Time `json:"create_date"`
 }
@@ -26,7 +26,14 @@ func Setup() {
 
 func Teardown() {
 	TestRouter = nil
-	err := db.Migrator().DropTable(&models.User{}, &models.UserProfile{}, &models.Message{}, &models.Relation{})
+	err := db.Migrator().DropTable(&models.User{}, &models.UserProfile{}, &models.Message{}, &models.Relation{},
+		&models.Video{}, &models.Favorite{}, &models.WatchHistory{}, &models.FeedFeedback{},
+		&models.Tag{}, &models.VideoTag{}, &models.SearchHistory{},
+		&models.Comment{}, &models.CommentLike{}, &models.ContentFilterLog{},
+		&models.Mention{}, &models.Notification{}, &models.NotificationActor{},
+		&models.Collection{}, &models.CollectionVideo{}, &models.PrivacySetting{},
+		&models.FollowSuggestion{}, &models.Block{}, &models.FollowRequest{}, &models.Mute{},
+		&models.Conversation{}, &models.ContentReview{})
 	if err != nil {
 		fmt.Println("Failed to drop DB table.")
 	}
@@ -0,0 +1,20 @@
+package utils
+
+import "app/modules/models"
+
+// BuildUserResponse 将用户转换为返回给客户端的结构体，user 需要已经 Preload 了 Profile
+func BuildUserResponse(user models.User, isFollow bool) UserResponse {
+	return UserResponse{
+		ID:             user.ID,
+		Name:           user.Username,
+		FollowCount:    user.Profile.FollowCount,
+		FollowerCount:  user.Profile.FollowerCount,
+		IsFollow:       isFollow,
+		Avatar:         user.Profile.Avatar,
+		Background:     user.Profile.Background,
+		Signature:      user.Profile.Signature,
+		TotalFavorited: user.Profile.TotalFavorited,
+		WorkCount:      user.Profile.WorkCount,
+		FavoriteCount:  user.Profile.FavoriteCount,
+	}
+}
@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"app/modules/models"
+	"gorm.io/gorm"
+)
+
+// BuildVideoResList 将视频列表转换为返回给客户端的结构体，videos 需要已经 Preload 了
+// User 和 User.Profile。对于当前登录用户，批量查询是否点赞过视频、是否关注了发布者
+func BuildVideoResList(db *gorm.DB, videos []models.Video, currentUserId uint) []VideoResItem {
+	var likedVideoIdSet = make(map[uint]bool)
+	var followedCreatorIdSet = make(map[uint]bool)
+	if currentUserId > 0 && len(videos) > 0 {
+		// 生成视频 ID 列表和视频发布者 ID 列表
+		var videoIds []uint
+		var creatorIds []uint
+		for _, v := range videos {
+			videoIds = append(videoIds, v.ID)
+			creatorIds = append(creatorIds, v.UserID)
+		}
+
+		// 查询 favorites 表，看看哪些视频被用户点赞过
+		var likedVideoIds []uint
+		db.Table("favorites").
+			Where("user_id = ? AND video_id IN ?", currentUserId, videoIds).
+			Pluck("video_id", &likedVideoIds)
+		for _, id := range likedVideoIds {
+			likedVideoIdSet[id] = true
+		}
+
+		// 查询 relations 表，看看当前用户关注了哪些视频发布者
+		var followedCreatorIds []uint
+		db.Table("relations").
+			Where("from_user_id = ? AND to_user_id IN ?", currentUserId, creatorIds).
+			Pluck("to_user_id", &followedCreatorIds)
+		for _, id := range followedCreatorIds {
+			followedCreatorIdSet[id] = true
+		}
+	}
+
+	// 查询视频标题中的@
+	var videoIds []uint
+	for _, v := range videos {
+		videoIds = append(videoIds, v.ID)
+	}
+	titleSpans := MentionSpans(db, models.MentionTitle, videoIds)
+
+	var videoResList []VideoResItem
+	for _, v := range videos {
+		videoResList = append(videoResList, VideoResItem{
+			ID:            v.ID,
+			PlayUrl:       v.PlayUrl,
+			CoverUrl:      v.CoverUrl,
+			FavoriteCount: v.FavoriteCount,
+			CommentCount:  v.CommentCount,
+			Title:         v.Title,
+			CommentPolicy: v.CommentPolicy,
+			TitleMentions: titleSpans[v.ID],
+			IsFavorite:    likedVideoIdSet[v.ID],
+			Author: UserResponse{
+				ID:             v.User.ID,
+				Name:           v.User.Username,
+				IsFollow:       followedCreatorIdSet[v.UserID],
+				Avatar:         v.User.Profile.Avatar,
+				Background:     v.User.Profile.Background,
+				Signature:      v.User.Profile.Signature,
+				FollowCount:    v.User.Profile.FollowCount,
+				FollowerCount:  v.User.Profile.FollowerCount,
+				TotalFavorited: v.User.Profile.TotalFavorited,
+				WorkCount:      v.User.Profile.WorkCount,
+				FavoriteCount:  v.User.Profile.FavoriteCount,
+			},
+		})
+	}
+	return videoResList
+}